@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Scopes of API keys
+const (
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+	// ScopeAdmin allows everything, keys having it act as admins
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every API key scope
+var Scopes = []string{ScopePostsRead, ScopePostsWrite, ScopeAdmin}
+
+// APIKeyPrefix starts every API key, so keys are told apart from JWTs and easy to spot in leaked files
+const APIKeyPrefix = "bk_"
+
+// GenerateAPIKey returns a new random API key
+func GenerateAPIKey() (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", errors.Wrap(err, "generate API key")
+	}
+	return APIKeyPrefix + token, nil
+}
+
+// IsAPIKey reports whether the credential looks like an API key rather than a JWT
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// HashAPIKey returns the hash of the key which is stored instead of the key
+func HashAPIKey(key string) string {
+	return HashToken(key)
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	key, err := GenerateAPIKey()
+	require.NoError(t, err)
+	other, err := GenerateAPIKey()
+	require.NoError(t, err)
+
+	assert.True(t, IsAPIKey(key))
+	assert.False(t, IsAPIKey(sign(t, jwt.SigningMethodHS256, secret, "", validClaims())))
+	assert.NotEqual(t, key, other)
+	assert.Equal(t, HashAPIKey(key), HashAPIKey(key))
+	assert.NotEqual(t, HashAPIKey(key), HashAPIKey(other))
+	assert.Len(t, HashAPIKey(key), 64)
+}
+
+func TestPrincipal_Allows(t *testing.T) {
+	assert.True(t, Principal{}.Allows(ScopePostsWrite), "principals without scopes aren't limited")
+	assert.True(t, Principal{Scopes: []string{ScopePostsRead, ScopePostsWrite}}.Allows(ScopePostsWrite))
+	assert.False(t, Principal{Scopes: []string{ScopePostsRead}}.Allows(ScopePostsWrite))
+	assert.False(t, Principal{Scopes: []string{}}.Allows(ScopePostsRead))
+	assert.True(t, Principal{Scopes: []string{ScopeAdmin}}.Allows(ScopePostsWrite))
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrNoKeys       = errors.New("no token verification keys configured")
+)
+
+// Claims are the JWT claims the blog understands on top of the registered ones
+type Claims struct {
+	jwt.RegisteredClaims
+	Name     string   `json:"name,omitempty"`
+	AuthorID int64    `json:"author_id,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+}
+
+// Verifier validates JWTs signed with HS256 or RS256. Tokens must carry a subject and an expiry,
+// the issuer and audience are checked when configured.
+type Verifier struct {
+	secret []byte
+	// rsaKeys holds RS256 public keys by key ID, a key without ID is stored under ""
+	rsaKeys  map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+// VerifierOption configures a Verifier
+type VerifierOption func(v *Verifier)
+
+// WithHS256Secret accepts tokens signed by the shared secret
+func WithHS256Secret(secret []byte) VerifierOption {
+	return func(v *Verifier) {
+		v.secret = secret
+	}
+}
+
+// WithRS256Keys accepts tokens signed by the private counterparts of the keys, mapped by key ID
+func WithRS256Keys(keys map[string]*rsa.PublicKey) VerifierOption {
+	return func(v *Verifier) {
+		for kid, key := range keys {
+			v.rsaKeys[kid] = key
+		}
+	}
+}
+
+// WithIssuer requires the "iss" claim to match
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience requires the "aud" claim to contain the audience
+func WithAudience(audience string) VerifierOption {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithLeeway tolerates clock skew when checking expiry and not before times
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.leeway = leeway
+	}
+}
+
+// NewVerifier fails with ErrNoKeys unless a secret or a public key is given
+func NewVerifier(opts ...VerifierOption) (*Verifier, error) {
+	v := &Verifier{rsaKeys: make(map[string]*rsa.PublicKey)}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if len(v.secret) == 0 && len(v.rsaKeys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return v, nil
+}
+
+// Verify validates the token and returns the principal it was issued for.
+// Every failure is reported as ErrInvalidToken wrapping the reason.
+func (v *Verifier) Verify(token string) (Principal, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.leeway),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+
+	var claims Claims
+	if _, err := jwt.ParseWithClaims(token, &claims, v.key, opts...); err != nil {
+		return Principal{}, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+	if claims.Subject == "" {
+		return Principal{}, errors.Wrap(ErrInvalidToken, "token has no subject")
+	}
+
+	return Principal{
+		Subject:  claims.Subject,
+		Name:     claims.Name,
+		AuthorID: claims.AuthorID,
+		Roles:    claims.Roles,
+	}, nil
+}
+
+// key picks the verification key by the token algorithm and key ID
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if len(v.secret) == 0 {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		return v.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := v.rsaKeys[kid]; ok {
+			return key, nil
+		}
+		// A token without key ID is accepted when there is a single key to try
+		if kid == "" && len(v.rsaKeys) == 1 {
+			for _, key := range v.rsaKeys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+// ParseRSAPublicKey parses a PEM encoded RSA public key, either PKIX or PKCS #1
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse RSA public key")
+	}
+	return key, nil
+}
+
+// jwks is a JSON Web Key Set as defined by RFC 7517
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// ParseJWKS returns the RSA signing keys of a JSON Web Key Set by key ID.
+// Keys of other types or meant for encryption are skipped.
+func ParseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, errors.Wrap(err, "decode JWKS")
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != jwt.SigningMethodRS256.Alg()) {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode modulus of key %q", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode exponent of key %q", k.Kid)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent of key %q", k.Kid)
+		}
+		if _, ok := keys[k.Kid]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no RSA signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("test-secret")
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims() Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Name:     "Jane Doe",
+		AuthorID: 7,
+		Roles:    []string{"admin"},
+	}
+}
+
+func TestVerifier_HS256(t *testing.T) {
+	verifier, err := NewVerifier(WithHS256Secret(secret))
+	require.NoError(t, err)
+
+	principal, err := verifier.Verify(sign(t, jwt.SigningMethodHS256, secret, "", validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, Principal{Subject: "user-1", Name: "Jane Doe", AuthorID: 7, Roles: []string{"admin"}}, principal)
+	assert.True(t, principal.IsAdmin())
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+	noSubject := validClaims()
+	noSubject.Subject = ""
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "wrong secret", token: sign(t, jwt.SigningMethodHS256, []byte("other"), "", validClaims())},
+		{name: "expired", token: sign(t, jwt.SigningMethodHS256, secret, "", expired)},
+		{name: "no expiry", token: sign(t, jwt.SigningMethodHS256, secret, "", noExpiry)},
+		{name: "no subject", token: sign(t, jwt.SigningMethodHS256, secret, "", noSubject)},
+		{name: "unsupported algorithm", token: sign(t, jwt.SigningMethodHS512, secret, "", validClaims())},
+		{name: "unsigned", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims())},
+		{name: "malformed", token: "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(tt.token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestVerifier_IssuerAndAudience(t *testing.T) {
+	verifier, err := NewVerifier(WithHS256Secret(secret), WithIssuer("https://id.example"), WithAudience("blog"))
+	require.NoError(t, err)
+
+	claims := validClaims()
+	claims.Issuer = "https://id.example"
+	claims.Audience = jwt.ClaimStrings{"blog", "shop"}
+	_, err = verifier.Verify(sign(t, jwt.SigningMethodHS256, secret, "", claims))
+	assert.NoError(t, err)
+
+	claims.Audience = jwt.ClaimStrings{"shop"}
+	_, err = verifier.Verify(sign(t, jwt.SigningMethodHS256, secret, "", claims))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	claims.Audience = jwt.ClaimStrings{"blog"}
+	claims.Issuer = "https://evil.example"
+	_, err = verifier.Verify(sign(t, jwt.SigningMethodHS256, secret, "", claims))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerifier_RS256(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	require.NoError(t, err)
+	public, err := ParseRSAPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	t.Run("single key", func(t *testing.T) {
+		verifier, err := NewVerifier(WithRS256Keys(map[string]*rsa.PublicKey{"": public}))
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(sign(t, jwt.SigningMethodRS256, private, "", validClaims()))
+		assert.NoError(t, err)
+		_, err = verifier.Verify(sign(t, jwt.SigningMethodRS256, other, "", validClaims()))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		_, err = verifier.Verify(sign(t, jwt.SigningMethodHS256, secret, "", validClaims()))
+		assert.ErrorIs(t, err, ErrInvalidToken, "HS256 is off without a secret")
+	})
+
+	t.Run("JWKS", func(t *testing.T) {
+		keys, err := ParseJWKS([]byte(fmt.Sprintf(`{"keys": [
+			{"kty": "RSA", "kid": "one", "use": "sig", "alg": "RS256", "n": %q, "e": %q},
+			{"kty": "RSA", "kid": "two", "n": %q, "e": %q},
+			{"kty": "EC", "kid": "three", "crv": "P-256"},
+			{"kty": "RSA", "kid": "four", "use": "enc", "n": %q, "e": %q}
+		]}`, jwkInt(private.N), jwkInt(big.NewInt(int64(private.E))),
+			jwkInt(other.N), jwkInt(big.NewInt(int64(other.E))),
+			jwkInt(other.N), jwkInt(big.NewInt(int64(other.E))))))
+		require.NoError(t, err)
+		assert.Len(t, keys, 2)
+
+		verifier, err := NewVerifier(WithRS256Keys(keys))
+		require.NoError(t, err)
+
+		_, err = verifier.Verify(sign(t, jwt.SigningMethodRS256, private, "one", validClaims()))
+		assert.NoError(t, err)
+		_, err = verifier.Verify(sign(t, jwt.SigningMethodRS256, other, "two", validClaims()))
+		assert.NoError(t, err)
+		_, err = verifier.Verify(sign(t, jwt.SigningMethodRS256, private, "two", validClaims()))
+		assert.ErrorIs(t, err, ErrInvalidToken, "key ID picks the key")
+		_, err = verifier.Verify(sign(t, jwt.SigningMethodRS256, private, "", validClaims()))
+		assert.ErrorIs(t, err, ErrInvalidToken, "key ID is required with several keys")
+	})
+}
+
+func TestParseJWKS_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"keys": []}`,
+		`{"keys": [{"kty": "RSA", "kid": "a", "n": "!!", "e": "AQAB"}]}`,
+		`{"keys": [{"kty": "RSA", "kid": "a", "n": "AQAB", "e": "AQ"}]}`,
+		`{"keys": [{"kty": "RSA", "kid": "a", "n": "AQAB", "e": "AQAB"}, {"kty": "RSA", "kid": "a", "n": "AQAB", "e": "AQAB"}]}`,
+	} {
+		_, err := ParseJWKS([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestNewVerifier_NoKeys(t *testing.T) {
+	_, err := NewVerifier(WithIssuer("https://id.example"))
+	assert.ErrorIs(t, err, ErrNoKeys)
+}
+
+func jwkInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
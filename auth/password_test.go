@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$"), hash)
+
+	other, err := HashPassword("correct horse battery staple")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "every hash has its own salt")
+
+	ok, err := VerifyPassword("correct horse battery staple", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = VerifyPassword("correct horse battery stable", hash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyPassword_OwnParameters(t *testing.T) {
+	// Verification must not assume the parameters of new hashes
+	salt := []byte("somesaltsomesalt")
+	key := argon2.IDKey([]byte("password"), salt, 1, 8192, 2, 24)
+	hash := "$argon2id$v=19$m=8192,t=1,p=2$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(key)
+	ok, err := VerifyPassword("password", hash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestVerifyPassword_Malformed(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plain text",
+		"$2a$10$abcdefghijklmnopqrstuu5pVyyFVI4QHmXCmUyj3I2W3ZW6wNoW6",
+		"$argon2i$v=19$m=19456,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$!!$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$",
+	} {
+		_, err := VerifyPassword("password", hash)
+		assert.ErrorIs(t, err, ErrMalformedHash, hash)
+	}
+}
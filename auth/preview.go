@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidPreviewToken = errors.New("invalid preview token")
+
+// PreviewToken is what a preview link grants: reading the post until ExpiresAt
+type PreviewToken struct {
+	PostID    int64
+	ExpiresAt time.Time
+}
+
+// SignPreview returns the preview token "<post id>.<expiry in unix seconds>.<signature>". The HMAC-SHA256 signature
+// also covers the nonce of the post, which isn't part of the token, so changing the nonce revokes every token
+// signed before.
+func SignPreview(secret []byte, token PreviewToken, nonce string) string {
+	payload := strconv.FormatInt(token.PostID, 10) + "." + strconv.FormatInt(token.ExpiresAt.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(previewMAC(secret, payload, nonce))
+}
+
+// ParsePreview reads the post and the expiry of a preview token without verifying it,
+// the nonce needed by VerifyPreview is looked up by the post ID
+func ParsePreview(token string) (PreviewToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return PreviewToken{}, ErrInvalidPreviewToken
+	}
+	postID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || postID <= 0 {
+		return PreviewToken{}, ErrInvalidPreviewToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return PreviewToken{}, ErrInvalidPreviewToken
+	}
+	return PreviewToken{PostID: postID, ExpiresAt: time.Unix(expiresAt, 0).UTC()}, nil
+}
+
+// VerifyPreview reports whether the token was signed with the secret and the nonce. Expiry is up to the caller.
+func VerifyPreview(secret []byte, token, nonce string) bool {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 || nonce == "" {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(signature, previewMAC(secret, token[:i], nonce))
+}
+
+func previewMAC(secret []byte, payload, nonce string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload + "." + nonce))
+	return mac.Sum(nil)
+}
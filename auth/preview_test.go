@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewToken(t *testing.T) {
+	key := []byte("preview-secret")
+	expiresAt := time.Date(2030, 1, 1, 8, 0, 0, 0, time.UTC)
+	token := SignPreview(key, PreviewToken{PostID: 42, ExpiresAt: expiresAt}, "nonce")
+
+	parsed, err := ParsePreview(token)
+	require.NoError(t, err)
+	assert.Equal(t, PreviewToken{PostID: 42, ExpiresAt: expiresAt}, parsed)
+	assert.True(t, VerifyPreview(key, token, "nonce"))
+
+	assert.False(t, VerifyPreview(key, token, "rotated"), "rotating the nonce revokes the token")
+	assert.False(t, VerifyPreview([]byte("other-secret"), token, "nonce"))
+	assert.False(t, VerifyPreview(key, token, ""))
+
+	forged := SignPreview(key, PreviewToken{PostID: 43, ExpiresAt: expiresAt}, "nonce")
+	assert.False(t, VerifyPreview(key, "42"+forged[2:], "nonce"), "the post ID is signed")
+	extended := SignPreview(key, PreviewToken{PostID: 42, ExpiresAt: expiresAt.Add(time.Hour)}, "nonce")
+	signature := token[strings.LastIndex(token, ".")+1:]
+	payload := extended[:strings.LastIndex(extended, ".")]
+	assert.False(t, VerifyPreview(key, payload+"."+signature, "nonce"), "the expiry is signed")
+
+	for _, invalid := range []string{"", "42", "42.abc.sig", "x.1.sig", "0.1.sig", "1.2.3.4"} {
+		_, err := ParsePreview(invalid)
+		assert.ErrorIs(t, err, ErrInvalidPreviewToken, invalid)
+	}
+}
@@ -0,0 +1,60 @@
+// Package auth authenticates API clients and carries who they are through the request context
+package auth
+
+import (
+	"context"
+)
+
+// Principal is the authenticated client of a request
+type Principal struct {
+	// Subject identifies the client, it is the "sub" claim of a JWT
+	Subject string
+	// Name is the author name the client writes as, empty when unknown
+	Name string
+	// AuthorID links the client to an author, zero when unknown
+	AuthorID int64
+	Roles    []string
+	// Scopes limit what the credentials may be used for, nil when they aren't limited like for JWT users
+	Scopes []string
+}
+
+// HasRole reports whether the principal was granted the role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the principal has the admin role
+func (p Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+// Allows reports whether the credentials may be used for the scope, the admin scope allows everything
+func (p Principal) Allows(scope string) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, ok is false for anonymous requests
+func FromContext(ctx context.Context) (p Principal, ok bool) {
+	p, ok = ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
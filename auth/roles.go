@@ -0,0 +1,12 @@
+package auth
+
+// Roles a principal may be granted, what each of them allows is decided by the authorization policy
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+	RoleReader = "reader"
+)
+
+// RoleAnonymous stands for requests without credentials in authorization policies, it is never granted to a principal
+const RoleAnonymous = "anonymous"
@@ -0,0 +1,39 @@
+// Command migrate-authors converts free text post authors of a blog data file into author records
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+
+	"rakia_blog_tt/migrate"
+)
+
+func main() {
+	in := flag.String("in", "blog_data.json", "data file to migrate")
+	out := flag.String("out", "", "file to write the result to, the input file is replaced when empty")
+	flag.Parse()
+
+	if *out == "" {
+		*out = *in
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		slog.Error("Reading data file failed", "error", err)
+		os.Exit(1)
+	}
+
+	migrated, report, err := migrate.Authors(data)
+	if err != nil {
+		slog.Error("Migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*out, migrated, 0o644); err != nil {
+		slog.Error("Writing data file failed", "error", err)
+		os.Exit(1)
+	}
+
+	slog.Info("Authors migrated", "authors_created", report.AuthorsCreated, "posts_linked", report.PostsLinked, "file", *out)
+}
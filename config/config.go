@@ -9,9 +9,20 @@ import (
 )
 
 type Config struct {
-	App        *App        `env:",prefix=APP_"`
-	Monitoring *Monitoring `env:",prefix=MONITORING_"`
-	Http       *Http       `env:",prefix=HTTP_"`
+	App         *App         `env:",prefix=APP_"`
+	Monitoring  *Monitoring  `env:",prefix=MONITORING_"`
+	Http        *Http        `env:",prefix=HTTP_"`
+	Markdown    *Markdown    `env:",prefix=MARKDOWN_"`
+	Site        *Site        `env:",prefix=SITE_"`
+	Moderation  *Moderation  `env:",prefix=MODERATION_"`
+	Auth        *Auth        `env:",prefix=AUTH_"`
+	Session     *Session     `env:",prefix=SESSION_"`
+	Scheduler   *Scheduler   `env:",prefix=SCHEDULER_"`
+	Preview     *Preview     `env:",prefix=PREVIEW_"`
+	RateLimit   *RateLimit   `env:",prefix=RATE_LIMIT_"`
+	CORS        *CORS        `env:",prefix=CORS_"`
+	Compression *Compression `env:",prefix=COMPRESSION_"`
+	HttpLog     *HttpLog     `env:",prefix=HTTP_LOG_"`
 }
 
 type App struct {
@@ -23,8 +34,108 @@ type Monitoring struct {
 	ReadTimeout time.Duration `env:"READ_TIMEOUT"`
 }
 
+// Http configures the server, MaxBodySize limits request bodies in bytes (1 MiB by default)
 type Http struct {
 	ReadTimeout time.Duration `env:"READ_TIMEOUT"`
+	MaxBodySize int64         `env:"MAX_BODY_SIZE"`
+}
+
+type Markdown struct {
+	Enabled         bool `env:"ENABLED"`
+	TableOfContents bool `env:"TABLE_OF_CONTENTS"`
+	CacheSize       int  `env:"CACHE_SIZE"`
+}
+
+type Site struct {
+	Title         string `env:"TITLE"`
+	Description   string `env:"DESCRIPTION"`
+	BaseURL       string `env:"BASE_URL"`
+	PageSize      int    `env:"PAGE_SIZE"`
+	FeedItemLimit int    `env:"FEED_ITEM_LIMIT"`
+}
+
+// Moderation configures spam checks of new comments, a zero setting turns its check off
+type Moderation struct {
+	Enabled         bool          `env:"ENABLED"`
+	MaxLinks        int           `env:"MAX_LINKS"`
+	BannedWords     []string      `env:"BANNED_WORDS"`
+	DuplicateWindow time.Duration `env:"DUPLICATE_WINDOW"`
+	RateLimit       int           `env:"RATE_LIMIT"`
+	RateWindow      time.Duration `env:"RATE_WINDOW"`
+}
+
+// Auth configures validation of JWT bearer tokens, at least one of the keys is required.
+// RS256PublicKey holds a PEM encoded key, JWKSFile is the path of a local JSON Web Key Set.
+// PolicyFile replaces the built-in permission policy of the roles, see service/policy.yaml.
+type Auth struct {
+	HS256Secret    string        `env:"HS256_SECRET"`
+	RS256PublicKey string        `env:"RS256_PUBLIC_KEY"`
+	JWKSFile       string        `env:"JWKS_FILE"`
+	Issuer         string        `env:"ISSUER"`
+	Audience       string        `env:"AUDIENCE"`
+	Leeway         time.Duration `env:"LEEWAY"`
+	PolicyFile     string        `env:"POLICY_FILE"`
+}
+
+// Session configures login sessions of the HTML pages. Accounts are locked for LoginWindow after MaxFailedLogins
+// failed logins within it. SecureCookie may be turned off for local development over plain HTTP only.
+type Session struct {
+	TTL             time.Duration `env:"TTL"`
+	SecureCookie    bool          `env:"SECURE_COOKIE,default=true"`
+	MaxFailedLogins int           `env:"MAX_FAILED_LOGINS"`
+	LoginWindow     time.Duration `env:"LOGIN_WINDOW"`
+}
+
+// Scheduler configures how often scheduled posts are checked for publication, every minute when Interval is unset
+type Scheduler struct {
+	Interval time.Duration `env:"INTERVAL"`
+}
+
+// Preview configures signed preview links of posts, they are disabled without Secret.
+// TTL is how long links last unless an expiry is asked for, a week when unset.
+type Preview struct {
+	Secret string        `env:"SECRET"`
+	TTL    time.Duration `env:"TTL"`
+}
+
+// RateLimit configures limits of requests per client, limits are written as requests/period like 100/1m.
+// Routes maps "METHOD /pattern" or "/pattern" to a limit replacing Default, like "POST /posts/{id}/comments:5/1m".
+// Requests aren't limited without any limit. X-Forwarded-For is trusted from TrustedProxies only, addresses or CIDRs.
+// FailedAuth limits requests failing authentication per address, Default applies when it's unset.
+type RateLimit struct {
+	Default        string            `env:"DEFAULT"`
+	Routes         map[string]string `env:"ROUTES"`
+	FailedAuth     string            `env:"FAILED_AUTH"`
+	TrustedProxies []string          `env:"TRUSTED_PROXIES"`
+}
+
+// CORS configures calls of browser clients from other origins, CORS is off without AllowedOrigins.
+// Origins may have one wildcard, like https://*.example.com, or be * for any origin unless AllowCredentials is set.
+type CORS struct {
+	AllowedOrigins   []string      `env:"ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `env:"ALLOWED_METHODS,default=GET,HEAD,POST,PUT,PATCH,DELETE"`
+	AllowedHeaders   []string      `env:"ALLOWED_HEADERS,default=Accept,Authorization,Content-Type,If-Match,If-None-Match,X-API-Key"`
+	ExposedHeaders   []string      `env:"EXPOSED_HEADERS,default=ETag,Location,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy"`
+	AllowCredentials bool          `env:"ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `env:"MAX_AGE,default=10m"`
+}
+
+// Compression configures compression of responses, MinSize is in bytes and ContentTypes may hold type/* entries
+type Compression struct {
+	Enabled      bool     `env:"ENABLED,default=true"`
+	MinSize      int      `env:"MIN_SIZE,default=1024"`
+	ContentTypes []string `env:"CONTENT_TYPES,default=text/*,application/json,application/problem+json,application/xml,application/yaml,application/rss+xml,application/atom+xml,application/javascript,image/svg+xml"`
+}
+
+// HttpLog configures logging of request and response bodies, Mode is off, errors or sampled, which logs SamplePercent
+// of the requests. Bodies are cut after MaxBodySize bytes, values of RedactFields in JSON, XML and form bodies and of
+// RedactHeaders are replaced.
+type HttpLog struct {
+	Mode          string   `env:"MODE,default=off"`
+	SamplePercent float64  `env:"SAMPLE_PERCENT,default=1"`
+	MaxBodySize   int      `env:"MAX_BODY_SIZE,default=2048"`
+	RedactFields  []string `env:"REDACT_FIELDS,default=password,token,key,secret,csrf_token"`
+	RedactHeaders []string `env:"REDACT_HEADERS,default=Authorization,Proxy-Authorization,Cookie,Set-Cookie,X-API-Key"`
 }
 
 func New(ctx context.Context) (*Config, error) {
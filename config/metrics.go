@@ -16,11 +16,13 @@ const (
 	labelCode   = "code"
 	labelMethod = "method"
 	labelName   = "name"
+	labelClient = "client"
 )
 
 type Metrics struct {
 	httpDurationSummary         *prometheus.SummaryVec
 	storageQueryDurationSummary *prometheus.SummaryVec
+	rateLimitedCounter          *prometheus.CounterVec
 }
 
 func InitMetrics() *Metrics {
@@ -41,6 +43,11 @@ func InitMetrics() *Metrics {
 		MaxAge:     120 * time.Second,
 	}, []string{labelApp, labelName})
 
+	metrics.rateLimitedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_rate_limited_requests_total",
+		Help: "Requests rejected by the rate limiter.",
+	}, []string{labelApp, labelPath, labelClient})
+
 	return &metrics
 }
 
@@ -59,3 +66,11 @@ func (m *Metrics) ObserveQueryDuration(timeSince time.Time, name string) {
 		labelName: name,
 	}).Observe(float64(time.Since(timeSince).Seconds()))
 }
+
+func (m *Metrics) ObserveRateLimited(path, client string) {
+	m.rateLimitedCounter.With(map[string]string{
+		labelApp:    AppName,
+		labelPath:   path,
+		labelClient: client,
+	}).Inc()
+}
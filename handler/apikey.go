@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-openapi/strfmt"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/service"
+)
+
+// writeAPIKeyError maps API key errors of the service into problem responses and reports whether err was one of them
+func writeAPIKeyError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if writeAuthorError(w, r, err, "author_id", "body") {
+		return true
+	}
+
+	switch {
+	case errors.Is(err, service.ErrAPIKeysDisabled):
+		writeProblem(w, r, http.StatusNotImplemented, "API keys are not enabled")
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		writeProblem(w, r, http.StatusNotFound, "API key not found")
+	case errors.Is(err, service.ErrExpiryInPast):
+		writeProblem(w, r, http.StatusBadRequest, "Invalid API key format", &models.ProblemFieldError{
+			Name:    "expires_at",
+			In:      "body",
+			Message: "expires_at in body must be in the future",
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *Handler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.GetAPIKeys(r.Context())
+	if err != nil {
+		if !writeAPIKeyError(w, r, err) {
+			h.log(r.Context()).Error("failed to get API keys", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve API keys")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, apiKeyMediaTypes, keys)
+}
+
+// CreateAPIKey mints a key, the response is the only time the key itself is shown
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var key models.APIKey
+	if err := decodeBody(r, &key); err != nil {
+		h.log(r.Context()).Error("API key decode failed", "error", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	if err := key.Validate(strfmt.NewFormats()); err != nil {
+		h.log(r.Context()).Error("invalid API key format", "error", err)
+		writeValidationProblem(w, r, "Invalid API key format", err)
+		return
+	}
+
+	// Negotiate before minting, a not acceptable response would lose the key
+	if _, err := negotiate(r.Header.Get("Accept"), apiKeyMediaTypes); err != nil {
+		writeProblem(w, r, http.StatusNotAcceptable, "Supported media types: "+strings.Join(apiKeyMediaTypes, ", "))
+		return
+	}
+
+	created, err := h.service.CreateAPIKey(r.Context(), key)
+	if err != nil {
+		if !writeAPIKeyError(w, r, err) {
+			h.log(r.Context()).Error("failed to create API key", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to create API key")
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.respond(w, r, http.StatusCreated, apiKeyMediaTypes, created)
+}
+
+// RevokeAPIKey deletes a key, requests using it are refused right away
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(r.Context(), id); err != nil {
+		if !writeAPIKeyError(w, r, err) {
+			h.log(r.Context()).Error("failed to revoke API key", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to revoke API key")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
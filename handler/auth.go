@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/logging"
+	"rakia_blog_tt/service"
+)
+
+// apiKeyHeader carries an API key for clients that can't set the Authorization header
+const apiKeyHeader = "X-API-Key"
+
+// TokenVerifier validates bearer tokens and returns who they were issued for
+type TokenVerifier interface {
+	Verify(token string) (auth.Principal, error)
+}
+
+// KeyVerifier validates API keys and returns the principal they act as
+type KeyVerifier interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (auth.Principal, error)
+}
+
+// Authenticator recognizes clients by a JWT or an API key. Both are accepted as "Authorization: Bearer",
+// API keys also in the X-API-Key header.
+type Authenticator struct {
+	tokens TokenVerifier
+	keys   KeyVerifier
+	logger *slog.Logger
+}
+
+func NewAuthenticator(tokens TokenVerifier, keys KeyVerifier, logger *slog.Logger) *Authenticator {
+	return &Authenticator{
+		tokens: tokens,
+		keys:   keys,
+		logger: logger,
+	}
+}
+
+// credential returns the credential sent with the request, the Authorization header takes precedence
+func credential(r *http.Request) (string, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+		token = strings.TrimSpace(token)
+		return token, token != ""
+	}
+	key := strings.TrimSpace(r.Header.Get(apiKeyHeader))
+	return key, key != ""
+}
+
+// authenticate places the principal of the request credentials into the request context.
+// Anonymous requests go on only when required is false, invalid credentials are refused either way.
+func (a *Authenticator) authenticate(required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" && r.Header.Get(apiKeyHeader) == "" {
+				if required {
+					writeUnauthorized(w, r, "", "Bearer token or API key required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cred, ok := credential(r)
+			if !ok {
+				writeUnauthorized(w, r, "invalid_request", "Malformed Authorization header")
+				return
+			}
+
+			var (
+				principal auth.Principal
+				err       error
+			)
+			if auth.IsAPIKey(cred) {
+				principal, err = a.keys.AuthenticateAPIKey(r.Context(), cred)
+			} else {
+				principal, err = a.tokens.Verify(cred)
+			}
+			switch {
+			case errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, service.ErrInvalidAPIKey):
+				writeUnauthorized(w, r, "invalid_token", "Invalid or expired credentials")
+				return
+			case err != nil:
+				logging.FromContext(r.Context(), a.logger).Error("failed to authenticate request", "error", err)
+				writeProblem(w, r, http.StatusInternalServerError, "Failed to authenticate request")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
+
+// Require refuses anonymous requests and places the principal into the request context
+func (a *Authenticator) Require(next http.Handler) http.Handler {
+	return a.authenticate(true)(next)
+}
+
+// Identify places the principal into the request context when credentials are sent, anonymous requests pass
+func (a *Authenticator) Identify(next http.Handler) http.Handler {
+	return a.authenticate(false)(next)
+}
+
+// RequireScope refuses credentials limited to other scopes. Anonymous requests pass,
+// so it is combined with Require where they aren't allowed.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if principal, ok := auth.FromContext(r.Context()); ok && !principal.Allows(scope) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="blog", error="insufficient_scope", scope="`+scope+`"`)
+				writeProblem(w, r, http.StatusForbidden, "Credentials lack the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeUnauthorized responds with 401 and a challenge as described by RFC 6750, errCode is omitted when empty
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, errCode, detail string) {
+	challenge := `Bearer realm="blog"`
+	if errCode != "" {
+		challenge += `, error="` + errCode + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	writeProblem(w, r, http.StatusUnauthorized, detail)
+}
+
+// writeAccessError maps authentication and authorization errors of the service into problem responses
+// and reports whether err was one of them
+func writeAccessError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var denied *service.PermissionError
+	switch {
+	case errors.Is(err, service.ErrUnauthenticated):
+		writeUnauthorized(w, r, "", "Bearer token or API key required")
+	case errors.As(err, &denied):
+		writeProblem(w, r, http.StatusForbidden, "Your roles don't permit "+string(denied.Action))
+	case errors.Is(err, service.ErrForbidden):
+		writeProblem(w, r, http.StatusForbidden, "Permission denied")
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	oaerrors "github.com/go-openapi/errors"
+	"github.com/go-openapi/strfmt"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/service"
+)
+
+// writeAuthorError maps author errors of the service into problem responses and reports whether err was one of them.
+// field and in locate the author reference of the request for validation problems.
+func writeAuthorError(w http.ResponseWriter, r *http.Request, err error, field, in string) bool {
+	switch {
+	case errors.Is(err, service.ErrAuthorsDisabled):
+		writeProblem(w, r, http.StatusNotImplemented, "Authors are not enabled")
+	case errors.Is(err, service.ErrAuthorNotFound):
+		writeProblem(w, r, http.StatusNotFound, "Author not found")
+	case errors.Is(err, service.ErrUnknownAuthor):
+		writeProblem(w, r, http.StatusBadRequest, "Unknown author", &models.ProblemFieldError{
+			Name:    field,
+			In:      in,
+			Message: field + " in " + in + " references an author that does not exist",
+		})
+	case errors.Is(err, service.ErrAuthorNameTaken):
+		writeProblem(w, r, http.StatusConflict, "Author name is used by another author")
+	case errors.Is(err, service.ErrAuthorInUse):
+		writeProblem(w, r, http.StatusConflict, "Author has posts")
+	default:
+		return writeAccessError(w, r, err)
+	}
+	return true
+}
+
+// validatePost validates the post model. Author is required unless the post references an author record by author_id.
+func validatePost(post *models.Post) error {
+	var res []error
+	if err := post.Validate(strfmt.NewFormats()); err != nil {
+		res = append(res, err)
+	}
+	if post.Author == "" && post.AuthorID == 0 {
+		res = append(res, oaerrors.Required("author", "body", post.Author))
+	}
+
+	if len(res) > 0 {
+		return oaerrors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+// authorID parses the {id} URL parameter of author routes
+func authorID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+}
+
+func (h *Handler) GetAuthors(w http.ResponseWriter, r *http.Request) {
+	authors, err := h.service.GetAuthors(r.Context())
+	if err != nil {
+		if !writeAuthorError(w, r, err, "", "") {
+			h.log(r.Context()).Error("failed to get authors", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve authors")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, authorMediaTypes, authors)
+}
+
+func (h *Handler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	var author models.Author
+	if err := decodeBody(r, &author); err != nil {
+		h.log(r.Context()).Error("author decode failed", "error", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	if err := author.Validate(strfmt.NewFormats()); err != nil {
+		h.log(r.Context()).Error("invalid author format", "error", err)
+		writeValidationProblem(w, r, "Invalid author format", err)
+		return
+	}
+
+	created, err := h.service.CreateAuthor(r.Context(), author)
+	if err != nil {
+		if !writeAuthorError(w, r, err, "", "") {
+			h.log(r.Context()).Error("failed to create author", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to create author")
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/authors/"+strconv.FormatInt(created.ID, 10))
+	h.respond(w, r, http.StatusCreated, authorMediaTypes, created)
+}
+
+func (h *Handler) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := authorID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	author, err := h.service.GetAuthorByID(r.Context(), id)
+	if err != nil {
+		if !writeAuthorError(w, r, err, "", "") {
+			h.log(r.Context()).Error("failed to get author", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve author")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, authorMediaTypes, author)
+}
+
+// UpdateAuthor replaces the author profile, a new name shows up on every post of the author
+func (h *Handler) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := authorID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	var author models.Author
+	if err := decodeBody(r, &author); err != nil {
+		h.log(r.Context()).Error("author decode failed", "error", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+	author.ID = id
+
+	if err := author.Validate(strfmt.NewFormats()); err != nil {
+		h.log(r.Context()).Error("invalid author format", "error", err)
+		writeValidationProblem(w, r, "Invalid author format", err)
+		return
+	}
+
+	updated, err := h.service.UpdateAuthor(r.Context(), author)
+	if err != nil {
+		if !writeAuthorError(w, r, err, "", "") {
+			h.log(r.Context()).Error("failed to update author", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to update author")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, authorMediaTypes, updated)
+}
+
+// DeleteAuthor removes an author without posts
+func (h *Handler) DeleteAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := authorID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	if err := h.service.DeleteAuthor(r.Context(), id); err != nil {
+		if !writeAuthorError(w, r, err, "", "") {
+			h.log(r.Context()).Error("failed to delete author", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to delete author")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
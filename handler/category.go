@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-openapi/strfmt"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/service"
+)
+
+// writeCategoryError maps category errors of the service into problem responses and reports whether err was one of them.
+// field and in locate the category reference of the request for validation problems.
+func writeCategoryError(w http.ResponseWriter, r *http.Request, err error, field, in string) bool {
+	switch {
+	case errors.Is(err, service.ErrCategoriesDisabled):
+		writeProblem(w, r, http.StatusNotImplemented, "Categories are not enabled")
+	case errors.Is(err, service.ErrCategoryNotFound):
+		writeProblem(w, r, http.StatusNotFound, "Category not found")
+	case errors.Is(err, service.ErrUnknownCategory):
+		writeProblem(w, r, http.StatusBadRequest, "Unknown category", &models.ProblemFieldError{
+			Name:    field,
+			In:      in,
+			Message: field + " in " + in + " references a category that does not exist",
+		})
+	case errors.Is(err, service.ErrCategoryCycle):
+		writeProblem(w, r, http.StatusBadRequest, "Category cannot be moved below itself", &models.ProblemFieldError{
+			Name:    field,
+			In:      in,
+			Message: field + " in " + in + " must not be the category itself or one of its descendants",
+		})
+	case errors.Is(err, service.ErrCategoryInUse):
+		writeProblem(w, r, http.StatusConflict, "Category has subcategories or posts")
+	default:
+		return writeAccessError(w, r, err)
+	}
+	return true
+}
+
+// categoryID parses the {id} URL parameter of category routes
+func categoryID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+}
+
+func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.service.GetCategories(r.Context())
+	if err != nil {
+		if !writeCategoryError(w, r, err, "", "") {
+			h.log(r.Context()).Error("failed to get categories", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve categories")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, categoryMediaTypes, categories)
+}
+
+func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category models.Category
+	if err := decodeBody(r, &category); err != nil {
+		h.log(r.Context()).Error("category decode failed", "error", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	if err := category.Validate(strfmt.NewFormats()); err != nil {
+		h.log(r.Context()).Error("invalid category format", "error", err)
+		writeValidationProblem(w, r, "Invalid category format", err)
+		return
+	}
+
+	created, err := h.service.CreateCategory(r.Context(), category)
+	if err != nil {
+		if !writeCategoryError(w, r, err, "parent_id", "body") {
+			h.log(r.Context()).Error("failed to create category", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to create category")
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/categories/"+strconv.FormatInt(created.ID, 10))
+	h.respond(w, r, http.StatusCreated, categoryMediaTypes, created)
+}
+
+func (h *Handler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := categoryID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	category, err := h.service.GetCategoryByID(r.Context(), id)
+	if err != nil {
+		if !writeCategoryError(w, r, err, "", "") {
+			h.log(r.Context()).Error("failed to get category", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve category")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, categoryMediaTypes, category)
+}
+
+// UpdateCategory renames the category or moves it to another parent
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := categoryID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	var category models.Category
+	if err := decodeBody(r, &category); err != nil {
+		h.log(r.Context()).Error("category decode failed", "error", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+	category.ID = id
+
+	if err := category.Validate(strfmt.NewFormats()); err != nil {
+		h.log(r.Context()).Error("invalid category format", "error", err)
+		writeValidationProblem(w, r, "Invalid category format", err)
+		return
+	}
+
+	updated, err := h.service.UpdateCategory(r.Context(), category)
+	if err != nil {
+		if !writeCategoryError(w, r, err, "parent_id", "body") {
+			h.log(r.Context()).Error("failed to update category", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to update category")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, categoryMediaTypes, updated)
+}
+
+// DeleteCategory removes a category without subcategories and posts
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := categoryID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	if err := h.service.DeleteCategory(r.Context(), id); err != nil {
+		if !writeCategoryError(w, r, err, "", "") {
+			h.log(r.Context()).Error("failed to delete category", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to delete category")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,569 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+
+	"rakia_blog_tt/handler/models"
+)
+
+const (
+	mediaTypeJSON    = "application/json"
+	mediaTypeXML     = "application/xml"
+	mediaTypeYAML    = "application/yaml"
+	mediaTypeCSV     = "text/csv"
+	mediaTypeMsgPack = "application/msgpack"
+)
+
+var (
+	errNotAcceptable        = errors.New("not acceptable")
+	errUnsupportedMediaType = errors.New("unsupported media type")
+	errTrailingData         = errors.New("unexpected data after the document")
+)
+
+// unknownFieldError is returned for request bodies with fields the model doesn't have
+type unknownFieldError struct {
+	Field string
+}
+
+func (e *unknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+// mediaTypeAliases maps widespread non-canonical media types to the ones we serve
+var mediaTypeAliases = map[string]string{
+	"text/json":               mediaTypeJSON,
+	"text/xml":                mediaTypeXML,
+	"application/x-yaml":      mediaTypeYAML,
+	"text/yaml":               mediaTypeYAML,
+	"text/x-yaml":             mediaTypeYAML,
+	"application/x-msgpack":   mediaTypeMsgPack,
+	"application/vnd.msgpack": mediaTypeMsgPack,
+	"application/csv":         mediaTypeCSV,
+}
+
+// Representations offered by the handlers, in the order of server preference
+var (
+	postListMediaTypes = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeCSV, mediaTypeMsgPack}
+	postMediaTypes     = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+	bodyDecodableTypes = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+	tagListMediaTypes  = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+	categoryMediaTypes = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+	authorMediaTypes   = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+	commentMediaTypes  = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+	moderationTypes    = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+	apiKeyMediaTypes   = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+	previewMediaTypes  = []string{mediaTypeJSON, mediaTypeXML, mediaTypeYAML, mediaTypeMsgPack}
+)
+
+// postXML, postListXML and the like give lower case root elements in XML representation
+type postXML struct {
+	XMLName xml.Name `xml:"post"`
+	models.Post
+}
+
+type postListXML struct {
+	XMLName xml.Name      `xml:"posts"`
+	Posts   []models.Post `xml:"post"`
+}
+
+type categoryXML struct {
+	XMLName xml.Name `xml:"category"`
+	models.Category
+}
+
+type categoryListXML struct {
+	XMLName    xml.Name          `xml:"categories"`
+	Categories []models.Category `xml:"category"`
+}
+
+type authorXML struct {
+	XMLName xml.Name `xml:"author"`
+	models.Author
+}
+
+type authorListXML struct {
+	XMLName xml.Name        `xml:"authors"`
+	Authors []models.Author `xml:"author"`
+}
+
+type commentXML struct {
+	XMLName xml.Name `xml:"comment"`
+	models.Comment
+}
+
+type commentListXML struct {
+	XMLName  xml.Name         `xml:"comments"`
+	Comments []models.Comment `xml:"comment"`
+}
+
+type commentModerationXML struct {
+	XMLName xml.Name `xml:"moderation"`
+	models.CommentModeration
+}
+
+type commentModerationResultXML struct {
+	XMLName xml.Name `xml:"moderation_result"`
+	models.CommentModerationResult
+}
+
+type apiKeyXML struct {
+	XMLName xml.Name `xml:"api_key"`
+	models.APIKey
+}
+
+type apiKeyListXML struct {
+	XMLName xml.Name        `xml:"api_keys"`
+	APIKeys []models.APIKey `xml:"api_key"`
+}
+
+type previewXML struct {
+	XMLName xml.Name `xml:"preview"`
+	models.Preview
+}
+
+type tagListXML struct {
+	XMLName xml.Name     `xml:"tags"`
+	Tags    []models.Tag `xml:"tag"`
+}
+
+// postCSVColumns is the column order of the CSV representation of a post list
+var postCSVColumns = []string{"id", "title", "content", "author", "tags"}
+
+func canonicalMediaType(mediaType string) string {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if alias, ok := mediaTypeAliases[mediaType]; ok {
+		return alias
+	}
+	return mediaType
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate picks the best of the offered media types for the Accept header value.
+// An empty header accepts anything, ties are resolved by the order of offers.
+func negotiate(accept string, offers []string) (string, error) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], nil
+	}
+
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: canonicalMediaType(mediaType), q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, ar := range ranges {
+			if s := matchSpecificity(ar.mediaType, offer); s > specificity {
+				q, specificity = ar.q, s
+			}
+		}
+		if specificity >= 0 && q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	if best == "" {
+		return "", errNotAcceptable
+	}
+	return best, nil
+}
+
+// matchSpecificity returns how specifically the media range matches the media type, -1 means no match
+func matchSpecificity(mediaRange, mediaType string) int {
+	if mediaRange == mediaType {
+		return 2
+	}
+	if mediaRange == "*/*" {
+		return 0
+	}
+	rangeType, rangeSubtype, _ := strings.Cut(mediaRange, "/")
+	offerType, _, _ := strings.Cut(mediaType, "/")
+	if rangeSubtype == "*" && rangeType == offerType {
+		return 1
+	}
+	return -1
+}
+
+// requestMediaType returns the canonical media type of the request body, a body without Content-Type is unsupported
+func requestMediaType(r *http.Request, supported []string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", errUnsupportedMediaType
+	}
+	mediaType = canonicalMediaType(mediaType)
+
+	for _, s := range supported {
+		if s == mediaType {
+			return mediaType, nil
+		}
+	}
+	return "", errUnsupportedMediaType
+}
+
+// decodeBody reads a post, a category, an author, a comment, a moderation decision, an API key or a preview from the
+// request body in the format given by Content-Type. Unknown fields and data after the document are rejected, the size
+// of the body is up to the BodyLimit middleware.
+func decodeBody(r *http.Request, v interface{}) error {
+	mediaType, err := requestMediaType(r, bodyDecodableTypes)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	switch mediaType {
+	case mediaTypeXML:
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		decode := func(doc interface{}) error {
+			if err := dec.Decode(doc); err != nil {
+				return err
+			}
+			return endOfXML(dec)
+		}
+		switch value := v.(type) {
+		case *models.Post:
+			doc := postXML{}
+			if err := decode(&doc); err != nil {
+				return err
+			}
+			*value = doc.Post
+			return nil
+		case *models.Category:
+			doc := categoryXML{}
+			if err := decode(&doc); err != nil {
+				return err
+			}
+			*value = doc.Category
+			return nil
+		case *models.Author:
+			doc := authorXML{}
+			if err := decode(&doc); err != nil {
+				return err
+			}
+			*value = doc.Author
+			return nil
+		case *models.Comment:
+			doc := commentXML{}
+			if err := decode(&doc); err != nil {
+				return err
+			}
+			*value = doc.Comment
+			return nil
+		case *models.CommentModeration:
+			doc := commentModerationXML{}
+			if err := decode(&doc); err != nil {
+				return err
+			}
+			*value = doc.CommentModeration
+			return nil
+		case *models.APIKey:
+			doc := apiKeyXML{}
+			if err := decode(&doc); err != nil {
+				return err
+			}
+			*value = doc.APIKey
+			return nil
+		case *models.Preview:
+			doc := previewXML{}
+			if err := decode(&doc); err != nil {
+				return err
+			}
+			*value = doc.Preview
+			return nil
+		}
+		return decode(v)
+	case mediaTypeYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		var generic interface{}
+		if err := dec.Decode(&generic); err != nil {
+			return err
+		}
+		if err := endOfDocument(func() error { return dec.Decode(new(interface{})) }); err != nil {
+			return err
+		}
+		return fromGeneric(generic, v)
+	case mediaTypeMsgPack:
+		dec := msgpack.NewDecoder(bytes.NewReader(data))
+		var generic interface{}
+		if err := dec.Decode(&generic); err != nil {
+			return err
+		}
+		if err := endOfDocument(func() error { return dec.Decode(new(interface{})) }); err != nil {
+			return err
+		}
+		return fromGeneric(generic, v)
+	default:
+		return decodeJSON(data, v)
+	}
+}
+
+// decodeJSON reads a single JSON document into v, rejecting fields v doesn't have
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		// The decoder has no error type of its own for unknown fields
+		if field, ok := strings.CutPrefix(err.Error(), `json: unknown field "`); ok {
+			return &unknownFieldError{Field: strings.TrimSuffix(field, `"`)}
+		}
+		return err
+	}
+	return endOfDocument(func() error { return dec.Decode(new(json.RawMessage)) })
+}
+
+// endOfDocument checks nothing but white space follows the document, next decodes whatever follows
+func endOfDocument(next func() error) error {
+	if err := next(); errors.Is(err, io.EOF) {
+		return nil
+	}
+	return errTrailingData
+}
+
+// endOfXML checks nothing but white space and comments follow the root element
+func endOfXML(dec *xml.Decoder) error {
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return errTrailingData
+		}
+		switch t := token.(type) {
+		case xml.Comment:
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return errTrailingData
+			}
+		default:
+			return errTrailingData
+		}
+	}
+}
+
+// writeDecodeError responds to a request body which couldn't be decoded
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, errUnsupportedMediaType):
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "Supported media types: "+strings.Join(bodyDecodableTypes, ", "))
+	case errors.As(err, &tooLarge):
+		writeTooLarge(w, r, tooLarge.Limit)
+	default:
+		writeValidationProblem(w, r, "Invalid request format", err)
+	}
+}
+
+// contentType returns the Content-Type header value for the media type
+func contentType(mediaType string) string {
+	if strings.HasPrefix(mediaType, "text/") {
+		return mediaType + "; charset=utf-8"
+	}
+	return mediaType
+}
+
+// encode writes v in the given media type. Posts, categories, authors, comments, API keys and their lists, previews, tag lists and moderation results are the only values
+// with XML representations, only post lists have a CSV one.
+func encode(w io.Writer, mediaType string, v interface{}) error {
+	switch mediaType {
+	case mediaTypeXML:
+		switch value := v.(type) {
+		case models.Post:
+			v = postXML{Post: value}
+		case []models.Post:
+			v = postListXML{Posts: value}
+		case []models.Tag:
+			v = tagListXML{Tags: value}
+		case models.Category:
+			v = categoryXML{Category: value}
+		case []models.Category:
+			v = categoryListXML{Categories: value}
+		case models.Author:
+			v = authorXML{Author: value}
+		case []models.Author:
+			v = authorListXML{Authors: value}
+		case models.Comment:
+			v = commentXML{Comment: value}
+		case []models.Comment:
+			v = commentListXML{Comments: value}
+		case models.CommentModerationResult:
+			v = commentModerationResultXML{CommentModerationResult: value}
+		case models.APIKey:
+			v = apiKeyXML{APIKey: value}
+		case []models.APIKey:
+			v = apiKeyListXML{APIKeys: value}
+		case models.Preview:
+			v = previewXML{Preview: value}
+		}
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return err
+		}
+		return xml.NewEncoder(w).Encode(v)
+	case mediaTypeYAML:
+		generic, err := toGeneric(v)
+		if err != nil {
+			return err
+		}
+		return yaml.NewEncoder(w).Encode(generic)
+	case mediaTypeMsgPack:
+		generic, err := toGeneric(v)
+		if err != nil {
+			return err
+		}
+		return msgpack.NewEncoder(w).Encode(generic)
+	case mediaTypeCSV:
+		return encodeCSV(w, v)
+	default:
+		return json.NewEncoder(w).Encode(v)
+	}
+}
+
+func encodeCSV(w io.Writer, v interface{}) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	rows, ok := generic.([]interface{})
+	if !ok {
+		return errors.Errorf("csv representation requires a list, got %T", v)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(postCSVColumns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		fields, _ := row.(map[string]interface{})
+		record := make([]string, len(postCSVColumns))
+		for i, column := range postCSVColumns {
+			record[i] = csvValue(fields[column])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, csvValue(item))
+		}
+		return strings.Join(values, ";")
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// toGeneric converts v into maps, slices and scalars using its JSON representation,
+// so every format shares JSON field names.
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(generic), nil
+}
+
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	}
+	return value
+}
+
+// fromGeneric fills v from a decoded YAML or MessagePack document through its JSON representation
+func fromGeneric(generic interface{}, v interface{}) error {
+	data, err := json.Marshal(stringKeys(generic))
+	if err != nil {
+		return fmt.Errorf("convert document: %w", err)
+	}
+	return decodeJSON(data, v)
+}
+
+// stringKeys converts map[interface{}]interface{} produced by some decoders into JSON compatible maps
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return converted
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = stringKeys(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringKeys(item)
+		}
+	}
+	return value
+}
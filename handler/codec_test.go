@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/handler/models"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		offers   []string
+		expected string
+		err      error
+	}{
+		{"empty accept uses first offer", "", postListMediaTypes, mediaTypeJSON, nil},
+		{"wildcard", "*/*", postListMediaTypes, mediaTypeJSON, nil},
+		{"exact match", "application/xml", postListMediaTypes, mediaTypeXML, nil},
+		{"alias", "application/x-yaml", postListMediaTypes, mediaTypeYAML, nil},
+		{"quality wins", "application/json;q=0.5, text/csv", postListMediaTypes, mediaTypeCSV, nil},
+		{"subtype wildcard", "text/*", postListMediaTypes, mediaTypeCSV, nil},
+		{"specific range overrides wildcard", "*/*;q=0.1, application/json;q=0", postMediaTypes, mediaTypeXML, nil},
+		{"not offered", "text/csv", postMediaTypes, "", errNotAcceptable},
+		{"unknown type", "image/png", postListMediaTypes, "", errNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mediaType, err := negotiate(tt.accept, tt.offers)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.expected, mediaType)
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		err         error
+	}{
+		{"json", mediaTypeJSON, `{"title": "Title"}` + "\n", nil},
+		{"json with trailing data", mediaTypeJSON, `{"title": "Title"} {}`, errTrailingData},
+		{"json with trailing garbage", mediaTypeJSON, `{"title": "Title"}]`, errTrailingData},
+		{"xml", mediaTypeXML, "<post><title>Title</title></post>\n<!-- end -->", nil},
+		{"xml with trailing data", mediaTypeXML, "<post><title>Title</title></post><post></post>", errTrailingData},
+		{"yaml", mediaTypeYAML, "title: Title\n", nil},
+		{"yaml with a second document", mediaTypeYAML, "title: Title\n---\ntitle: Other\n", errTrailingData},
+		{"missing content type", "", `{"title": "Title"}`, errUnsupportedMediaType},
+		{"unsupported content type", "text/plain", `{"title": "Title"}`, errUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			var post models.Post
+			err := decodeBody(req, &post)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err == nil {
+				assert.Equal(t, "Title", post.Title)
+			}
+		})
+	}
+
+	t.Run("unknown fields", func(t *testing.T) {
+		for contentType, body := range map[string]string{
+			mediaTypeJSON: `{"title": "Title", "titel": "Typo"}`,
+			mediaTypeYAML: "title: Title\ntitel: Typo\n",
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			var unknown *unknownFieldError
+			require.ErrorAs(t, decodeBody(req, &models.Post{}), &unknown, contentType)
+			assert.Equal(t, "titel", unknown.Field)
+		}
+	})
+}
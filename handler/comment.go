@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/middleware"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/service"
+)
+
+const (
+	defaultCommentsPerPage = 20
+	maxCommentsPerPage     = 100
+
+	// maxCommentLength is the maxLength of the content of comments in the API spec
+	maxCommentLength = 10000
+)
+
+// writeCommentError maps comment errors of the service into problem responses and reports whether err was one of them
+func writeCommentError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrCommentsDisabled):
+		writeProblem(w, r, http.StatusNotImplemented, "Comments are not enabled")
+	case errors.Is(err, service.ErrPostNotFound):
+		writeProblem(w, r, http.StatusNotFound, "Post not found")
+	case errors.Is(err, service.ErrCommentNotFound):
+		writeProblem(w, r, http.StatusNotFound, "Comment not found")
+	case errors.Is(err, service.ErrUnknownParentComment):
+		writeProblem(w, r, http.StatusBadRequest, "Unknown parent comment", &models.ProblemFieldError{
+			Name:    "parent_id",
+			In:      "body",
+			Message: "parent_id in body references a comment of the post that does not exist",
+		})
+	case errors.Is(err, service.ErrNotCommentAuthor):
+		writeProblem(w, r, http.StatusForbidden, "Only the author may change the comment")
+	default:
+		return writeAccessError(w, r, err)
+	}
+	return true
+}
+
+// commentIDs parses the {id} and {commentID} URL parameters of comment routes
+func commentIDs(r *http.Request) (postID, commentID int64, err error) {
+	if postID, err = strconv.ParseInt(chi.URLParam(r, "id"), 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if value := chi.URLParam(r, "commentID"); value != "" {
+		if commentID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	return postID, commentID, nil
+}
+
+// pagination reads ?page= and ?per_page=, both must be positive integers when given
+func pagination(r *http.Request) (page, perPage int, fieldErr *models.ProblemFieldError) {
+	page, perPage = 1, defaultCommentsPerPage
+
+	query := r.URL.Query()
+	if value := query.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, &models.ProblemFieldError{Name: "page", In: "query", Message: "page in query must be a positive integer"}
+		}
+		page = parsed
+	}
+	if value := query.Get("per_page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxCommentsPerPage {
+			return 0, 0, &models.ProblemFieldError{
+				Name:    "per_page",
+				In:      "query",
+				Message: fmt.Sprintf("per_page in query must be an integer between 1 and %d", maxCommentsPerPage),
+			}
+		}
+		perPage = parsed
+	}
+	return page, perPage, nil
+}
+
+// setPageLinks adds X-Total-Count and RFC 8288 Link headers pointing to neighbouring pages
+func setPageLinks(w http.ResponseWriter, r *http.Request, page, perPage, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	lastPage := (total + perPage - 1) / perPage
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	link := func(p int, rel string) string {
+		query := url.Values{}
+		for key, values := range r.URL.Query() {
+			query[key] = values
+		}
+		query.Set("page", strconv.Itoa(p))
+		query.Set("per_page", strconv.Itoa(perPage))
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+	}
+
+	links := []string{link(1, "first")}
+	if page > 1 {
+		links = append(links, link(page-1, "prev"))
+	}
+	if page < lastPage {
+		links = append(links, link(page+1, "next"))
+	}
+	links = append(links, link(lastPage, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// clientIP returns the address of the client as resolved by the rate limiter, through trusted proxies only.
+// Without a rate limiter it's the connected address, forwarding headers are ignored, as they can be forged.
+func clientIP(r *http.Request) string {
+	if addr, ok := middleware.ClientAddr(r.Context()); ok {
+		return addr.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// validateComment validates a new comment against the model
+func validateComment(comment *models.Comment) error {
+	return comment.Validate(strfmt.NewFormats())
+}
+
+// validateCommentEdit validates only the content of an edit, the rest of the comment is kept as stored.
+// The limit is that of content in the Comment model.
+func validateCommentEdit(comment *models.Comment) error {
+	if err := validate.RequiredString("content", "body", comment.Content); err != nil {
+		return err
+	}
+	if err := validate.MaxLength("content", "body", comment.Content, maxCommentLength); err != nil {
+		return err
+	}
+	return nil
+}
+
+// decodeComment reads a comment from the request body and checks it with valid, it writes the problem response on
+// failure
+func (h *Handler) decodeComment(w http.ResponseWriter, r *http.Request, comment *models.Comment, valid func(*models.Comment) error) bool {
+	if err := decodeBody(r, comment); err != nil {
+		h.log(r.Context()).Error("comment decode failed", "error", err)
+		writeDecodeError(w, r, err)
+		return false
+	}
+
+	if err := valid(comment); err != nil {
+		h.log(r.Context()).Error("invalid comment format", "error", err)
+		writeValidationProblem(w, r, "Invalid comment format", err)
+		return false
+	}
+	return true
+}
+
+// GetComments returns a page of comment threads of the post, replies are nested into their parents
+func (h *Handler) GetComments(w http.ResponseWriter, r *http.Request) {
+	postID, _, err := commentIDs(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+	page, perPage, fieldErr := pagination(r)
+	if fieldErr != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid pagination", fieldErr)
+		return
+	}
+
+	result, err := h.service.GetComments(r.Context(), postID, page, perPage)
+	if err != nil {
+		if !writeCommentError(w, r, err) {
+			h.log(r.Context()).Error("failed to get comments", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve comments")
+		}
+		return
+	}
+
+	setPageLinks(w, r, page, perPage, result.Total)
+	h.respond(w, r, http.StatusOK, commentMediaTypes, result.Threads)
+}
+
+// CreateComment adds a comment or, with parent_id, a reply to the post.
+// Moderated comments are returned with the pending or spam status and show up publicly once approved.
+func (h *Handler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	postID, _, err := commentIDs(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var comment models.Comment
+	if !h.decodeComment(w, r, &comment, validateComment) {
+		return
+	}
+
+	created, err := h.service.CreateComment(r.Context(), postID, comment, clientIP(r))
+	if err != nil {
+		if !writeCommentError(w, r, err) {
+			h.log(r.Context()).Error("failed to create comment", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to create comment")
+		}
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/posts/%d/comments/%d", postID, created.ID))
+	h.respond(w, r, http.StatusCreated, commentMediaTypes, created)
+}
+
+// UpdateComment replaces the content of a comment the authenticated client wrote
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	postID, commentID, err := commentIDs(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	if _, ok := auth.FromContext(r.Context()); !ok {
+		writeAccessError(w, r, service.ErrUnauthenticated)
+		return
+	}
+
+	// Only the content changes, the stored author is kept
+	var comment models.Comment
+	if !h.decodeComment(w, r, &comment, validateCommentEdit) {
+		return
+	}
+	comment.ID = commentID
+
+	updated, err := h.service.UpdateComment(r.Context(), postID, comment, clientIP(r))
+	if err != nil {
+		if !writeCommentError(w, r, err) {
+			h.log(r.Context()).Error("failed to update comment", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to update comment")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, commentMediaTypes, updated)
+}
+
+// DeleteComment removes a comment the authenticated client wrote
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	postID, commentID, err := commentIDs(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	if err := h.service.DeleteComment(r.Context(), postID, commentID); err != nil {
+		if !writeCommentError(w, r, err) {
+			h.log(r.Context()).Error("failed to delete comment", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to delete comment")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,15 +1,22 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-openapi/strfmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/logging"
 	"rakia_blog_tt/service"
 )
 
@@ -25,6 +32,11 @@ type Handler struct {
 	logger  *slog.Logger
 }
 
+// log returns the logger of the request, lines carry its ID
+func (h *Handler) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, h.logger)
+}
+
 func (h *Handler) DefaultHandler(w http.ResponseWriter, req *http.Request) {
 	respText := map[string]string{"response": "test BE response"}
 	rj, _ := json.Marshal(respText)
@@ -34,92 +46,425 @@ func (h *Handler) DefaultHandler(w http.ResponseWriter, req *http.Request) {
 	w.Write(rj) // nolint:errcheck
 }
 
+// respond writes v in the representation negotiated from the Accept header.
+// The body is encoded before the status is sent, so encoding failures still result in a proper error response.
+func (h *Handler) respond(w http.ResponseWriter, r *http.Request, status int, offers []string, v interface{}) {
+	w.Header().Add("Vary", "Accept")
+
+	mediaType, err := negotiate(r.Header.Get("Accept"), offers)
+	if err != nil {
+		writeProblem(w, r, http.StatusNotAcceptable, "Supported media types: "+strings.Join(offers, ", "))
+		return
+	}
+
+	var body bytes.Buffer
+	if err := encode(&body, mediaType, v); err != nil {
+		h.log(r.Context()).Error("response encode failed", "error", err, "media_type", mediaType)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType(mediaType))
+	w.WriteHeader(status)
+	w.Write(body.Bytes()) // nolint:errcheck
+}
+
+// renderRequested reports whether the client asked for content rendered into HTML with ?render=html
+func renderRequested(r *http.Request) (bool, error) {
+	switch value := r.URL.Query().Get("render"); value {
+	case "":
+		return false, nil
+	case "html":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported render value %q", value)
+	}
+}
+
+// renderContent fills content_html of the posts, writing an error response on failure
+func (h *Handler) renderContent(w http.ResponseWriter, r *http.Request, posts []models.Post) bool {
+	for i := range posts {
+		if err := h.service.RenderContent(r.Context(), &posts[i]); err != nil {
+			switch {
+			case errors.Is(err, service.ErrRenderingDisabled):
+				writeProblem(w, r, http.StatusBadRequest, "HTML rendering is disabled")
+			case !writeAccessError(w, r, err):
+				h.log(r.Context()).Error("failed to render post", "error", err, "post_id", posts[i].ID)
+				writeProblem(w, r, http.StatusInternalServerError, "Failed to render post")
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// tagFilter parses ?tag= filter of the post list. Tags may be repeated or comma separated,
+// ?match=all requires every tag while the default ?match=any requires at least one.
+func tagFilter(r *http.Request) (tags []string, matchAll bool, err error) {
+	query := r.URL.Query()
+	for _, value := range query["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	switch match := query.Get("match"); match {
+	case "", "any":
+		return tags, false, nil
+	case "all":
+		return tags, true, nil
+	default:
+		return nil, false, fmt.Errorf("unsupported match value %q", match)
+	}
+}
+
+// postFilter parses filters of the post list: tags, ?category= matching the category with its descendants
+// and ?status= matching the workflow status
+func postFilter(r *http.Request) (service.PostFilter, error) {
+	tags, matchAll, err := tagFilter(r)
+	if err != nil {
+		return service.PostFilter{}, err
+	}
+	filter := service.PostFilter{Tags: tags, MatchAllTags: matchAll}
+
+	if value := r.URL.Query().Get("category"); value != "" {
+		filter.CategoryID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || filter.CategoryID <= 0 {
+			return service.PostFilter{}, fmt.Errorf("invalid category value %q", value)
+		}
+	}
+	if value := r.URL.Query().Get("status"); value != "" {
+		if !slices.Contains(postStatuses, value) {
+			return service.PostFilter{}, fmt.Errorf("unsupported status value %q", value)
+		}
+		filter.Status = value
+	}
+
+	return filter, nil
+}
+
 func (h *Handler) GetPosts(w http.ResponseWriter, r *http.Request) {
-	posts, err := h.service.GetPosts()
+	render, err := renderRequested(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := postFilter(r)
 	if err != nil {
-		h.logger.Error("failed to get posts", "error", err)
-		http.Error(w, "Failed to retrieve posts", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
-	json.NewEncoder(w).Encode(posts) // nolint:errcheck
+
+	posts, err := h.service.FindPosts(r.Context(), filter)
+	if writeCategoryError(w, r, err, "category", "query") {
+		return
+	}
+	if err != nil {
+		h.log(r.Context()).Error("failed to get posts", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve posts")
+		return
+	}
+
+	if render && !h.renderContent(w, r, posts) {
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, postListMediaTypes, posts)
+}
+
+// GetTags lists tags in use with their post counts
+func (h *Handler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.service.GetTags(r.Context())
+	if writeAccessError(w, r, err) {
+		return
+	}
+	if err != nil {
+		h.log(r.Context()).Error("failed to get tags", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve tags")
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, tagListMediaTypes, tags)
+}
+
+// GetTagPosts lists posts having the tag
+func (h *Handler) GetTagPosts(w http.ResponseWriter, r *http.Request) {
+	render, err := renderRequested(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	posts, err := h.service.GetPostsByTags(r.Context(), []string{chi.URLParam(r, "tag")}, false)
+	if writeAccessError(w, r, err) {
+		return
+	}
+	if err != nil {
+		h.log(r.Context()).Error("failed to get posts", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve posts")
+		return
+	}
+
+	if render && !h.renderContent(w, r, posts) {
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, postListMediaTypes, posts)
 }
 
 func (h *Handler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	var post models.Post
-	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
-		h.logger.Error("post decode failed", "error", err)
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+	if err := decodeBody(r, &post); err != nil {
+		h.log(r.Context()).Error("post decode failed", "error", err)
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	if err := post.Validate(strfmt.NewFormats()); err != nil {
-		h.logger.Error("invalid post format", "error", err)
-		http.Error(w, "Invalid post format", http.StatusBadRequest)
+	if err := validatePost(&post); err != nil {
+		h.log(r.Context()).Error("invalid post format", "error", err)
+		writeValidationProblem(w, r, "Invalid post format", err)
 		return
 	}
 
-	if err := h.service.CreatePost(post); err != nil {
-		h.logger.Error("failed to create post", "error", err)
-		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+	// Negotiate before storing, a not acceptable response must not leave a created post behind
+	if _, err := negotiate(r.Header.Get("Accept"), postMediaTypes); err != nil {
+		writeProblem(w, r, http.StatusNotAcceptable, "Supported media types: "+strings.Join(postMediaTypes, ", "))
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	created, err := h.service.CreatePost(r.Context(), post)
+	if writeCategoryError(w, r, err, "category_id", "body") || writeAuthorError(w, r, err, "author_id", "body") ||
+		writeWorkflowError(w, r, err) {
+		return
+	}
+	if err != nil {
+		h.log(r.Context()).Error("failed to create post", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to create post")
+		return
+	}
+
+	w.Header().Set("Location", "/posts/"+strconv.FormatInt(created.ID, 10))
+	h.respond(w, r, http.StatusCreated, postMediaTypes, created)
 }
 
 func (h *Handler) GetPost(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
-	post, err := h.service.GetPostByID(id)
+	render, err := renderRequested(r)
 	if err != nil {
-		if errors.Is(err, service.ErrPostNotFound) {
-			http.Error(w, "Post not found", http.StatusNotFound)
-		} else {
-			h.logger.Error("failed to get post", "error", err)
-			http.Error(w, "Failed to retrieve post", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	post, err := h.service.GetPostByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPostNotFound):
+			writeProblem(w, r, http.StatusNotFound, "Post not found")
+		case !writeAccessError(w, r, err):
+			h.log(r.Context()).Error("failed to get post", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve post")
+		}
+		return
+	}
+
+	if render {
+		posts := []models.Post{post}
+		if !h.renderContent(w, r, posts) {
+			return
+		}
+		post = posts[0]
+	}
+
+	h.respond(w, r, http.StatusOK, postMediaTypes, post)
+}
+
+// GetPostBySlug returns a post by its slug. Former slugs of a post are permanently redirected to the current one.
+func (h *Handler) GetPostBySlug(w http.ResponseWriter, r *http.Request) {
+	render, err := renderRequested(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	post, moved, err := h.service.GetPostBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPostNotFound):
+			writeProblem(w, r, http.StatusNotFound, "Post not found")
+		case !writeAccessError(w, r, err):
+			h.log(r.Context()).Error("failed to get post", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve post")
+		}
+		return
+	}
+
+	if moved {
+		location := "/posts/by-slug/" + post.Slug
+		if r.URL.RawQuery != "" {
+			location += "?" + r.URL.RawQuery
 		}
+		http.Redirect(w, r, location, http.StatusMovedPermanently)
 		return
 	}
-	json.NewEncoder(w).Encode(post) // nolint:errcheck
+
+	if render {
+		posts := []models.Post{post}
+		if !h.renderContent(w, r, posts) {
+			return
+		}
+		post = posts[0]
+	}
+
+	h.respond(w, r, http.StatusOK, postMediaTypes, post)
+}
+
+// writeUpdateError maps errors of service.UpdatePost into problem responses
+func (h *Handler) writeUpdateError(w http.ResponseWriter, r *http.Request, err error) {
+	if writeCategoryError(w, r, err, "category_id", "body") || writeAuthorError(w, r, err, "author_id", "body") ||
+		writeWorkflowError(w, r, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrPostNotFound):
+		writeProblem(w, r, http.StatusNotFound, "Post not found")
+	case errors.Is(err, service.ErrSlugTaken):
+		writeProblem(w, r, http.StatusConflict, "Slug is already used by another post")
+	case errors.Is(err, service.ErrPostChanged):
+		writeProblem(w, r, http.StatusConflict, "Post was changed meanwhile, fetch it and try again")
+	default:
+		h.log(r.Context()).Error("failed to update post", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to update post")
+	}
 }
 
 func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 	var post models.Post
-	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
-		h.logger.Error("post decode failed", "error", err)
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+	if err := decodeBody(r, &post); err != nil {
+		h.log(r.Context()).Error("post decode failed", "error", err)
+		writeDecodeError(w, r, err)
 		return
 	}
 	post.ID = int64(id)
-	if err := h.service.UpdatePost(post); err != nil {
-		h.logger.Error("failed to update post", "error", err)
-		http.Error(w, "Failed to update post", http.StatusInternalServerError)
+	post.Version = 0 // read only, a replacement applies to whatever version is stored
+
+	if err := validatePost(&post); err != nil {
+		h.log(r.Context()).Error("invalid post format", "error", err)
+		writeValidationProblem(w, r, "Invalid post format", err)
+		return
+	}
+
+	if err := h.service.UpdatePost(r.Context(), post); err != nil {
+		h.writeUpdateError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// PatchPost applies a partial update to a post. Both JSON Merge Patch (RFC 7396)
+// and JSON Patch (RFC 6902) documents are accepted, the media type is taken from Content-Type.
+func (h *Handler) PatchPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != mediaTypeMergePatch && mediaType != mediaTypeJSONPatch) {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "Unsupported patch media type")
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.log(r.Context()).Error("patch read failed", "error", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	post, err := h.service.GetPostByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPostNotFound):
+			writeProblem(w, r, http.StatusNotFound, "Post not found")
+		case !writeAccessError(w, r, err):
+			h.log(r.Context()).Error("failed to get post", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve post")
+		}
+		return
+	}
+
+	patched, err := applyPatch(mediaType, post, patch)
+	if err != nil {
+		h.log(r.Context()).Error("patch apply failed", "error", err)
+		switch {
+		case errors.Is(err, errInvalidPatch):
+			writeValidationProblem(w, r, "Invalid patch document", err)
+		case errors.Is(err, errPatchTestFailed):
+			writeProblem(w, r, http.StatusConflict, "Patch test operation failed")
+		default:
+			writeProblem(w, r, http.StatusUnprocessableEntity, "Patch cannot be applied to the post")
+		}
+		return
+	}
+	patched.ID = int64(id)
+	// The update applies only to the version patched, so concurrent patches don't overwrite each other
+	patched.Version = post.Version
+	// A new author name links the post to another author unless author_id was patched as well
+	if patched.Author != post.Author && patched.AuthorID == post.AuthorID {
+		patched.AuthorID = 0
+	}
+
+	if err := validatePost(&patched); err != nil {
+		h.log(r.Context()).Error("invalid post format", "error", err)
+		writeValidationProblem(w, r, "Invalid post format", err)
+		return
+	}
+
+	if err := h.service.UpdatePost(r.Context(), patched); err != nil {
+		h.writeUpdateError(w, r, err)
+		return
+	}
+
+	// Respond with the stored state, so read only fields like version are up to date
+	updated, err := h.service.GetPostByID(r.Context(), id)
+	if err != nil {
+		h.log(r.Context()).Error("failed to get post", "error", err)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve post")
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, postMediaTypes, updated)
+}
+
 func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid post ID")
 		return
 	}
 
-	if err := h.service.DeletePost(id); err != nil {
+	if err := h.service.DeletePost(r.Context(), id); err != nil {
+		if writeAccessError(w, r, err) {
+			return
+		}
 		if errors.Is(err, service.ErrPostNotFound) {
-			http.Error(w, "Post not found", http.StatusNotFound)
+			writeProblem(w, r, http.StatusNotFound, "Post not found")
 		} else {
-			h.logger.Error("failed to delete post", "error", err)
-			http.Error(w, "Failed to delete post", http.StatusInternalServerError)
+			h.log(r.Context()).Error("failed to delete post", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to delete post")
 		}
 		return
 	}
@@ -2,20 +2,33 @@ package handler
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/andybalholm/brotli"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/cors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/middleware"
 	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/handler/web"
+	"rakia_blog_tt/moderation"
+	"rakia_blog_tt/render"
 	"rakia_blog_tt/service"
+	"rakia_blog_tt/sitemap"
 	"rakia_blog_tt/storage"
 )
 
@@ -29,12 +42,87 @@ func loggerMock() *slog.Logger {
 	return slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
 }
 
-func setupTestServer() *httptest.Server {
-	logger := loggerMock()
+const testSecret = "test-secret"
+
+var testVerifier = func() *auth.Verifier {
+	verifier, err := auth.NewVerifier(auth.WithHS256Secret([]byte(testSecret)))
+	if err != nil {
+		panic(err)
+	}
+	return verifier
+}()
+
+// testToken signs the claims for the test server, tokens expire in an hour unless set otherwise
+func testToken(claims auth.Claims) string {
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+var adminToken = testToken(auth.Claims{
+	RegisteredClaims: jwt.RegisteredClaims{Subject: "admin"},
+	Roles:            []string{auth.RoleAdmin},
+})
+
+// authorize authenticates the request as an admin
+func authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+}
+
+// authorizedPost is http.Post authenticated as an admin
+func authorizedPost(url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	authorize(req)
+	return http.DefaultClient.Do(req)
+}
+
+// testMaxBodySize is the request body limit of the test server
+const testMaxBodySize = 4096
+
+func setupTestServer(opts ...service.Option) *httptest.Server {
+	return setupRoutedTestServer(loggerMock(), RouterOptions{}, opts...)
+}
+
+// setupRoutedTestServer is setupTestServer logging to logger with options of the router, the body limit is
+// testMaxBodySize unless set
+func setupRoutedTestServer(logger *slog.Logger, routerOpts RouterOptions, opts ...service.Option) *httptest.Server {
 	postRepo := storage.NewInMemoryPostRepository(logger)
-	application := service.New(postRepo, logger)
+	renderer := render.NewMarkdownRenderer(render.Options{TableOfContents: true, CacheSize: 10})
+	sitemaps := sitemap.New(sitemap.Options{
+		BaseURL: "http://blog.test",
+		PostURL: func(post models.Post) string { return web.PostURL("http://blog.test", post) },
+	})
+	categoryRepo := storage.NewInMemoryCategoryRepository(logger)
+	commentRepo := storage.NewInMemoryCommentRepository(logger)
+	authorRepo := storage.NewInMemoryAuthorRepository(logger)
+	apiKeyRepo := storage.NewInMemoryAPIKeyRepository(logger)
+	application := service.New(postRepo, logger, append([]service.Option{
+		service.WithContentRenderer(renderer),
+		service.WithPostListener(sitemaps),
+		service.WithCategoryRepo(categoryRepo),
+		service.WithCommentRepo(commentRepo),
+		service.WithAuthorRepo(authorRepo),
+		service.WithAPIKeyRepo(apiKeyRepo),
+	}, opts...)...)
 	hndl := New(application, logger)
-	router := NewRouter(hndl, logger, &metricsMock{})
+	if routerOpts.MaxBodySize == 0 {
+		routerOpts.MaxBodySize = testMaxBodySize
+	}
+	pages, err := web.New(application, logger, web.Site{Title: "Test Blog", BaseURL: "http://blog.test"}, sitemaps)
+	if err != nil {
+		panic(err)
+	}
+	router := NewRouter(hndl, pages, logger, &metricsMock{}, NewAuthenticator(testVerifier, application, logger),
+		routerOpts)
 
 	return httptest.NewServer(router)
 }
@@ -47,13 +135,21 @@ func TestIntegration_CreatePostHandler(t *testing.T) {
 		Title:   "Test Post",
 		Content: "This is a test post",
 		Author:  "Test Author",
+		Status:  models.PostStatusPublished,
 	}
 	body, err := json.Marshal(post)
 	require.NoError(t, err)
 
-	resp, err := http.Post(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
+	resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
 	require.NoError(t, err)
+	defer resp.Body.Close()
 	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "/posts/1", resp.Header.Get("Location"))
+
+	var created models.Post
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, int64(1), created.ID)
+	assert.Equal(t, "test-post", created.Slug)
 }
 
 func TestIntegration_GetPostsHandler(t *testing.T) {
@@ -65,10 +161,11 @@ func TestIntegration_GetPostsHandler(t *testing.T) {
 		Title:   "Test Post",
 		Content: "This is a test post",
 		Author:  "Test Author",
+		Status:  models.PostStatusPublished,
 	}
 	body, err := json.Marshal(post)
 	require.NoError(t, err)
-	_, err = http.Post(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
+	_, err = authorizedPost(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
 	require.NoError(t, err)
 
 	resp, err := http.Get(server.URL + "/posts")
@@ -90,10 +187,11 @@ func TestIntegration_GetPostHandler(t *testing.T) {
 		Title:   "Test Post",
 		Content: "This is a test post",
 		Author:  "Test Author",
+		Status:  models.PostStatusPublished,
 	}
 	body, err := json.Marshal(post)
 	require.NoError(t, err)
-	resp, err := http.Post(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
+	resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
 	require.NoError(t, err)
 
 	// Parse the created post ID from the response
@@ -122,10 +220,11 @@ func TestIntegration_UpdatePostHandler(t *testing.T) {
 		Title:   "Test Post",
 		Content: "This is a test post",
 		Author:  "Test Author",
+		Status:  models.PostStatusPublished,
 	}
 	body, err := json.Marshal(post)
 	require.NoError(t, err)
-	resp, err := http.Post(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
+	resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
 	require.NoError(t, err)
 	resp.Body.Close()
 
@@ -139,6 +238,7 @@ func TestIntegration_UpdatePostHandler(t *testing.T) {
 	require.NoError(t, err)
 	req, err := http.NewRequest(http.MethodPut, server.URL+"/posts/1", bytes.NewBuffer(updatedBody))
 	require.NoError(t, err)
+	authorize(req)
 	req.Header.Set("Content-Type", "application/json")
 	client := &http.Client{}
 	resp, err = client.Do(req)
@@ -167,16 +267,18 @@ func TestIntegration_DeletePostHandler(t *testing.T) {
 		Title:   "Test Post",
 		Content: "This is a test post",
 		Author:  "Test Author",
+		Status:  models.PostStatusPublished,
 	}
 	body, err := json.Marshal(post)
 	require.NoError(t, err)
-	resp, err := http.Post(fmt.Sprintf("%s/posts", server.URL), "application/json", bytes.NewBuffer(body))
+	resp, err := authorizedPost(fmt.Sprintf("%s/posts", server.URL), "application/json", bytes.NewBuffer(body))
 	require.NoError(t, err)
 	resp.Body.Close()
 
 	// Delete the created post
 	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/posts/1", server.URL), nil)
 	require.NoError(t, err)
+	authorize(req)
 	client := &http.Client{}
 	resp, err = client.Do(req)
 	require.NoError(t, err)
@@ -187,3 +289,2050 @@ func TestIntegration_DeletePostHandler(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
+
+func TestIntegration_PatchPostHandler(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	// Create a post first
+	post := models.Post{
+		Title:   "Test Post",
+		Content: "This is a test post",
+		Author:  "Test Author",
+		Status:  models.PostStatusPublished,
+	}
+	body, err := json.Marshal(post)
+	require.NoError(t, err)
+	resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	patch := func(contentType, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPatch, server.URL+"/posts/1", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Merge Patch", func(t *testing.T) {
+		resp := patch("application/merge-patch+json", `{"title": "Merged Title"}`)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var patchedPost models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&patchedPost))
+		assert.Equal(t, "Merged Title", patchedPost.Title)
+		assert.Equal(t, post.Content, patchedPost.Content)
+		assert.Equal(t, post.Author, patchedPost.Author)
+	})
+
+	t.Run("JSON Patch", func(t *testing.T) {
+		resp := patch("application/json-patch+json", `[
+			{"op": "test", "path": "/title", "value": "Merged Title"},
+			{"op": "replace", "path": "/content", "value": "Patched content"}
+		]`)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp, err := http.Get(server.URL + "/posts/1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var retrievedPost models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&retrievedPost))
+		assert.Equal(t, "Merged Title", retrievedPost.Title)
+		assert.Equal(t, "Patched content", retrievedPost.Content)
+		assert.Equal(t, post.Author, retrievedPost.Author)
+	})
+
+	t.Run("Failed JSON Patch test operation", func(t *testing.T) {
+		resp := patch("application/json-patch+json", `[{"op": "test", "path": "/title", "value": "Other"}]`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp = patch("application/json-patch+json", `[{"op": "test", "path": "/missing", "value": "Other"}]`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, "a missing value doesn't equal any")
+	})
+
+	t.Run("JSON Patch operations which don't apply", func(t *testing.T) {
+		for _, body := range []string{
+			`[{"op": "replace", "path": "/missing", "value": "Other"}]`,
+			`[{"op": "remove", "path": "/tags/5"}]`,
+			`[{"op": "move", "from": "/missing", "path": "/title"}]`,
+		} {
+			resp := patch("application/json-patch+json", body)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, body)
+		}
+	})
+
+	t.Run("Version is kept", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/posts/1", nil)
+		require.NoError(t, err)
+		authorize(req)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		var before models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&before))
+		resp.Body.Close()
+
+		resp = patch("application/json-patch+json", `[{"op": "replace", "path": "/version", "value": 100}]`)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode, "patches apply to the version read")
+
+		var patchedPost models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&patchedPost))
+		assert.Equal(t, before.Version+1, patchedPost.Version)
+		assert.NotEqual(t, int64(100), patchedPost.Version)
+	})
+
+	t.Run("Invalid result is rejected", func(t *testing.T) {
+		resp := patch("application/merge-patch+json", `{"title": null}`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Unsupported media type", func(t *testing.T) {
+		resp := patch("application/json", `{"title": "Plain JSON"}`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	})
+
+	t.Run("Post not found", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPatch, server.URL+"/posts/100", bytes.NewBufferString(`{}`))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestIntegration_ProblemResponses(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	decodeProblem := func(t *testing.T, resp *http.Response) models.Problem {
+		t.Helper()
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+		var problem models.Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		assert.Equal(t, int64(resp.StatusCode), problem.Status)
+		assert.Equal(t, http.StatusText(resp.StatusCode), problem.Title)
+		assert.NotEmpty(t, problem.RequestID)
+		return problem
+	}
+
+	t.Run("Validation errors list every invalid field", func(t *testing.T) {
+		resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBufferString(`{"title": "Only title"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		problem := decodeProblem(t, resp)
+		assert.Equal(t, "/problems/validation-error", problem.Type)
+		assert.Equal(t, "/posts", problem.Instance)
+
+		var names []string
+		for _, fieldErr := range problem.Errors {
+			assert.Equal(t, "body", fieldErr.In)
+			assert.NotEmpty(t, fieldErr.Message)
+			names = append(names, fieldErr.Name)
+		}
+		assert.ElementsMatch(t, []string{"author", "content"}, names)
+	})
+
+	t.Run("Wrong field type", func(t *testing.T) {
+		resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBufferString(`{"title": 1}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		problem := decodeProblem(t, resp)
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, "title", problem.Errors[0].Name)
+	})
+
+	t.Run("Update of a missing post", func(t *testing.T) {
+		body := `{"title": "Title", "content": "Content", "author": "Author"}`
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/posts/100", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		problem := decodeProblem(t, resp)
+		assert.Equal(t, "about:blank", problem.Type)
+		assert.Equal(t, "Post not found", problem.Detail)
+		assert.NotNil(t, problem.Errors)
+	})
+
+	t.Run("Unknown route", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/unknown")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		decodeProblem(t, resp)
+	})
+
+	t.Run("Method not allowed", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodDelete, server.URL+"/posts", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		decodeProblem(t, resp)
+	})
+}
+
+func TestIntegration_RequestDecoding(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	post := `{"title": "Title", "content": "Content", "author": "Author", "status": "published"}`
+	resp, err := authorizedPost(server.URL+"/posts", "application/json", strings.NewReader(post))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	send := func(method, path, contentType string, body io.Reader) (*http.Response, models.Problem) {
+		req, err := http.NewRequest(method, server.URL+path, body)
+		require.NoError(t, err)
+		authorize(req)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var problem models.Problem
+		if resp.Header.Get("Content-Type") == "application/problem+json" {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		}
+		return resp, problem
+	}
+
+	// Every write endpoint decodes its body the same way
+	endpoints := []struct{ method, path string }{
+		{http.MethodPost, "/posts"},
+		{http.MethodPut, "/posts/1"},
+		{http.MethodPost, "/categories"},
+		{http.MethodPost, "/authors"},
+		{http.MethodPost, "/posts/1/comments"},
+		{http.MethodPost, "/admin/api-keys"},
+	}
+
+	t.Run("Unknown fields", func(t *testing.T) {
+		for _, e := range endpoints {
+			resp, problem := send(e.method, e.path, "application/json", strings.NewReader(`{"unknown": true}`))
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode, e.path)
+			require.Len(t, problem.Errors, 1, e.path)
+			assert.Equal(t, "unknown", problem.Errors[0].Name)
+			assert.Equal(t, "unknown in body is not a known field", problem.Errors[0].Message)
+		}
+	})
+
+	t.Run("Data after the document", func(t *testing.T) {
+		for _, e := range endpoints {
+			resp, _ := send(e.method, e.path, "application/json", strings.NewReader(`{"name": "Name"} {"name": "Name"}`))
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, e.path)
+		}
+	})
+
+	t.Run("Missing or unsupported Content-Type", func(t *testing.T) {
+		for _, e := range endpoints {
+			resp, problem := send(e.method, e.path, "", strings.NewReader(post))
+			assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode, e.path)
+			assert.Contains(t, problem.Detail, "Supported media types")
+			resp, _ = send(e.method, e.path, "text/plain", strings.NewReader(post))
+			assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode, e.path)
+		}
+	})
+
+	t.Run("Bodies over the limit", func(t *testing.T) {
+		large := `{"title": "Title", "content": "` + strings.Repeat("a", testMaxBodySize) + `", "author": "Author"}`
+		for _, e := range endpoints {
+			resp, problem := send(e.method, e.path, "application/json", strings.NewReader(large))
+			assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, e.path)
+			assert.Equal(t, fmt.Sprintf("Request body exceeds %d bytes", testMaxBodySize), problem.Detail)
+		}
+
+		// Without Content-Length the body is cut off while decoding
+		resp, problem := send(http.MethodPost, "/posts", "application/json", io.MultiReader(strings.NewReader(large)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		assert.Equal(t, fmt.Sprintf("Request body exceeds %d bytes", testMaxBodySize), problem.Detail)
+
+		resp, _ = send(http.MethodPatch, "/posts/1", "application/merge-patch+json", io.MultiReader(strings.NewReader(large)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	})
+
+	t.Run("Patches can't add unknown fields", func(t *testing.T) {
+		resp, problem := send(http.MethodPatch, "/posts/1", "application/merge-patch+json", strings.NewReader(`{"unknown": 1}`))
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, "unknown", problem.Errors[0].Name)
+	})
+}
+
+func TestIntegration_ContentNegotiation(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	create := func(contentType, body string) *http.Response {
+		resp, err := authorizedPost(server.URL+"/posts", contentType, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	get := func(path, accept string) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	require.Equal(t, http.StatusCreated, create("application/xml",
+		`<post><title>XML Post</title><content>XML content</content><author>XML Author</author><status>published</status></post>`).StatusCode)
+	require.Equal(t, http.StatusCreated, create("application/yaml",
+		"title: YAML Post\ncontent: YAML content\nauthor: YAML Author\nstatus: published\n").StatusCode)
+	require.Equal(t, http.StatusCreated, create("application/msgpack",
+		string(mustMsgPack(t, map[string]string{"title": "MsgPack Post", "content": "MsgPack content", "author": "MsgPack Author", "status": "published"}))).StatusCode)
+
+	t.Run("JSON by default", func(t *testing.T) {
+		resp, body := get("/posts/1", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), `"title":"XML Post"`)
+	})
+
+	t.Run("XML", func(t *testing.T) {
+		resp, body := get("/posts/2", "application/xml")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/xml", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), `<post><author>YAML Author</author>`)
+		assert.Contains(t, string(body), `<content>YAML content</content>`)
+		assert.Contains(t, string(body), `<id>2</id><publish_at>`)
+		assert.Contains(t, string(body), `<slug>yaml-post</slug><status>published</status>`)
+		assert.Contains(t, string(body), `<title>YAML Post</title>`)
+
+		resp, body = get("/posts", "text/xml")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), `<posts><post>`)
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		resp, body := get("/posts/3", "application/yaml")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), "title: MsgPack Post")
+		assert.Contains(t, string(body), "id: 3")
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		resp, body := get("/posts", "text/csv")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), "id,title,content,author,tags\n")
+		assert.Contains(t, string(body), "1,XML Post,XML content,XML Author,\n")
+	})
+
+	t.Run("MessagePack", func(t *testing.T) {
+		resp, body := get("/posts/1", "application/msgpack")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var post models.Post
+		require.NoError(t, fromMsgPack(body, &post))
+		assert.Equal(t, int64(1), post.ID)
+		assert.Equal(t, "XML Post", post.Title)
+	})
+
+	t.Run("Not acceptable", func(t *testing.T) {
+		resp, _ := get("/posts/1", "text/csv")
+		assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("Unsupported media type", func(t *testing.T) {
+		resp := create("text/csv", "title,content,author\nT,C,A\n")
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	})
+}
+
+func mustMsgPack(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	var body bytes.Buffer
+	require.NoError(t, encode(&body, mediaTypeMsgPack, v))
+	return body.Bytes()
+}
+
+func fromMsgPack(data []byte, post *models.Post) error {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	req.Header.Set("Content-Type", mediaTypeMsgPack)
+	return decodeBody(req, post)
+}
+
+func TestIntegration_RenderContent(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	post := models.Post{
+		Title:   "Markdown Post",
+		Content: "# Heading\n\nSome **bold** text <script>alert(1)</script>",
+		Author:  "Test Author",
+		Status:  models.PostStatusPublished,
+	}
+	body, err := json.Marshal(post)
+	require.NoError(t, err)
+	resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	t.Run("Raw content by default", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/posts/1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var retrievedPost models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&retrievedPost))
+		assert.Equal(t, post.Content, retrievedPost.Content)
+		assert.Empty(t, retrievedPost.ContentHTML)
+	})
+
+	t.Run("Rendered on request", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/posts/1?render=html")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var retrievedPost models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&retrievedPost))
+		assert.Equal(t, post.Content, retrievedPost.Content)
+		assert.Contains(t, retrievedPost.ContentHTML, `<h1 id="heading">Heading</h1>`)
+		assert.Contains(t, retrievedPost.ContentHTML, `<strong>bold</strong>`)
+		assert.NotContains(t, retrievedPost.ContentHTML, "<script>")
+	})
+
+	t.Run("Rendered list", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/posts?render=html")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var posts []models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&posts))
+		require.Len(t, posts, 1)
+		assert.NotEmpty(t, posts[0].ContentHTML)
+	})
+
+	t.Run("Unsupported render value", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/posts/1?render=pdf")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestIntegration_Slugs(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	// Redirects are checked explicitly, so they must not be followed
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	create := func(body string) models.Post {
+		resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var post models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&post))
+		return post
+	}
+	send := func(method, path, contentType, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		authorize(req)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	first := create(`{"title":"Crème Brûlée","content":"Content","author":"Author","status":"published"}`)
+	second := create(`{"title":"Creme brulee!","content":"Content","author":"Author","status":"published"}`)
+	assert.Equal(t, "creme-brulee", first.Slug)
+	assert.Equal(t, "creme-brulee-2", second.Slug)
+
+	t.Run("Get by slug", func(t *testing.T) {
+		resp, err := client.Get(server.URL + "/posts/by-slug/creme-brulee-2")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var post models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&post))
+		assert.Equal(t, second.ID, post.ID)
+
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/posts/by-slug/missing", "", "").StatusCode)
+	})
+
+	t.Run("Title change redirects the old slug", func(t *testing.T) {
+		resp := send(http.MethodPut, "/posts/1", "application/json", `{"title":"Tarte Tatin","content":"Content","author":"Author"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = send(http.MethodGet, "/posts/by-slug/creme-brulee?render=html", "", "")
+		assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+		assert.Equal(t, "/posts/by-slug/tarte-tatin?render=html", resp.Header.Get("Location"))
+	})
+
+	t.Run("Explicit slug", func(t *testing.T) {
+		resp := send(http.MethodPatch, "/posts/1", "application/merge-patch+json", `{"slug":"apple-tart"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, http.StatusOK, send(http.MethodGet, "/posts/by-slug/apple-tart", "", "").StatusCode)
+		assert.Equal(t, http.StatusMovedPermanently, send(http.MethodGet, "/posts/by-slug/tarte-tatin", "", "").StatusCode)
+		assert.Equal(t, http.StatusMovedPermanently, send(http.MethodGet, "/posts/by-slug/creme-brulee", "", "").StatusCode)
+	})
+
+	t.Run("Slug of another post is a conflict", func(t *testing.T) {
+		resp := send(http.MethodPatch, "/posts/2", "application/merge-patch+json", `{"slug":"creme-brulee"}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("Invalid slug", func(t *testing.T) {
+		resp := send(http.MethodPatch, "/posts/2", "application/merge-patch+json", `{"slug":"Not A Slug"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestIntegration_Tags(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	for _, body := range []string{
+		`{"title":"First","content":"Content","author":"Author","status":"published","tags":["Go","Web Development"]}`,
+		`{"title":"Second","content":"Content","author":"Author","status":"published","tags":["go"]}`,
+		`{"title":"Third","content":"Content","author":"Author","status":"published"}`,
+	} {
+		resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+	resp, err := authorizedPost(server.URL+"/posts", "application/xml", bytes.NewBufferString(
+		`<post><title>Fourth</title><content>Content</content><author>Author</author><status>published</status><tags><tag>web-development</tag></tags></post>`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp, err = authorizedPost(server.URL+"/posts", "application/json", bytes.NewBufferString(
+		`{"title":"Draft","content":"Content","author":"Author","tags":["go","secret-product-x"]}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	getPosts := func(path string) []models.Post {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var posts []models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&posts))
+		return posts
+	}
+	titles := func(posts []models.Post) []string {
+		result := []string{}
+		for _, post := range posts {
+			result = append(result, post.Title)
+		}
+		return result
+	}
+
+	t.Run("Tags are normalized", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/posts/1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var post models.Post
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&post))
+		assert.Equal(t, []string{"go", "web-development"}, post.Tags)
+	})
+
+	t.Run("List tags", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/tags")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var tags []models.Tag
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&tags))
+		assert.Equal(t, []models.Tag{{Name: "go", PostCount: 2}, {Name: "web-development", PostCount: 2}}, tags,
+			"tags of the draft aren't shown to anonymous clients")
+	})
+
+	t.Run("Posts of a tag", func(t *testing.T) {
+		assert.Equal(t, []string{"First", "Second"}, titles(getPosts("/tags/go/posts")))
+		assert.Empty(t, getPosts("/tags/missing/posts"))
+	})
+
+	t.Run("Filter by any tag", func(t *testing.T) {
+		assert.Equal(t, []string{"First", "Second", "Fourth"}, titles(getPosts("/posts?tag=go,web-development")))
+		assert.Equal(t, []string{"First", "Second", "Fourth"}, titles(getPosts("/posts?tag=Go&tag=Web+Development&match=any")))
+	})
+
+	t.Run("Filter by all tags", func(t *testing.T) {
+		assert.Equal(t, []string{"First"}, titles(getPosts("/posts?tag=go&tag=web-development&match=all")))
+	})
+
+	t.Run("Invalid match", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/posts?tag=go&match=some")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestIntegration_Categories(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	send := func(method, path, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+	createCategory := func(body string) models.Category {
+		resp, data := send(http.MethodPost, "/categories", body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+		var category models.Category
+		require.NoError(t, json.Unmarshal(data, &category))
+		assert.Equal(t, fmt.Sprintf("/categories/%d", category.ID), resp.Header.Get("Location"))
+		return category
+	}
+
+	engineering := createCategory(`{"name":"Engineering"}`)
+	backend := createCategory(fmt.Sprintf(`{"name":"Backend","parent_id":%d}`, engineering.ID))
+	golang := createCategory(fmt.Sprintf(`{"name":"Go","parent_id":%d}`, backend.ID))
+	design := createCategory(`{"name":"Design"}`)
+
+	for _, body := range []string{
+		fmt.Sprintf(`{"title":"Engineering post","content":"Content","author":"Author","category_id":%d}`, engineering.ID),
+		fmt.Sprintf(`{"title":"Go post","content":"Content","author":"Author","category_id":%d}`, golang.ID),
+		fmt.Sprintf(`{"title":"Design post","content":"Content","author":"Author","category_id":%d}`, design.ID),
+		`{"title":"Uncategorized post","content":"Content","author":"Author"}`,
+	} {
+		resp, data := send(http.MethodPost, "/posts", body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+	}
+
+	t.Run("Breadcrumb", func(t *testing.T) {
+		resp, data := send(http.MethodGet, "/posts/2", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var post models.Post
+		require.NoError(t, json.Unmarshal(data, &post))
+		var names []string
+		for _, category := range post.Breadcrumb {
+			names = append(names, category.Name)
+		}
+		assert.Equal(t, []string{"Engineering", "Backend", "Go"}, names)
+	})
+
+	t.Run("Filter includes descendants", func(t *testing.T) {
+		resp, data := send(http.MethodGet, fmt.Sprintf("/posts?category=%d", engineering.ID), "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var posts []models.Post
+		require.NoError(t, json.Unmarshal(data, &posts))
+		var titles []string
+		for _, post := range posts {
+			titles = append(titles, post.Title)
+		}
+		assert.ElementsMatch(t, []string{"Engineering post", "Go post"}, titles)
+
+		resp, _ = send(http.MethodGet, "/posts?category=42", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = send(http.MethodGet, "/posts?category=abc", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Cycle prevention", func(t *testing.T) {
+		resp, data := send(http.MethodPut, fmt.Sprintf("/categories/%d", engineering.ID),
+			fmt.Sprintf(`{"name":"Engineering","parent_id":%d}`, golang.ID))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var problem models.Problem
+		require.NoError(t, json.Unmarshal(data, &problem))
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, "parent_id", problem.Errors[0].Name)
+	})
+
+	t.Run("Move category", func(t *testing.T) {
+		resp, _ := send(http.MethodPut, fmt.Sprintf("/categories/%d", backend.ID),
+			fmt.Sprintf(`{"name":"Backend","parent_id":%d}`, design.ID))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, data := send(http.MethodGet, fmt.Sprintf("/posts?category=%d", design.ID), "")
+		var posts []models.Post
+		require.NoError(t, json.Unmarshal(data, &posts))
+		assert.Len(t, posts, 2)
+	})
+
+	t.Run("Unknown category of a post", func(t *testing.T) {
+		resp, _ := send(http.MethodPost, "/posts", `{"title":"Post","content":"Content","author":"Author","category_id":42}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		resp, _ := send(http.MethodDelete, fmt.Sprintf("/categories/%d", backend.ID), "")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, "has subcategories")
+
+		resp, _ = send(http.MethodDelete, fmt.Sprintf("/categories/%d", golang.ID), "")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode, "has posts")
+
+		resp, _ = send(http.MethodDelete, "/posts/2", "")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp, _ = send(http.MethodDelete, fmt.Sprintf("/categories/%d", golang.ID), "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp, _ = send(http.MethodGet, fmt.Sprintf("/categories/%d", golang.ID), "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		resp, data := send(http.MethodGet, "/categories", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var categories []models.Category
+		require.NoError(t, json.Unmarshal(data, &categories))
+		assert.Len(t, categories, 3)
+	})
+}
+
+func TestIntegration_Comments(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	aliceToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "alice"}, Name: "Alice"})
+	bobToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "bob"}, Name: "Bob"})
+	malloryToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "mallory"}, Name: "Mallory"})
+
+	// send authenticates with token, as an admin when it's empty and anonymously when it's "-"
+	send := func(method, path, token, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		switch token {
+		case "":
+			authorize(req)
+		case "-":
+		default:
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+	createComment := func(token, body string) models.Comment {
+		resp, data := send(http.MethodPost, "/posts/1/comments", token, body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+		var comment models.Comment
+		require.NoError(t, json.Unmarshal(data, &comment))
+		assert.Equal(t, fmt.Sprintf("/posts/1/comments/%d", comment.ID), resp.Header.Get("Location"))
+		return comment
+	}
+
+	resp, data := send(http.MethodPost, "/posts", "", `{"title":"Discussed post","content":"Content","author":"Author","status":"published"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+
+	first := createComment(aliceToken, `{"author":"Alice","content":"First"}`)
+	reply := createComment(bobToken, fmt.Sprintf(`{"author":"Bob","content":"Reply","parent_id":%d}`, first.ID))
+	nested := createComment(aliceToken, fmt.Sprintf(`{"author":"Alice","content":"Nested reply","parent_id":%d}`, reply.ID))
+	second := createComment("-", `{"author":"Carol","content":"Second"}`)
+	assert.Equal(t, int64(1), first.PostID)
+
+	t.Run("Threads and pagination", func(t *testing.T) {
+		resp, data := send(http.MethodGet, "/posts/1/comments?per_page=1", "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "2", resp.Header.Get("X-Total-Count"))
+		assert.Contains(t, resp.Header.Get("Link"), `rel="next"`)
+
+		var threads []models.Comment
+		require.NoError(t, json.Unmarshal(data, &threads))
+		require.Len(t, threads, 1)
+		assert.Equal(t, first.ID, threads[0].ID)
+		require.Len(t, threads[0].Replies, 1)
+		require.Len(t, threads[0].Replies[0].Replies, 1)
+		assert.Equal(t, nested.ID, threads[0].Replies[0].Replies[0].ID)
+
+		resp, data = send(http.MethodGet, "/posts/1/comments?page=2&per_page=1", "", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Link"), `rel="prev"`)
+		assert.NotContains(t, resp.Header.Get("Link"), `rel="next"`)
+		require.NoError(t, json.Unmarshal(data, &threads))
+		require.Len(t, threads, 1)
+		assert.Equal(t, second.ID, threads[0].ID)
+
+		resp, _ = send(http.MethodGet, "/posts/1/comments?per_page=1000", "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Invalid requests", func(t *testing.T) {
+		resp, _ := send(http.MethodPost, "/posts/42/comments", "", `{"author":"Alice","content":"Lost"}`)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp, data := send(http.MethodPost, "/posts/1/comments", "", `{"author":"Alice","content":"Lost","parent_id":42}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, string(data), "parent_id")
+
+		resp, data = send(http.MethodPost, "/posts/1/comments", "", `{"author":"Alice"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, string(data), "content")
+	})
+
+	t.Run("Edit by author only", func(t *testing.T) {
+		path := fmt.Sprintf("/posts/1/comments/%d", reply.ID)
+
+		resp, _ := send(http.MethodPut, path, malloryToken, `{"content":"Hijacked"}`)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp, _ = send(http.MethodPut, path, "-", `{"author":"Bob","content":"Anonymous"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp, _ = send(http.MethodDelete, path, "-", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp, _ = send(http.MethodPut, fmt.Sprintf("/posts/1/comments/%d", second.ID), malloryToken, `{"content":"Claimed"}`)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, "anonymous comments can't be changed")
+
+		resp, data := send(http.MethodPut, path, bobToken, `{"content":"Edited"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+		var updated models.Comment
+		require.NoError(t, json.Unmarshal(data, &updated))
+		assert.Equal(t, "Edited", updated.Content)
+		assert.Equal(t, first.ID, updated.ParentID)
+	})
+
+	t.Run("Edits are validated by content only", func(t *testing.T) {
+		longToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: strings.Repeat("s", 150)}})
+		comment := createComment(longToken, `{"author":"Long","content":"Original"}`)
+		path := fmt.Sprintf("/posts/1/comments/%d", comment.ID)
+
+		resp, data := send(http.MethodPut, path, longToken, `{"content":"Edited"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+		var updated models.Comment
+		require.NoError(t, json.Unmarshal(data, &updated))
+		assert.Equal(t, "Edited", updated.Content)
+		assert.Equal(t, "Long", updated.Author)
+
+		resp, data = send(http.MethodPut, path, longToken, `{"author":"Long"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, string(data), "content")
+		assert.NotContains(t, string(data), `"author"`)
+	})
+
+	t.Run("Delete keeps replies", func(t *testing.T) {
+		resp, _ := send(http.MethodDelete, fmt.Sprintf("/posts/1/comments/%d", reply.ID), bobToken, "")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		_, data := send(http.MethodGet, "/posts/1/comments", "", "")
+		var threads []models.Comment
+		require.NoError(t, json.Unmarshal(data, &threads))
+		require.Len(t, threads[0].Replies, 1)
+		assert.True(t, threads[0].Replies[0].Deleted)
+		assert.Empty(t, threads[0].Replies[0].Content)
+		require.Len(t, threads[0].Replies[0].Replies, 1)
+
+		resp, _ = send(http.MethodDelete, fmt.Sprintf("/posts/1/comments/%d", nested.ID), aliceToken, "")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		_, data = send(http.MethodGet, "/posts/1/comments", "", "")
+		var remaining []models.Comment
+		require.NoError(t, json.Unmarshal(data, &remaining))
+		assert.Empty(t, remaining[0].Replies, "placeholder goes away with its last reply")
+	})
+
+	t.Run("Post deletion removes comments", func(t *testing.T) {
+		resp, _ := send(http.MethodDelete, "/posts/1", "", "")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp, _ = send(http.MethodGet, "/posts/1/comments", "", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestIntegration_CommentModeration(t *testing.T) {
+	server := setupTestServer(service.WithCommentModerator(moderation.New(moderation.MaxLinks(1))))
+	defer server.Close()
+
+	send := func(method, path, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+	publicComments := func() []models.Comment {
+		resp, data := send(http.MethodGet, "/posts/1/comments", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var comments []models.Comment
+		require.NoError(t, json.Unmarshal(data, &comments))
+		return comments
+	}
+
+	resp, data := send(http.MethodPost, "/posts", `{"title":"Moderated post","content":"Content","author":"Author"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+
+	var clean, spam models.Comment
+	resp, data = send(http.MethodPost, "/posts/1/comments", `{"author":"Alice","content":"Nice post"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+	require.NoError(t, json.Unmarshal(data, &clean))
+	assert.Equal(t, "pending", clean.Status)
+
+	resp, data = send(http.MethodPost, "/posts/1/comments", `{"author":"Bot","content":"https://a.example https://b.example"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+	require.NoError(t, json.Unmarshal(data, &spam))
+	assert.Equal(t, "spam", spam.Status)
+	assert.Empty(t, spam.Flags, "only moderators see why")
+
+	assert.Empty(t, publicComments(), "nothing is approved yet")
+
+	t.Run("Queue", func(t *testing.T) {
+		resp, data := send(http.MethodGet, "/admin/comments", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var queue []models.Comment
+		require.NoError(t, json.Unmarshal(data, &queue))
+		require.Len(t, queue, 1)
+		assert.Equal(t, clean.ID, queue[0].ID)
+
+		resp, data = send(http.MethodGet, "/admin/comments?status=spam", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, json.Unmarshal(data, &queue))
+		require.Len(t, queue, 1)
+		assert.NotEmpty(t, queue[0].Flags)
+
+		resp, _ = send(http.MethodGet, "/admin/comments?status=unknown", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Bulk approve", func(t *testing.T) {
+		resp, data := send(http.MethodPost, "/admin/comments/moderation", fmt.Sprintf(`{"ids":[%d,42],"status":"approved"}`, clean.ID))
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+		var result models.CommentModerationResult
+		require.NoError(t, json.Unmarshal(data, &result))
+		assert.Equal(t, []int64{clean.ID}, result.Updated)
+		assert.Equal(t, []int64{42}, result.NotFound)
+
+		comments := publicComments()
+		require.Len(t, comments, 1)
+		assert.Equal(t, clean.ID, comments[0].ID)
+
+		resp, _ = send(http.MethodPost, "/admin/comments/moderation", `{"ids":[1],"status":"published"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = send(http.MethodPost, "/admin/comments/moderation", `{"ids":[],"status":"spam"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestIntegration_CommentClientAddress(t *testing.T) {
+	trusted, err := middleware.ParseTrustedProxies([]string{"127.0.0.1"})
+	require.NoError(t, err)
+	limiter := middleware.NewRateLimiter(middleware.RateLimitOptions{TrustedProxies: trusted})
+	server := setupRoutedTestServer(loggerMock(), RouterOptions{RateLimiter: limiter},
+		service.WithCommentModerator(moderation.New(moderation.RateLimit(1, time.Hour))))
+	defer server.Close()
+
+	comment := func(forwardedFor string) models.Comment {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/posts/1/comments",
+			strings.NewReader(`{"author":"Alice","content":"Nice post"}`))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var created models.Comment
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		return created
+	}
+
+	resp, err := authorizedPost(server.URL+"/posts", "application/json",
+		strings.NewReader(`{"title":"Title","content":"Content","author":"Author"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	assert.Equal(t, "pending", comment("192.0.2.1").Status)
+	assert.Equal(t, "pending", comment("192.0.2.2").Status, "clients behind the proxy are told apart")
+	assert.Equal(t, "spam", comment("192.0.2.1").Status)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/admin/comments?status=spam", nil)
+	require.NoError(t, err)
+	authorize(req)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var queue []models.Comment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&queue))
+	require.Len(t, queue, 1)
+	require.Len(t, queue[0].Flags, 1)
+	assert.Contains(t, queue[0].Flags[0], "from 192.0.2.1 ")
+}
+
+func TestIntegration_Authors(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	send := func(method, path, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+	getPost := func(path string) models.Post {
+		resp, data := send(http.MethodGet, path, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+		var post models.Post
+		require.NoError(t, json.Unmarshal(data, &post))
+		return post
+	}
+
+	resp, data := send(http.MethodPost, "/authors", `{"name":"Author 1","bio":"Writes about Go","avatar_url":"https://example.com/1.png"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+	var author models.Author
+	require.NoError(t, json.Unmarshal(data, &author))
+	assert.Equal(t, fmt.Sprintf("/authors/%d", author.ID), resp.Header.Get("Location"))
+
+	t.Run("Names are unique ignoring case", func(t *testing.T) {
+		resp, _ := send(http.MethodPost, "/authors", `{"name":"author 1"}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("Posts link to authors", func(t *testing.T) {
+		resp, data := send(http.MethodPost, "/posts", fmt.Sprintf(`{"title":"By ID","content":"Content","author_id":%d,"status":"published"}`, author.ID))
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+		resp, data = send(http.MethodPost, "/posts", `{"title":"By name","content":"Content","author":"AUTHOR 1","status":"published"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+
+		for _, path := range []string{"/posts/1", "/posts/2"} {
+			post := getPost(path)
+			assert.Equal(t, author.ID, post.AuthorID)
+			assert.Equal(t, "Author 1", post.Author)
+			require.NotNil(t, post.AuthorSummary)
+			assert.Equal(t, "https://example.com/1.png", post.AuthorSummary.AvatarURL.String())
+		}
+
+		resp, data = send(http.MethodPost, "/posts", `{"title":"Unknown","content":"Content","author_id":42}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, string(data), "author_id")
+	})
+
+	t.Run("Rename shows on every post", func(t *testing.T) {
+		resp, data := send(http.MethodPut, fmt.Sprintf("/authors/%d", author.ID), `{"name":"Jane Doe"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+
+		assert.Equal(t, "Jane Doe", getPost("/posts/1").Author)
+		assert.Equal(t, "Jane Doe", getPost("/posts/2").Author)
+
+		resp, _ = send(http.MethodGet, "/authors/Jane%20Doe/feed.atom", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "author feeds stay reachable next to the authors resource")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		resp, _ := send(http.MethodDelete, fmt.Sprintf("/authors/%d", author.ID), "")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp, data := send(http.MethodPost, "/authors", `{"name":"Guest"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+		var guest models.Author
+		require.NoError(t, json.Unmarshal(data, &guest))
+
+		resp, _ = send(http.MethodDelete, fmt.Sprintf("/authors/%d", guest.ID), "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp, _ = send(http.MethodGet, fmt.Sprintf("/authors/%d", guest.ID), "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestIntegration_Authentication(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	author := []string{auth.RoleAuthor}
+	janeToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}, Name: "Jane Doe", Roles: author})
+	johnToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "john"}, Name: "John Roe", Roles: author})
+	editorToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "ed"}, Name: "Ed Itor", Roles: []string{auth.RoleEditor}})
+	readerToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "rita"}, Name: "Rita Reader"})
+	expiredToken := testToken(auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "jane", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))},
+		Name:             "Jane Doe",
+	})
+
+	send := func(method, path, token, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	janePost := `{"title":"Jane's post","content":"Content","author":"Jane Doe"}`
+
+	t.Run("Writes require a valid token", func(t *testing.T) {
+		resp := send(http.MethodPost, "/posts", "", janePost)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, `Bearer realm="blog"`, resp.Header.Get("WWW-Authenticate"))
+
+		resp = send(http.MethodPost, "/posts", expiredToken, janePost)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`)
+
+		resp = send(http.MethodPost, "/posts", "not-a-token", janePost)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Authors modify only their own posts", func(t *testing.T) {
+		resp := send(http.MethodPost, "/posts", johnToken, janePost)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = send(http.MethodPost, "/posts", janeToken, janePost)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		location := resp.Header.Get("Location")
+
+		resp = send(http.MethodGet, location, "", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "drafts are hidden from readers")
+		resp = send(http.MethodGet, location, janeToken, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "authors read their drafts")
+
+		resp = send(http.MethodPut, location, johnToken, `{"title":"Taken over","content":"Content","author":"John Roe"}`)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp = send(http.MethodPut, location, janeToken, `{"title":"Given away","content":"Content","author":"John Roe"}`)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp = send(http.MethodPut, location, janeToken, `{"title":"Edited","content":"Content","author":"Jane Doe"}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = send(http.MethodDelete, location, johnToken, "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp = send(http.MethodDelete, location, janeToken, "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("Admins modify any post", func(t *testing.T) {
+		resp := send(http.MethodPost, "/posts", janeToken, janePost)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		resp = send(http.MethodDelete, resp.Header.Get("Location"), adminToken, "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("Editors edit but don't delete posts of others", func(t *testing.T) {
+		resp := send(http.MethodPost, "/posts", janeToken, janePost)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		location := resp.Header.Get("Location")
+
+		resp = send(http.MethodPut, location, editorToken, `{"title":"Proofread","content":"Content","author":"Jane Doe"}`)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp = send(http.MethodDelete, location, editorToken, "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = send(http.MethodPost, "/categories", editorToken, `{"name":"Desserts"}`)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp = send(http.MethodGet, "/admin/comments", editorToken, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp = send(http.MethodGet, "/admin/api-keys", editorToken, "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Readers only read", func(t *testing.T) {
+		resp := send(http.MethodPost, "/posts", readerToken, `{"title":"Mine","content":"Content","author":"Rita Reader"}`)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp = send(http.MethodPost, "/categories", readerToken, `{"name":"Drinks"}`)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp = send(http.MethodPost, "/categories", "", `{"name":"Drinks"}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		resp = send(http.MethodGet, "/posts", readerToken, "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestIntegration_Workflow(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	janeToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}, Name: "Jane Doe", Roles: []string{auth.RoleAuthor}})
+	editorToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "ed"}, Name: "Ed Itor", Roles: []string{auth.RoleEditor}})
+
+	send := func(method, path, token, body string) (*http.Response, string) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(data)
+	}
+	getPost := func(path, token string) models.Post {
+		resp, data := send(http.MethodGet, path, token, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, data)
+		var post models.Post
+		require.NoError(t, json.Unmarshal([]byte(data), &post))
+		return post
+	}
+
+	resp, data := send(http.MethodPost, "/posts", janeToken, `{"title":"Jane's post","content":"Content","author":"Jane Doe"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, data)
+	location := resp.Header.Get("Location")
+	assert.Equal(t, models.PostStatusDraft, getPost(location, janeToken).Status)
+
+	t.Run("Authors submit but don't publish", func(t *testing.T) {
+		resp, data := send(http.MethodPatch, location, janeToken, `{"status":"published"}`)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode, data)
+
+		resp, data = send(http.MethodPut, location, janeToken, `{"title":"Jane's post","content":"Content","author":"Jane Doe","status":"in_review"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, data)
+
+		resp, data = send(http.MethodGet, "/posts?status=in_review", editorToken, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, data, "Jane's post", "editors find posts waiting for review")
+	})
+
+	t.Run("Invalid status filter", func(t *testing.T) {
+		resp, _ := send(http.MethodGet, "/posts?status=lost", "", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Scheduling needs a future time", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		resp, data := send(http.MethodPatch, location, editorToken, `{"status":"scheduled","publish_at":"`+past+`"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, data, `"publish_at"`)
+	})
+
+	t.Run("Editors publish", func(t *testing.T) {
+		resp, data := send(http.MethodPatch, location, editorToken, `{"status":"published"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, data)
+
+		post := getPost(location, "")
+		assert.Equal(t, models.PostStatusPublished, post.Status)
+		assert.NotNil(t, post.PublishAt)
+	})
+
+	t.Run("Invalid transition", func(t *testing.T) {
+		resp, data := send(http.MethodPatch, location, editorToken, `{"status":"in_review"}`)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Contains(t, data, "Posts can't change from published to in_review")
+	})
+
+	t.Run("Archived posts are hidden", func(t *testing.T) {
+		resp, data := send(http.MethodPatch, location, editorToken, `{"status":"archived"}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode, data)
+
+		resp, _ = send(http.MethodGet, location, "", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		_, data = send(http.MethodGet, "/posts", "", "")
+		assert.NotContains(t, data, "Jane's post")
+	})
+}
+
+func TestIntegration_Previews(t *testing.T) {
+	server := setupTestServer(service.WithPreviewSecret([]byte("preview-secret")))
+	defer server.Close()
+
+	janeToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "jane"}, Name: "Jane Doe", Roles: []string{auth.RoleAuthor}})
+	johnToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "john"}, Name: "John Roe", Roles: []string{auth.RoleAuthor}})
+
+	send := func(method, path, token, body string) (*http.Response, string) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(data)
+	}
+
+	resp, data := send(http.MethodPost, "/posts", janeToken, `{"title":"Jane's draft","content":"Content","author":"Jane Doe"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, data)
+	location := resp.Header.Get("Location")
+
+	resp, data = send(http.MethodPost, location+"/previews", janeToken, "")
+	require.Equal(t, http.StatusCreated, resp.StatusCode, data)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	var preview models.Preview
+	require.NoError(t, json.Unmarshal([]byte(data), &preview))
+	assert.Equal(t, web.PreviewPath+"/"+preview.Token, preview.URL)
+	require.NotNil(t, preview.ExpiresAt)
+
+	resp, data = send(http.MethodGet, preview.URL, "", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, data, "Jane&#39;s draft", "reviewers read the draft without an account")
+
+	t.Run("Expiry", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		resp, data := send(http.MethodPost, location+"/previews", janeToken, `{"expires_at":"`+expiresAt+`"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, data)
+		assert.Contains(t, data, expiresAt[:len(expiresAt)-1])
+
+		resp, data = send(http.MethodPost, location+"/previews", janeToken, `{"expires_at":"2030-13-01"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, data)
+		resp, data = send(http.MethodPost, location+"/previews", janeToken, `{"expires_at":"2001-01-01T00:00:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, data, `"expires_at"`)
+	})
+
+	t.Run("Only those editing the post", func(t *testing.T) {
+		resp, _ := send(http.MethodPost, location+"/previews", johnToken, "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp, _ = send(http.MethodPost, location+"/previews", "", "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		resp, _ = send(http.MethodPost, "/posts/42/previews", janeToken, "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		resp, _ := send(http.MethodDelete, location+"/previews", johnToken, "")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp, _ = send(http.MethodDelete, location+"/previews", janeToken, "")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp, _ = send(http.MethodGet, preview.URL, "", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		server := setupTestServer()
+		defer server.Close()
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/posts/1/previews", nil)
+		require.NoError(t, err)
+		authorize(req)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+	})
+}
+
+func TestIntegration_APIKeys(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
+
+	send := func(method, path, body string, headers ...string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+	asAdmin := []string{"Authorization", "Bearer " + adminToken}
+	mint := func(body string) models.APIKey {
+		resp, data := send(http.MethodPost, "/admin/api-keys", body, asAdmin...)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+		assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+		var key models.APIKey
+		require.NoError(t, json.Unmarshal(data, &key))
+		require.NotEmpty(t, key.Key)
+		return key
+	}
+
+	resp, data := send(http.MethodPost, "/authors", `{"name":"Bot"}`, asAdmin...)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+	var bot models.Author
+	require.NoError(t, json.Unmarshal(data, &bot))
+
+	writer := mint(fmt.Sprintf(`{"name":"Importer","scopes":["posts:read","posts:write"],"author_id":%d}`, bot.ID))
+	reader := mint(`{"name":"Dashboard","scopes":["posts:read"]}`)
+	admin := mint(`{"name":"Ops","scopes":["admin"]}`)
+
+	t.Run("Minting requires an admin", func(t *testing.T) {
+		resp, _ := send(http.MethodPost, "/admin/api-keys", `{"name":"CI","scopes":["posts:read"]}`)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		resp, _ = send(http.MethodPost, "/admin/api-keys", `{"name":"CI","scopes":["posts:read"]}`, "X-API-Key", writer.Key)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp, _ = send(http.MethodPost, "/admin/api-keys", `{"name":"CI","scopes":["posts:delete"]}`, asAdmin...)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = send(http.MethodPost, "/admin/api-keys", `{"name":"CI","scopes":["posts:read"],"expires_at":"2001-01-01T00:00:00Z"}`, asAdmin...)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Keys are accepted in both headers", func(t *testing.T) {
+		resp, data := send(http.MethodPost, "/posts", `{"title":"Imported","content":"Content","author":"Bot"}`, "Authorization", "Bearer "+writer.Key)
+		require.Equal(t, http.StatusCreated, resp.StatusCode, string(data))
+		resp, data = send(http.MethodPut, resp.Header.Get("Location"), `{"title":"Imported again","content":"Content","author":"Bot"}`, "X-API-Key", writer.Key)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+	})
+
+	t.Run("Scopes limit the key", func(t *testing.T) {
+		resp, _ := send(http.MethodPost, "/posts", `{"title":"Nope","content":"Content","author":"Bot"}`, "X-API-Key", reader.Key)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), `error="insufficient_scope"`)
+		resp, _ = send(http.MethodGet, "/posts", "", "X-API-Key", reader.Key)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp, _ = send(http.MethodGet, "/admin/api-keys", "", "X-API-Key", reader.Key)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp, _ = send(http.MethodGet, "/posts", "", "X-API-Key", "bk_unknown")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "invalid credentials are refused even where anonymous reads pass")
+	})
+
+	t.Run("List shows last use but never the key", func(t *testing.T) {
+		resp, data := send(http.MethodGet, "/admin/api-keys", "", "X-API-Key", admin.Key)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(data))
+		var keys []models.APIKey
+		require.NoError(t, json.Unmarshal(data, &keys))
+		require.Len(t, keys, 3)
+		for _, key := range keys {
+			assert.Empty(t, key.Key)
+		}
+		assert.True(t, strings.HasPrefix(writer.Key, keys[0].Prefix))
+		assert.NotNil(t, keys[0].LastUsedAt)
+		assert.NotContains(t, string(data), writer.Key)
+	})
+
+	t.Run("Revoked keys stop working", func(t *testing.T) {
+		resp, _ := send(http.MethodDelete, fmt.Sprintf("/admin/api-keys/%d", writer.ID), "", asAdmin...)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp, _ = send(http.MethodDelete, fmt.Sprintf("/admin/api-keys/%d", writer.ID), "", asAdmin...)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp, _ = send(http.MethodPost, "/posts", `{"title":"Late","content":"Content","author":"Bot"}`, "X-API-Key", writer.Key)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+type rateLimitMetricsMock struct {
+	rejected []string
+}
+
+func (m *rateLimitMetricsMock) ObserveRateLimited(route, client string) {
+	m.rejected = append(m.rejected, route+" "+client)
+}
+
+func TestIntegration_RateLimit(t *testing.T) {
+	metrics := &rateLimitMetricsMock{}
+	hndl := New(nil, loggerMock())
+	trusted, err := middleware.ParseTrustedProxies([]string{"127.0.0.1"})
+	require.NoError(t, err)
+	limiter := middleware.NewRateLimiter(middleware.RateLimitOptions{
+		Default:        middleware.Limit{Requests: 3, Period: time.Minute},
+		Routes:         map[string]middleware.Limit{"POST /posts": {Requests: 1, Period: time.Hour}},
+		TrustedProxies: trusted,
+		Metrics:        metrics,
+		Rejected:       hndl.TooManyRequests,
+	})
+	server := setupRoutedTestServer(loggerMock(), RouterOptions{RateLimiter: limiter})
+	defer server.Close()
+
+	get := func(path string, headers ...string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("Clients over the limit get 429", func(t *testing.T) {
+		for i := 2; i >= 0; i-- {
+			resp := get("/posts", "X-Forwarded-For", "192.0.2.1")
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "3", resp.Header.Get("RateLimit-Limit"))
+			assert.Equal(t, fmt.Sprint(i), resp.Header.Get("RateLimit-Remaining"))
+			assert.Equal(t, "3;w=60", resp.Header.Get("RateLimit-Policy"))
+			assert.NotEmpty(t, resp.Header.Get("RateLimit-Reset"))
+		}
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/blog", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Forwarded-For", "192.0.2.1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "pages share the default limit")
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "20", resp.Header.Get("Retry-After"))
+		assert.Equal(t, "0", resp.Header.Get("RateLimit-Remaining"))
+
+		var problem models.Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		assert.Equal(t, int64(http.StatusTooManyRequests), problem.Status)
+		assert.Equal(t, "Too many requests, retry after 20 seconds", problem.Detail)
+		assert.Equal(t, []string{"/blog ip"}, metrics.rejected)
+	})
+
+	t.Run("Clients are told apart by the forwarded address behind trusted proxies", func(t *testing.T) {
+		resp := get("/posts", "X-Forwarded-For", "192.0.2.2")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp = get("/posts", "X-Forwarded-For", "192.0.2.1, 127.0.0.1")
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "hops of trusted proxies are skipped")
+	})
+
+	t.Run("Authenticated clients are told apart by their principal", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			resp := get("/posts", "X-Forwarded-For", "192.0.2.1", "Authorization", "Bearer "+adminToken)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+		resp := get("/posts", "X-Forwarded-For", "192.0.2.3", "Authorization", "Bearer "+adminToken)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	})
+
+	t.Run("Routes with a limit of their own", func(t *testing.T) {
+		body := `{"title":"Title","content":"Content","author":"Author"}`
+		resp, err := authorizedPost(server.URL+"/posts", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode, "the route doesn't share the exhausted default bucket")
+		assert.Equal(t, "1;w=3600", resp.Header.Get("RateLimit-Policy"))
+
+		resp, err = authorizedPost(server.URL+"/posts", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, "3600", resp.Header.Get("Retry-After"))
+	})
+
+	t.Run("Failed authentications are limited by address", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			resp := get("/posts", "X-Forwarded-For", "192.0.2.4", "Authorization", "Bearer forged")
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+		resp := get("/posts", "X-Forwarded-For", "192.0.2.4", "X-API-Key", "forged")
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, "other credentials don't help")
+		assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+		readerToken := testToken(auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "rita"}})
+		resp = get("/posts", "X-Forwarded-For", "192.0.2.5", "Authorization", "Bearer "+readerToken)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "valid credentials of other addresses aren't limited")
+	})
+}
+
+func TestIntegration_CORS(t *testing.T) {
+	server := setupRoutedTestServer(loggerMock(), RouterOptions{CORS: &cors.Options{
+		AllowedOrigins:   []string{"https://*.example.com", "https://spa.test"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "If-Match"},
+		ExposedHeaders:   []string{"ETag", "Location"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}})
+	defer server.Close()
+
+	request := func(method, path, origin string, headers ...string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("Preflight requests of every route in the posts tree", func(t *testing.T) {
+		for _, path := range []string{"/posts", "/posts/1", "/posts/1/comments", "/posts/1/comments/2", "/posts/1/previews",
+			"/posts/slug/hello"} {
+			resp := request(http.MethodOptions, path, "https://app.example.com",
+				"Access-Control-Request-Method", http.MethodPatch, "Access-Control-Request-Headers", "authorization,content-type")
+			require.Equal(t, http.StatusNoContent, resp.StatusCode, path)
+			assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"), path)
+			assert.Equal(t, http.MethodPatch, resp.Header.Get("Access-Control-Allow-Methods"), path)
+			assert.Equal(t, "authorization,content-type", resp.Header.Get("Access-Control-Allow-Headers"), path)
+			assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"), path)
+			assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"), path)
+		}
+	})
+
+	t.Run("Preflight requests of other origins, methods or headers", func(t *testing.T) {
+		resp := request(http.MethodOptions, "/posts", "https://evil.test", "Access-Control-Request-Method", http.MethodPost)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+		resp = request(http.MethodOptions, "/posts", "https://example.com", "Access-Control-Request-Method", http.MethodPost)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"), "the wildcard needs a subdomain")
+		resp = request(http.MethodOptions, "/posts", "https://spa.test", "Access-Control-Request-Method", "PROPFIND")
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+		resp = request(http.MethodOptions, "/posts", "https://spa.test",
+			"Access-Control-Request-Method", http.MethodPost, "Access-Control-Request-Headers", "x-unknown")
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Actual requests", func(t *testing.T) {
+		resp := request(http.MethodGet, "/posts", "https://spa.test")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "https://spa.test", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Etag, Location", resp.Header.Get("Access-Control-Expose-Headers"))
+		assert.Contains(t, resp.Header.Values("Vary"), "Origin")
+
+		resp = request(http.MethodGet, "/posts/100", "https://spa.test")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "https://spa.test", resp.Header.Get("Access-Control-Allow-Origin"), "errors are readable too")
+
+		resp = request(http.MethodGet, "/posts", "https://evil.test")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("OPTIONS without preflight headers isn't answered by CORS", func(t *testing.T) {
+		resp := request(http.MethodOptions, "/posts", "https://spa.test")
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func TestIntegration_Compression(t *testing.T) {
+	server := setupRoutedTestServer(loggerMock(), RouterOptions{Compression: &middleware.CompressOptions{
+		MinSize:      256,
+		ContentTypes: []string{"text/*", "application/json", "application/problem+json"},
+	}})
+	defer server.Close()
+
+	content := strings.Repeat("Post content compresses well. ", 100)
+	body, err := json.Marshal(models.Post{Title: "Title", Content: content, Author: "Author", Status: models.PostStatusPublished})
+	require.NoError(t, err)
+	resp, err := authorizedPost(server.URL+"/posts", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// The transport would decode gzip on its own and hide the header
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	get := func(path string, headers ...string) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, data
+	}
+
+	t.Run("Negotiated encodings", func(t *testing.T) {
+		decoders := map[string]func(io.Reader) (io.Reader, error){
+			"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+			"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+			"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		}
+		for accept, expected := range map[string]string{
+			"gzip":                       "gzip",
+			"gzip, deflate, br":          "br",
+			"gzip, br, zstd":             "zstd",
+			"zstd;q=0.5, gzip":           "gzip",
+			"*":                          "zstd",
+			"*, zstd;q=0, br;q=0":        "gzip",
+			"x-gzip":                     "gzip",
+			"br;q=0.8, gzip;q=0.8, zstd": "zstd",
+		} {
+			resp, data := get("/posts/1", "Accept-Encoding", accept)
+			require.Equal(t, http.StatusOK, resp.StatusCode, accept)
+			require.Equal(t, expected, resp.Header.Get("Content-Encoding"), accept)
+			assert.Contains(t, resp.Header.Values("Vary"), "Accept-Encoding")
+			assert.Less(t, len(data), len(content))
+
+			decoded, err := decoders[expected](bytes.NewReader(data))
+			require.NoError(t, err)
+			var post models.Post
+			require.NoError(t, json.NewDecoder(decoded).Decode(&post))
+			assert.Equal(t, content, post.Content)
+		}
+	})
+
+	t.Run("Responses left as they are", func(t *testing.T) {
+		resp, _ := get("/posts/1")
+		assert.Empty(t, resp.Header.Get("Content-Encoding"), "no Accept-Encoding")
+		resp, _ = get("/posts/1", "Accept-Encoding", "identity")
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		resp, _ = get("/posts/100", "Accept-Encoding", "gzip")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"), "small responses")
+		assert.Contains(t, resp.Header.Values("Vary"), "Accept-Encoding")
+		resp, _ = get("/posts/1", "Accept-Encoding", "gzip", "Accept", "application/msgpack")
+		assert.Empty(t, resp.Header.Get("Content-Encoding"), "types off the allow-list")
+		for _, path := range []string{"/blog/login", "/blog/signup"} {
+			resp, data := get(path, "Accept-Encoding", "gzip")
+			require.Equal(t, http.StatusOK, resp.StatusCode, path)
+			assert.Greater(t, len(data), 256, path)
+			assert.Contains(t, string(data), `name="csrf_token"`, path)
+			assert.Empty(t, resp.Header.Get("Content-Encoding"), "pages with CSRF tokens aren't compressed, %s", path)
+		}
+	})
+
+	t.Run("Compressed pages revalidate with weak validators", func(t *testing.T) {
+		resp, _ := get("/blog/posts/1", "Accept-Encoding", "gzip")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+		etag := resp.Header.Get("ETag")
+		assert.True(t, strings.HasPrefix(etag, `W/"`), etag)
+
+		resp, _ = get("/blog/posts/1", "Accept-Encoding", "gzip", "If-None-Match", etag)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	})
+
+	send := func(encoding string, data []byte) (*http.Response, models.Problem) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/posts", bytes.NewReader(data))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var problem models.Problem
+		if resp.Header.Get("Content-Type") == "application/problem+json" {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		}
+		return resp, problem
+	}
+
+	t.Run("Compressed request bodies", func(t *testing.T) {
+		var gzipped, brotlied, zstded bytes.Buffer
+		gw := gzip.NewWriter(&gzipped)
+		_, err := gw.Write(body)
+		require.NoError(t, err)
+		require.NoError(t, gw.Close())
+		bw := brotli.NewWriter(&brotlied)
+		_, err = bw.Write(body)
+		require.NoError(t, err)
+		require.NoError(t, bw.Close())
+		zw, err := zstd.NewWriter(&zstded)
+		require.NoError(t, err)
+		_, err = zw.Write(body)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		for encoding, data := range map[string][]byte{"gzip": gzipped.Bytes(), "br": brotlied.Bytes(), "zstd": zstded.Bytes()} {
+			resp, _ := send(encoding, data)
+			assert.Equal(t, http.StatusCreated, resp.StatusCode, encoding)
+		}
+
+		resp, problem := send("compress", body)
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+		assert.Equal(t, "gzip, br, zstd", resp.Header.Get("Accept-Encoding"))
+		assert.Equal(t, "Supported content encodings: gzip, br, zstd", problem.Detail)
+
+		resp, _ = send("gzip", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "bodies which aren't what they claim to be")
+	})
+
+	t.Run("Decompression bombs", func(t *testing.T) {
+		// A few kilobytes inflating way past the body limit
+		var bomb bytes.Buffer
+		gw, err := gzip.NewWriterLevel(&bomb, gzip.BestCompression)
+		require.NoError(t, err)
+		_, err = gw.Write([]byte(`{"title": "` + strings.Repeat("a", 100*testMaxBodySize)))
+		require.NoError(t, err)
+		require.NoError(t, gw.Close())
+		require.Less(t, bomb.Len(), testMaxBodySize)
+
+		resp, problem := send("gzip", bomb.Bytes())
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		assert.Equal(t, fmt.Sprintf("Request body exceeds %d bytes", testMaxBodySize), problem.Detail)
+	})
+}
+
+// syncBuffer collects log lines written while the server handles requests
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the decoded log lines having the attribute key set to value
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) lines(t *testing.T, key, value string) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(raw), &line))
+		if line[key] == value {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestIntegration_RequestID(t *testing.T) {
+	logs := &syncBuffer{}
+	server := setupRoutedTestServer(slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		RouterOptions{})
+	defer server.Close()
+
+	send := func(method, path, requestID string, body io.Reader) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, body)
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", "application/json")
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Generated IDs", func(t *testing.T) {
+		first := send(http.MethodGet, "/posts", "", nil)
+		first.Body.Close()
+		second := send(http.MethodGet, "/posts", "", nil)
+		second.Body.Close()
+		assert.Regexp(t, "^[0-9a-f]{32}$", first.Header.Get("X-Request-ID"))
+		assert.NotEqual(t, first.Header.Get("X-Request-ID"), second.Header.Get("X-Request-ID"))
+	})
+
+	t.Run("IDs of clients are echoed unless unfit for logs", func(t *testing.T) {
+		resp := send(http.MethodGet, "/posts/100", "trace-123", nil)
+		defer resp.Body.Close()
+		assert.Equal(t, "trace-123", resp.Header.Get("X-Request-ID"))
+		var problem models.Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+		assert.Equal(t, "trace-123", problem.RequestID)
+
+		for _, id := range []string{"with\ttab", "with space", strings.Repeat("a", 129)} {
+			resp := send(http.MethodGet, "/posts", id, nil)
+			resp.Body.Close()
+			assert.Regexp(t, "^[0-9a-f]{32}$", resp.Header.Get("X-Request-ID"))
+		}
+	})
+
+	t.Run("Every layer logs with the ID", func(t *testing.T) {
+		body := `{"title": "Title", "content": "Content", "author": "Author"}`
+		resp := send(http.MethodPost, "/posts", "create-1", strings.NewReader(body))
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		// The response may arrive before the middleware logged that it was sent
+		assert.Eventually(t, func() bool {
+			for _, line := range logs.lines(t, "request_id", "create-1") {
+				if line["msg"] == "Request handled" {
+					return true
+				}
+			}
+			return false
+		}, time.Second, 10*time.Millisecond)
+
+		var messages []string
+		for _, line := range logs.lines(t, "request_id", "create-1") {
+			assert.Equal(t, http.MethodPost, line["method"])
+			assert.Equal(t, "/posts", line["route"])
+			messages = append(messages, line["msg"].(string))
+		}
+		assert.Contains(t, messages, "Request received")    // middleware
+		assert.Contains(t, messages, "Creating a new post") // service
+		assert.Contains(t, messages, "Post stored")         // repository
+		assert.Contains(t, messages, "Request handled")
+	})
+}
+
+func TestIntegration_BodyLogging(t *testing.T) {
+	send := func(server *httptest.Server, method, path, contentType, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		authorize(req)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Request-ID", "body-log")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	// bodies returns the body log line of the request, nil when there is none
+	bodies := func(logs *syncBuffer) map[string]interface{} {
+		for _, line := range logs.lines(t, "request_id", "body-log") {
+			if line["msg"] == "Request and response bodies" {
+				return line
+			}
+		}
+		return nil
+	}
+	newServer := func(opts middleware.BodyLogOptions) (*httptest.Server, *syncBuffer) {
+		logs := &syncBuffer{}
+		opts.RedactFields = []string{"content", "password"}
+		opts.RedactHeaders = []string{"authorization"}
+		return setupRoutedTestServer(slog.New(slog.NewJSONHandler(logs, nil)), RouterOptions{BodyLog: &opts}), logs
+	}
+
+	t.Run("Errors only", func(t *testing.T) {
+		server, logs := newServer(middleware.BodyLogOptions{Mode: middleware.BodyLogErrors, MaxSize: 128})
+		defer server.Close()
+
+		resp := send(server, http.MethodPost, "/posts", "application/json",
+			`{"title": "Title", "content": "Content", "author": "Author"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Nil(t, bodies(logs))
+
+		body := `{"title": "", "content": {"nested": "secret"}, "author": "Author", "password": "hunter2"}`
+		resp = send(server, http.MethodPost, "/posts", "application/json", body)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		line := bodies(logs)
+		require.NotNil(t, line)
+
+		request := line["request"].(map[string]interface{})
+		assert.Equal(t, `{"title":"","content":"[REDACTED]","author":"Author","password":"[REDACTED]"}`, request["body"])
+		assert.Equal(t, float64(len(body)), request["size"])
+		assert.Equal(t, false, request["truncated"])
+		assert.Equal(t, "[REDACTED]", request["headers"].(map[string]interface{})["Authorization"])
+
+		response := line["response"].(map[string]interface{})
+		assert.Equal(t, true, response["truncated"])
+		assert.Greater(t, response["size"], float64(128))
+		assert.True(t, strings.HasPrefix(response["body"].(string), `{"detail":"Invalid request format"`))
+	})
+
+	t.Run("Sampled", func(t *testing.T) {
+		server, logs := newServer(middleware.BodyLogOptions{Mode: middleware.BodyLogSampled, SamplePercent: 100, MaxSize: 1024})
+		defer server.Close()
+
+		resp := send(server, http.MethodPost, "/posts", "application/xml",
+			`<post><title>XML Post</title><content>XML <b>secret</b></content><author>XML Author</author></post>`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		line := bodies(logs)
+		require.NotNil(t, line)
+
+		request := line["request"].(map[string]interface{})
+		assert.Equal(t, `<post><title>XML Post</title><content>[REDACTED]</content><author>XML Author</author></post>`,
+			request["body"])
+		response := line["response"].(map[string]interface{})
+		assert.Contains(t, response["body"], `"content":"[REDACTED]"`)
+		assert.NotContains(t, response["body"], "secret")
+	})
+
+	t.Run("Binary bodies by size only", func(t *testing.T) {
+		server, logs := newServer(middleware.BodyLogOptions{Mode: middleware.BodyLogSampled, SamplePercent: 100, MaxSize: 1024})
+		defer server.Close()
+
+		resp := send(server, http.MethodPost, "/posts", "application/msgpack",
+			string(mustMsgPack(t, map[string]string{"title": "MsgPack Post", "content": "secret", "author": "Author"})))
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		line := bodies(logs)
+		require.NotNil(t, line)
+
+		request := line["request"].(map[string]interface{})
+		assert.NotContains(t, request, "body")
+		assert.Greater(t, request["size"], float64(0))
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		logs := &syncBuffer{}
+		server := setupRoutedTestServer(slog.New(slog.NewJSONHandler(logs, nil)), RouterOptions{})
+		defer server.Close()
+
+		resp := send(server, http.MethodPost, "/posts", "application/json", `{"title": ""}`)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Nil(t, bodies(logs))
+	})
+
+	t.Run("Preview tokens stay out of the logs", func(t *testing.T) {
+		logs := &syncBuffer{}
+		opts := middleware.BodyLogOptions{Mode: middleware.BodyLogErrors, MaxSize: 1024}
+		server := setupRoutedTestServer(slog.New(slog.NewJSONHandler(logs, nil)), RouterOptions{BodyLog: &opts},
+			service.WithPreviewSecret([]byte("preview-secret")))
+		defer server.Close()
+
+		resp := send(server, http.MethodPost, "/posts", "application/json",
+			`{"title": "Draft", "content": "Content", "author": "Author"}`)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/posts/1/previews", nil)
+		require.NoError(t, err)
+		authorize(req)
+		resp, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		var preview models.Preview
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&preview))
+
+		for _, path := range []string{preview.URL, preview.URL + "/"} {
+			resp = send(server, http.MethodGet, path, "", "")
+			require.Equal(t, http.StatusOK, resp.StatusCode, path)
+		}
+
+		lines := logs.lines(t, "route", web.PreviewPath+"/{token}")
+		require.NotEmpty(t, lines, "preview views are logged by their route")
+		assert.NotContains(t, logs.String(), preview.Token)
+	})
+}
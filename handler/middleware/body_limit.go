@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// BodyLimit keeps request bodies within limit bytes. Bodies declaring a larger Content-Length are rejected before
+// anything reads them, reading other bodies past the limit fails with *http.MaxBytesError. Rejected writes the response
+// of requests refused upfront.
+func BodyLimit(limit int64, rejected http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				// The client may still be sending, don't keep the connection for the rest of the body
+				w.Header().Set("Connection", "close")
+				rejected(w, r)
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Modes of body logging
+const (
+	BodyLogOff     = "off"
+	BodyLogErrors  = "errors"
+	BodyLogSampled = "sampled"
+)
+
+// redacted replaces values of redacted fields and headers
+const redacted = "[REDACTED]"
+
+// BodyLogOptions configures logging of request and response bodies by LoggingMiddleware
+type BodyLogOptions struct {
+	// Mode is BodyLogErrors for responses with status 400 and above, BodyLogSampled for SamplePercent of the requests
+	// or BodyLogOff
+	Mode          string
+	SamplePercent float64
+	// MaxSize is the number of bytes of a body logged at most, the rest is cut
+	MaxSize int
+	// RedactFields are names of JSON fields, XML elements and form fields whose values aren't logged, at any depth
+	RedactFields []string
+	// RedactHeaders are headers whose values aren't logged
+	RedactHeaders []string
+}
+
+// capture tells whether bodies of a new request may be logged, so they have to be captured
+func (o *BodyLogOptions) capture() bool {
+	switch {
+	case o == nil || o.MaxSize <= 0:
+		return false
+	case o.Mode == BodyLogErrors:
+		return true
+	case o.Mode == BodyLogSampled:
+		return rand.Float64()*100 < o.SamplePercent
+	}
+	return false
+}
+
+// logged tells whether bodies captured are logged for the response status
+func (o *BodyLogOptions) logged(status int) bool {
+	return o.Mode == BodyLogSampled || status >= http.StatusBadRequest
+}
+
+// redactField tells whether values of the field are kept out of the logs, names are compared case-insensitively
+func (o *BodyLogOptions) redactField(name string) bool {
+	for _, field := range o.RedactFields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// headers returns the headers to log, values of multiple ones are joined
+func (o *BodyLogOptions) headers(h http.Header) map[string]string {
+	logged := make(map[string]string, len(h))
+	for name, values := range h {
+		logged[name] = strings.Join(values, ", ")
+	}
+	for _, name := range o.RedactHeaders {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if _, ok := logged[name]; ok {
+			logged[name] = redacted
+		}
+	}
+	return logged
+}
+
+// attrs returns the attributes of a message with the headers h. Seen holds the first bytes of its body, size counts
+// all of them. Bodies of other than textual types, and YAML ones which aren't redacted, are only logged by size.
+func (o *BodyLogOptions) attrs(h http.Header, seen []byte, size int64) []interface{} {
+	attrs := []interface{}{"headers", o.headers(h), "size", size}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || size == 0 {
+		return attrs
+	}
+	var body string
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		body = redactJSON(seen, o.redactField)
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		body = redactXML(seen, o.redactField)
+	case mediaType == "application/x-www-form-urlencoded":
+		body = redactForm(seen, o.redactField)
+	case strings.HasPrefix(mediaType, "text/"):
+		body = string(seen)
+	default:
+		return attrs
+	}
+	return append(attrs, "body", body, "truncated", size > int64(len(seen)))
+}
+
+// redactJSON re-encodes the JSON document in data with values of redacted fields replaced. A document cut short is
+// re-encoded up to the last complete token, a malformed one up to where it goes wrong.
+func redactJSON(data []byte, redact func(string) bool) string {
+	type level struct {
+		object  bool
+		written int  // keys of an object, values of an array
+		key     bool // the next token of an object is a key
+	}
+	var (
+		out        bytes.Buffer
+		stack      []*level
+		redactNext bool
+	)
+	done := func() { // a value was written to the innermost level
+		if len(stack) == 0 {
+			return
+		}
+		if top := stack[len(stack)-1]; top.object {
+			top.key = true
+		} else {
+			top.written++
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			out.WriteByte(byte(delim))
+			stack = stack[:len(stack)-1]
+			done()
+			continue
+		}
+
+		var top *level
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch {
+		case top == nil:
+			if out.Len() > 0 {
+				out.WriteByte('\n')
+			}
+		case top.object && top.key:
+			if top.written > 0 {
+				out.WriteByte(',')
+			}
+			writeJSONToken(&out, token)
+			top.written++
+			top.key = false
+			redactNext = redact(token.(string))
+			continue
+		case top.object:
+			out.WriteByte(':')
+		case top.written > 0:
+			out.WriteByte(',')
+		}
+
+		delim, nested := token.(json.Delim)
+		switch {
+		case redactNext:
+			redactNext = false
+			writeJSONToken(&out, redacted)
+			if nested && skipJSONValue(dec) != nil {
+				return out.String()
+			}
+			done()
+		case nested:
+			out.WriteByte(byte(delim))
+			stack = append(stack, &level{object: delim == '{', key: delim == '{'})
+		default:
+			writeJSONToken(&out, token)
+			done()
+		}
+	}
+	return out.String()
+}
+
+// skipJSONValue reads the rest of the object or array whose opening delimiter was read
+func skipJSONValue(dec *json.Decoder) error {
+	for depth := 1; depth > 0; {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// writeJSONToken writes a string, number, boolean or null without escaping HTML characters
+func writeJSONToken(out *bytes.Buffer, token json.Token) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	if enc.Encode(token) == nil {
+		out.Truncate(out.Len() - 1) // the newline Encode ends with
+	}
+}
+
+// redactXML re-encodes the XML document in data with the content of redacted elements replaced. Like redactJSON, a
+// document cut short or malformed is re-encoded as far as it can be read.
+func redactXML(data []byte, redact func(string) bool) string {
+	var out bytes.Buffer
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	enc := xml.NewEncoder(&out)
+
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || !redact(start.Name.Local) {
+			if enc.EncodeToken(xml.CopyToken(token)) != nil {
+				break
+			}
+			continue
+		}
+
+		enc.EncodeToken(start)                  // nolint:errcheck
+		enc.EncodeToken(xml.CharData(redacted)) // nolint:errcheck
+		if dec.Skip() != nil {
+			break
+		}
+		enc.EncodeToken(start.End()) // nolint:errcheck
+	}
+	enc.Flush() // nolint:errcheck
+	return out.String()
+}
+
+// redactForm replaces values of redacted fields of the URL encoded form in data, keeping the order of the fields
+func redactForm(data []byte, redact func(string) bool) string {
+	pairs := strings.Split(string(data), "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && redact(name) {
+			pairs[i] = key + "=" + redacted
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// captureReader keeps the first bytes of a request body read by the handlers
+type captureReader struct {
+	io.ReadCloser
+	max  int
+	seen []byte
+	size int64
+}
+
+func (c *captureReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if keep := min(n, c.max-len(c.seen)); keep > 0 {
+		c.seen = append(c.seen, p[:keep]...)
+	}
+	c.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,353 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings of responses and request bodies
+const (
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// encodings are the content codings of responses, in the order of server preference
+var encodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// maxDecoderWindow bounds the memory a zstd request body may ask the decoder for
+const maxDecoderWindow = 8 << 20
+
+// CompressOptions configures Compress
+type CompressOptions struct {
+	// MinSize is the size in bytes of the smallest response compressed, smaller ones aren't worth it
+	MinSize int
+	// ContentTypes lists the media types compressed, like text/html, or text/* for every subtype
+	ContentTypes []string
+}
+
+// Compress encodes responses with the best content coding the client accepts in Accept-Encoding, zstd, br or gzip.
+// Responses are compressed when their type is listed and they reach MinSize, which they are buffered until.
+// Responses already encoded, partial ones and those marked no-transform are left as they are. So are those marked
+// private or no-store: they may carry secrets, like CSRF tokens, next to text the client sent, which compressed
+// sizes would give away (BREACH).
+func Compress(opts CompressOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, opts: &opts, encoding: encoding}
+			defer cw.Close() // nolint:errcheck
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiateEncoding picks the content coding of the response from the Accept-Encoding header value, an empty one
+// means the response goes out unencoded. Ties are resolved by the order of server preference.
+func negotiateEncoding(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "x-gzip" {
+			coding = EncodingGzip
+		}
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		accepted[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := accepted[encoding]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// resetWriter is an encoder which can be reused for another response
+type resetWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// encoders keeps encoders of every content coding for reuse, they are expensive to set up
+var encoders = map[string]*sync.Pool{
+	EncodingGzip: {New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	}},
+	EncodingBrotli: {New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, 5)
+	}},
+	EncodingZstd: {New: func() interface{} {
+		encoder, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1)) // nolint:errcheck
+		return encoder
+	}},
+}
+
+// compressWriter holds the response back until it knows whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	opts     *CompressOptions
+	encoding string
+
+	status  int
+	decided bool
+	buf     []byte
+	encoder resetWriter
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	if status < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+
+	h := cw.Header()
+	if status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent ||
+		h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" ||
+		strings.Contains(h.Get("Cache-Control"), "no-transform") || confidential(h.Get("Cache-Control")) {
+		cw.start(false)
+		return
+	}
+	if length, err := strconv.Atoi(h.Get("Content-Length")); err == nil && length < cw.opts.MinSize {
+		cw.start(false)
+	}
+}
+
+// confidential tells whether Cache-Control marks a response for a single user or none to store it
+func confidential(cacheControl string) bool {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name = strings.ToLower(name); name == "private" || name == "no-store" {
+			return true
+		}
+	}
+	return false
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.opts.MinSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// start sends the header and the body buffered so far, compressed when compress is true and the type allows it
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+
+	h := cw.Header()
+	contentType := h.Get("Content-Type")
+	if contentType == "" && len(cw.buf) > 0 {
+		// Sniffed here, the encoded body would be sniffed as binary otherwise
+		contentType = http.DetectContentType(cw.buf)
+		h.Set("Content-Type", contentType)
+	}
+	if cw.compressible(contentType) {
+		// Smaller responses of the type would be compressed, caches need to know either way
+		h.Add("Vary", "Accept-Encoding")
+	} else {
+		compress = false
+	}
+
+	if compress {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		// The encoded representation isn't byte for byte the one the strong validator was made for
+		if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.encoder = encoders[cw.encoding].Get().(resetWriter)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// compressible tells whether the content type is on the allow-list
+func (cw *compressWriter) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range cw.opts.ContentTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush sends what the handler wrote so far, a response flushed before reaching MinSize goes out uncompressed
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if !cw.decided {
+			cw.start(len(cw.buf) >= cw.opts.MinSize) // nolint:errcheck
+		}
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush() // nolint:errcheck
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close sends what is left of the response and returns the encoder to its pool
+func (cw *compressWriter) Close() error {
+	if !cw.decided && cw.status != 0 {
+		if err := cw.start(false); err != nil {
+			return err
+		}
+	}
+	if cw.encoder == nil {
+		return nil
+	}
+	err := cw.encoder.Close()
+	cw.encoder.Reset(io.Discard)
+	encoders[cw.encoding].Put(cw.encoder)
+	cw.encoder = nil
+	return err
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Decompress decodes request bodies sent with Content-Encoding gzip, br or zstd. Decoded bodies are limited to limit
+// bytes like the others, reading past it fails with *http.MaxBytesError, so small bodies can't inflate without bound.
+// Unsupported is called for other content codings after Accept-Encoding is set to the supported ones.
+func Decompress(limit int64, unsupported http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var decoded io.ReadCloser
+			switch encoding {
+			case EncodingGzip, "x-gzip":
+				decoded = &gzipReader{body: r.Body}
+			case EncodingBrotli:
+				decoded = readCloser{Reader: brotli.NewReader(r.Body), Closer: r.Body}
+			case EncodingZstd:
+				decoder, err := zstd.NewReader(r.Body, zstd.WithDecoderConcurrency(1),
+					zstd.WithDecoderMaxWindow(maxDecoderWindow), zstd.WithDecoderMaxMemory(uint64(limit)))
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				decoded = readCloser{Reader: decoder, Closer: closerFunc(func() error {
+					decoder.Close()
+					return r.Body.Close()
+				})}
+			default:
+				w.Header().Set("Accept-Encoding", strings.Join([]string{EncodingGzip, EncodingBrotli, EncodingZstd}, ", "))
+				unsupported(w, r)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, decoded, limit)
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// gzipReader reads the gzip header on first use, a malformed one fails reading the body like malformed data
+type gzipReader struct {
+	body   io.ReadCloser
+	reader *gzip.Reader
+}
+
+func (g *gzipReader) Read(p []byte) (int, error) {
+	if g.reader == nil {
+		reader, err := gzip.NewReader(g.body)
+		if err != nil {
+			return 0, err
+		}
+		g.reader = reader
+	}
+	return g.reader.Read(p)
+}
+
+func (g *gzipReader) Close() error {
+	return g.body.Close()
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
@@ -1,33 +1,39 @@
 package middleware
 
 import (
-	"bytes"
-	"io"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+
+	"rakia_blog_tt/logging"
 )
 
 type LoggerController struct {
 	logger  *slog.Logger
 	metrics MetricsInterface
+	bodyLog *BodyLogOptions
 }
 
 type MetricsInterface interface {
 	ObserveHTTPDuration(timeSince time.Time, path string, code int, method string)
 }
 
-func NewLoggerController(logger *slog.Logger, m MetricsInterface) *LoggerController {
-	return &LoggerController{logger: logger, metrics: m}
+// NewLoggerController creates the logging middleware, bodies are logged as bodyLog says, never when it's nil
+func NewLoggerController(logger *slog.Logger, m MetricsInterface, bodyLog *BodyLogOptions) *LoggerController {
+	return &LoggerController{logger: logger, metrics: m, bodyLog: bodyLog}
 }
 
 type statusRecorder struct {
 	http.ResponseWriter
-	Status       int
-	ResponseBody string
+	Status int
+
+	// The first MaxBody bytes of the response body are kept when MaxBody is set
+	MaxBody int
+	Body    []byte
+	Size    int64
 }
 
 func (r *statusRecorder) WriteHeader(status int) {
@@ -36,10 +42,17 @@ func (r *statusRecorder) WriteHeader(status int) {
 }
 
 func (r *statusRecorder) Write(body []byte) (int, error) {
-	r.ResponseBody = string(body)
+	if keep := min(len(body), r.MaxBody-len(r.Body)); keep > 0 {
+		r.Body = append(r.Body, body[:keep]...)
+	}
+	r.Size += int64(len(body))
 	return r.ResponseWriter.Write(body)
 }
 
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func (lc *LoggerController) LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		recorder := &statusRecorder{
@@ -47,14 +60,24 @@ func (lc *LoggerController) LoggingMiddleware(next http.Handler) http.Handler {
 			Status:         200,
 		}
 
-		request, err := io.ReadAll(r.Body)
-		if err != nil {
-			lc.logger.Info("http_logger: read request body")
-		}
+		// Lines carry the request ID, method and route when RequestID ran before. The route is logged rather than the
+		// path, paths like those of preview links carry credentials.
+		logger := logging.FromContext(r.Context(), lc.logger)
 
-		lc.logger.Info(string(request))
+		// The body is left to the handlers, reading it here would bypass the limits they decode it within
+		logger.Info("Request received", "method", r.Method, "content_length", r.ContentLength)
 
-		r.Body = io.NopCloser(bytes.NewBuffer(request))
+		// Only what the handlers read of the request body is seen, which keeps it within their limits
+		var request *captureReader
+		if lc.bodyLog.capture() {
+			recorder.MaxBody = lc.bodyLog.MaxSize
+			if r.Body != nil && r.Body != http.NoBody {
+				request = &captureReader{ReadCloser: r.Body, max: lc.bodyLog.MaxSize}
+				r.Body = request
+			} else {
+				request = &captureReader{}
+			}
+		}
 
 		start := time.Now()
 
@@ -62,8 +85,12 @@ func (lc *LoggerController) LoggingMiddleware(next http.Handler) http.Handler {
 
 		routeContext := chi.RouteContext(r.Context())
 		path := strings.Join(routeContext.RoutePatterns, "")
-		lc.logger.Info("Request handled", "path", path, "responce_status", recorder.Status, "method", r.Method)
+		logger.Info("Request handled", "path", path, "responce_status", recorder.Status, "method", r.Method)
 		lc.metrics.ObserveHTTPDuration(start, path, recorder.Status, r.Method)
-		lc.logger.Info("Response returned", "body", recorder.ResponseBody)
+		if request != nil && lc.bodyLog.logged(recorder.Status) {
+			logger.Info("Request and response bodies",
+				slog.Group("request", lc.bodyLog.attrs(r.Header, request.seen, request.size)...),
+				slog.Group("response", lc.bodyLog.attrs(w.Header(), recorder.Body, recorder.Size)...))
+		}
 	})
 }
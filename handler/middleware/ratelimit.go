@@ -0,0 +1,367 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"rakia_blog_tt/auth"
+)
+
+// Client kinds told apart by the rate limiter, they label the rejected requests metric
+const (
+	ClientIP     = "ip"
+	ClientUser   = "user"
+	ClientAPIKey = "api_key"
+)
+
+// sweepInterval is how often buckets refilled to their capacity are dropped, a full bucket is the same as none
+const sweepInterval = time.Minute
+
+// Limit allows Requests per Period to a client, all of them at once at most
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit reads a limit written as requests/period, like 100/1m
+func ParseLimit(value string) (Limit, error) {
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q is not requests/period", value)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("limit %q needs a positive number of requests", value)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q needs a positive period", value)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// ParseTrustedProxies reads addresses and CIDR ranges of proxies trusted to set X-Forwarded-For
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+type RateLimitMetrics interface {
+	ObserveRateLimited(route, client string)
+}
+
+// RateLimitOptions configures a RateLimiter
+type RateLimitOptions struct {
+	// Default applies to routes without a limit of their own, requests aren't limited when it's zero
+	Default Limit
+	// Routes holds limits of single routes by "METHOD /pattern", or by "/pattern" for every method.
+	// Patterns are those of the router, like "POST /posts/{id}/comments".
+	Routes map[string]Limit
+	// FailedAuth limits requests answered with 401 Unauthorized per client address, Default when it's zero
+	FailedAuth Limit
+	// TrustedProxies may tell the client address with X-Forwarded-For
+	TrustedProxies []netip.Prefix
+	Metrics        RateLimitMetrics
+	// Rejected writes the response of limited requests, Retry-After and RateLimit headers are set before
+	Rejected http.HandlerFunc
+}
+
+// RateLimiter limits requests of every client with token buckets. Clients are told apart by their principal when
+// authenticated, by their address otherwise. Routes with a limit of their own get a bucket of their own, the others
+// share the bucket of the default limit.
+type RateLimiter struct {
+	opts RateLimitOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func NewRateLimiter(opts RateLimitOptions) *RateLimiter {
+	if opts.Rejected == nil {
+		opts.Rejected = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}
+	}
+	return &RateLimiter{opts: opts, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Limit rejects requests of clients over their limit with 429 Too Many Requests. It identifies authenticated
+// clients only when it runs after the authentication middleware.
+func (rl *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routePattern(r)
+		name, limit := rl.limit(r.Method, route)
+		if limit.Requests == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client, kind := rl.client(r)
+		state := rl.take(name+" "+client, limit)
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		h.Set("RateLimit-Remaining", strconv.Itoa(state.remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(seconds(state.reset)))
+		h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(seconds(limit.Period)))
+		if !state.allowed {
+			h.Set("Retry-After", strconv.Itoa(max(1, seconds(state.retryAfter))))
+			if rl.opts.Metrics != nil {
+				rl.opts.Metrics.ObserveRateLimited(route, kind)
+			}
+			rl.opts.Rejected(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitFailedAuth limits requests failing authentication per client address, whatever credentials they carry.
+// It runs before the authentication middleware, so clients over the limit are turned away before their credentials
+// are verified again. Only responses with 401 Unauthorized take from the bucket.
+func (rl *RateLimiter) LimitFailedAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := rl.opts.FailedAuth
+		if limit.Requests == 0 {
+			limit = rl.opts.Default
+		}
+		if limit.Requests == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := "failed-auth ip:" + rl.clientIP(r).String()
+		if state := rl.peek(key); !state.allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, seconds(state.retryAfter))))
+			if rl.opts.Metrics != nil {
+				rl.opts.Metrics.ObserveRateLimited(routePattern(r), ClientIP)
+			}
+			rl.opts.Rejected(w, r)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, Status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.Status == http.StatusUnauthorized {
+			rl.take(key, limit)
+		}
+	})
+}
+
+type clientAddrKey struct{}
+
+// ResolveClient stores the address of the client in the request context, where ClientAddr finds it. It's the address
+// the limiter tells clients apart by, read from X-Forwarded-For when the request comes through a trusted proxy.
+func (rl *RateLimiter) ResolveClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientAddrKey{}, rl.clientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientAddr returns the client address stored by ResolveClient, ok is false when there is none
+func ClientAddr(ctx context.Context) (addr netip.Addr, ok bool) {
+	addr, ok = ctx.Value(clientAddrKey{}).(netip.Addr)
+	return addr, ok && addr.IsValid()
+}
+
+// limit returns the limit of the route and the name of its bucket
+func (rl *RateLimiter) limit(method, route string) (string, Limit) {
+	if limit, ok := rl.opts.Routes[method+" "+route]; ok && route != "" {
+		return method + " " + route, limit
+	}
+	if limit, ok := rl.opts.Routes[route]; ok && route != "" {
+		return route, limit
+	}
+	return "default", rl.opts.Default
+}
+
+// client returns the bucket key of the client and its kind
+func (rl *RateLimiter) client(r *http.Request) (string, string) {
+	if p, ok := auth.FromContext(r.Context()); ok && p.Subject != "" {
+		// Only API keys carry scopes
+		if p.Scopes != nil {
+			return "principal:" + p.Subject, ClientAPIKey
+		}
+		return "principal:" + p.Subject, ClientUser
+	}
+	return "ip:" + rl.clientIP(r).String(), ClientIP
+}
+
+// clientIP returns the address of the client. X-Forwarded-For is read from the right, every entry appended by a
+// trusted proxy is skipped, the first other one is the client. Entries left of it could be made up by the client.
+func (rl *RateLimiter) clientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+	if !rl.trusted(addr) {
+		return addr
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !rl.trusted(addr) {
+			break
+		}
+	}
+	return addr
+}
+
+func (rl *RateLimiter) trusted(addr netip.Addr) bool {
+	for _, prefix := range rl.opts.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+type bucketState struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration // until a request is allowed again
+	reset      time.Duration // until the bucket is full again
+}
+
+// take takes a token from the bucket of the key, creating a full one when there is none
+func (rl *RateLimiter) take(key string, limit Limit) bucketState {
+	now := rl.now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.swept) >= sweepInterval {
+		for k, b := range rl.buckets {
+			if b.tokensAt(now) >= float64(b.limit.Requests) {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.swept = now
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: float64(limit.Requests), updated: now}
+		rl.buckets[key] = b
+	}
+	return b.take(now)
+}
+
+// peek tells whether the bucket of the key has a token left without taking it, clients without a bucket have
+func (rl *RateLimiter) peek(key string) bucketState {
+	now := rl.now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		return bucketState{allowed: true}
+	}
+	tokens := b.tokensAt(now)
+	if tokens >= 1 {
+		return bucketState{allowed: true}
+	}
+	return bucketState{retryAfter: duration((1 - tokens) / b.rate())}
+}
+
+// bucket holds the tokens of a client, refilled continuously to the number of requests of the limit
+type bucket struct {
+	limit   Limit
+	tokens  float64
+	updated time.Time
+}
+
+// rate is the number of tokens added per second
+func (b *bucket) rate() float64 {
+	return float64(b.limit.Requests) / b.limit.Period.Seconds()
+}
+
+func (b *bucket) tokensAt(now time.Time) float64 {
+	return math.Min(float64(b.limit.Requests), b.tokens+now.Sub(b.updated).Seconds()*b.rate())
+}
+
+func (b *bucket) take(now time.Time) bucketState {
+	b.tokens = b.tokensAt(now)
+	b.updated = now
+
+	state := bucketState{allowed: b.tokens >= 1}
+	if state.allowed {
+		b.tokens--
+	} else {
+		state.retryAfter = duration((1 - b.tokens) / b.rate())
+	}
+	state.remaining = int(b.tokens)
+	state.reset = duration((float64(b.limit.Requests) - b.tokens) / b.rate())
+	return state
+}
+
+// routePattern finds the pattern of the route the router will take, middlewares run before routing completes.
+// A trailing slash is ignored, as RemoveTrailingSlash drops it before routing.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return ""
+	}
+	path := r.URL.Path
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	match := chi.NewRouteContext()
+	if !rctx.Routes.Match(match, r.Method, path) {
+		return ""
+	}
+	return match.RoutePattern()
+}
+
+func duration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -1,17 +1,17 @@
 package middleware
 
 import (
-	"log"
 	"net/http"
 )
 
+// RemoveTrailingSlash routes paths with a trailing slash like those without. Paths aren't logged, some carry
+// credentials like the token of preview links.
 func RemoveTrailingSlash(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		originalPath := r.URL.Path
 		if len(originalPath) > 1 && originalPath[len(originalPath)-1] == '/' {
 			r.URL.Path = originalPath[:len(originalPath)-1]
 		}
-		log.Printf("Original URL path: %s, Updated URL path: %s", originalPath, r.URL.Path)
 		next.ServeHTTP(w, r)
 	})
 }
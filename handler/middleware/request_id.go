@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"rakia_blog_tt/logging"
+)
+
+// RequestIDHeader carries the ID of a request from the client or a proxy, and back in the response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs taken from clients, they end up in every log line of the request
+const maxRequestIDLength = 128
+
+// RequestID identifies every request by the ID given in X-Request-ID, or a new one when it's missing or unfit for
+// logs, and echoes it in the response. The ID is stored in the context where chi's GetReqID finds it, along with a
+// logger whose lines carry the ID, the method and the route of the request.
+func RequestID(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			route := routePattern(r)
+			if route == "" {
+				route = r.URL.Path
+			}
+			ctx := context.WithValue(r.Context(), chimiddleware.RequestIDKey, id)
+			ctx = logging.NewContext(ctx, logger.With("request_id", id, "method", r.Method, "route", route))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, nothing which could forge log lines or headers
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:]) // nolint:errcheck
+	return hex.EncodeToString(b[:])
+}
@@ -7,6 +7,8 @@ package models
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
 
 	"github.com/go-openapi/errors"
 	"github.com/go-openapi/strfmt"
@@ -19,31 +21,92 @@ import (
 // swagger:model Post
 type Post struct {
 
-	// author
+	// Display name of the author, required unless author_id is given
 	// Example: Author 1
-	// Required: true
-	Author string `json:"author"`
+	Author string `json:"author" xml:"author"`
+
+	// ID of the post author, takes precedence over author
+	// Example: 1
+	AuthorID int64 `json:"author_id,omitempty" xml:"author_id,omitempty"`
+
+	// author summary
+	// Read Only: true
+	AuthorSummary *AuthorSummary `json:"author_summary,omitempty" xml:"author_summary,omitempty"`
+
+	// Path from the root category to the category of the post
+	// Read Only: true
+	Breadcrumb []*Category `json:"breadcrumb,omitempty" xml:"breadcrumb>category,omitempty"`
+
+	// ID of the post category
+	// Example: 3
+	CategoryID int64 `json:"category_id,omitempty" xml:"category_id,omitempty"`
 
 	// content
 	// Example: Quaerat sit dolorem velit. Ipsum non tempora magnam neque tempora. Tempora dolorem adipisci tempora neque labore. Dolorem sed dolore sed. Voluptatem consectetur dolor voluptatem. Quiquia adipisci voluptatem modi dolore. Dolor etincidunt neque consectetur dolor. Numquam etincidunt voluptatem sit amet tempora. Modi dolorem sed magnam consectetur. Dolor dolorem est amet magnam velit.
 	// Required: true
-	Content string `json:"content"`
+	Content string `json:"content" xml:"content"`
+
+	// Content rendered to sanitized HTML, present when requested with render=html
+	// Read Only: true
+	ContentHTML string `json:"content_html,omitempty" xml:"content_html,omitempty"`
+
+	// created at
+	// Example: 2024-05-01T10:00:00.000Z
+	// Read Only: true
+	// Format: date-time
+	CreatedAt strfmt.DateTime `json:"created_at,omitempty" xml:"created_at,omitempty"`
 
 	// id
 	// Example: 1
-	ID int64 `json:"id,omitempty"`
+	ID int64 `json:"id,omitempty" xml:"id,omitempty"`
+
+	// When a scheduled post gets published, or when a published post was published. Required in the future to schedule a post, ignored otherwise
+	// Example: 2024-05-02T08:00:00.000Z
+	// Format: date-time
+	PublishAt *strfmt.DateTime `json:"publish_at,omitempty" xml:"publish_at,omitempty"`
+
+	// URL friendly identifier, unique across posts. Generated from the title when empty and regenerated when the title changes, former slugs redirect to the current one
+	// Example: title-1
+	// Max Length: 80
+	// Pattern: ^[a-z0-9]+(?:-[a-z0-9]+)*$
+	Slug string `json:"slug,omitempty" xml:"slug,omitempty"`
+
+	// Workflow status, only published posts are shown to readers. New posts are drafts and an omitted status keeps the current one. Changes into or out of scheduled and published need posts.publish
+	// Example: published
+	// Enum: [draft in_review scheduled published archived]
+	Status string `json:"status,omitempty" xml:"status,omitempty"`
+
+	// Tags of the post, normalized into lowercase slugs on save
+	// Example: ["golang","web-development"]
+	// Max Items: 20
+	Tags []string `json:"tags,omitempty" xml:"tags>tag,omitempty"`
 
 	// title
 	// Example: Title 1
 	// Required: true
-	Title string `json:"title"`
+	Title string `json:"title" xml:"title"`
+
+	// updated at
+	// Example: 2024-05-02T10:00:00.000Z
+	// Read Only: true
+	// Format: date-time
+	UpdatedAt strfmt.DateTime `json:"updated_at,omitempty" xml:"updated_at,omitempty"`
+
+	// Incremented on every update
+	// Example: 1
+	// Read Only: true
+	Version int64 `json:"version,omitempty" xml:"version,omitempty"`
 }
 
 // Validate validates this post
 func (m *Post) Validate(formats strfmt.Registry) error {
 	var res []error
 
-	if err := m.validateAuthor(formats); err != nil {
+	if err := m.validateAuthorSummary(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateBreadcrumb(formats); err != nil {
 		res = append(res, err)
 	}
 
@@ -51,20 +114,80 @@ func (m *Post) Validate(formats strfmt.Registry) error {
 		res = append(res, err)
 	}
 
+	if err := m.validateCreatedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validatePublishAt(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateSlug(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateStatus(formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.validateTags(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if err := m.validateTitle(formats); err != nil {
 		res = append(res, err)
 	}
 
+	if err := m.validateUpdatedAt(formats); err != nil {
+		res = append(res, err)
+	}
+
 	if len(res) > 0 {
 		return errors.CompositeValidationError(res...)
 	}
 	return nil
 }
 
-func (m *Post) validateAuthor(formats strfmt.Registry) error {
+func (m *Post) validateAuthorSummary(formats strfmt.Registry) error {
+	if swag.IsZero(m.AuthorSummary) { // not required
+		return nil
+	}
+
+	if m.AuthorSummary != nil {
+		if err := m.AuthorSummary.Validate(formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("author_summary")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("author_summary")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Post) validateBreadcrumb(formats strfmt.Registry) error {
+	if swag.IsZero(m.Breadcrumb) { // not required
+		return nil
+	}
+
+	for i := 0; i < len(m.Breadcrumb); i++ {
+		if swag.IsZero(m.Breadcrumb[i]) { // not required
+			continue
+		}
+
+		if m.Breadcrumb[i] != nil {
+			if err := m.Breadcrumb[i].Validate(formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("breadcrumb" + "." + strconv.Itoa(i))
+				} else if ce, ok := err.(*errors.CompositeError); ok {
+					return ce.ValidateName("breadcrumb" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
 
-	if err := validate.RequiredString("author", "body", m.Author); err != nil {
-		return err
 	}
 
 	return nil
@@ -79,6 +202,123 @@ func (m *Post) validateContent(formats strfmt.Registry) error {
 	return nil
 }
 
+func (m *Post) validateCreatedAt(formats strfmt.Registry) error {
+	if swag.IsZero(m.CreatedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("created_at", "body", "date-time", m.CreatedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Post) validatePublishAt(formats strfmt.Registry) error {
+	if swag.IsZero(m.PublishAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("publish_at", "body", "date-time", m.PublishAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Post) validateSlug(formats strfmt.Registry) error {
+	if swag.IsZero(m.Slug) { // not required
+		return nil
+	}
+
+	if err := validate.MaxLength("slug", "body", m.Slug, 80); err != nil {
+		return err
+	}
+
+	if err := validate.Pattern("slug", "body", m.Slug, `^[a-z0-9]+(?:-[a-z0-9]+)*$`); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var postTypeStatusPropEnum []interface{}
+
+func init() {
+	var res []string
+	if err := json.Unmarshal([]byte(`["draft","in_review","scheduled","published","archived"]`), &res); err != nil {
+		panic(err)
+	}
+	for _, v := range res {
+		postTypeStatusPropEnum = append(postTypeStatusPropEnum, v)
+	}
+}
+
+const (
+
+	// PostStatusDraft captures enum value "draft"
+	PostStatusDraft string = "draft"
+
+	// PostStatusInReview captures enum value "in_review"
+	PostStatusInReview string = "in_review"
+
+	// PostStatusScheduled captures enum value "scheduled"
+	PostStatusScheduled string = "scheduled"
+
+	// PostStatusPublished captures enum value "published"
+	PostStatusPublished string = "published"
+
+	// PostStatusArchived captures enum value "archived"
+	PostStatusArchived string = "archived"
+)
+
+// prop value enum
+func (m *Post) validateStatusEnum(path, location string, value string) error {
+	if err := validate.EnumCase(path, location, value, postTypeStatusPropEnum, true); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Post) validateStatus(formats strfmt.Registry) error {
+	if swag.IsZero(m.Status) { // not required
+		return nil
+	}
+
+	// value enum
+	if err := m.validateStatusEnum("status", "body", m.Status); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Post) validateTags(formats strfmt.Registry) error {
+	if swag.IsZero(m.Tags) { // not required
+		return nil
+	}
+
+	iTagsSize := int64(len(m.Tags))
+
+	if err := validate.MaxItems("tags", "body", iTagsSize, 20); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(m.Tags); i++ {
+
+		if err := validate.MinLength("tags"+"."+strconv.Itoa(i), "body", m.Tags[i], 1); err != nil {
+			return err
+		}
+
+		if err := validate.MaxLength("tags"+"."+strconv.Itoa(i), "body", m.Tags[i], 50); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+}
+
 func (m *Post) validateTitle(formats strfmt.Registry) error {
 
 	if err := validate.RequiredString("title", "body", m.Title); err != nil {
@@ -88,8 +328,135 @@ func (m *Post) validateTitle(formats strfmt.Registry) error {
 	return nil
 }
 
-// ContextValidate validates this post based on context it is used
+func (m *Post) validateUpdatedAt(formats strfmt.Registry) error {
+	if swag.IsZero(m.UpdatedAt) { // not required
+		return nil
+	}
+
+	if err := validate.FormatOf("updated_at", "body", "date-time", m.UpdatedAt.String(), formats); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ContextValidate validate this post based on the context it is used
 func (m *Post) ContextValidate(ctx context.Context, formats strfmt.Registry) error {
+	var res []error
+
+	if err := m.contextValidateAuthorSummary(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.contextValidateBreadcrumb(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.contextValidateContentHTML(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.contextValidateCreatedAt(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.contextValidateUpdatedAt(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if err := m.contextValidateVersion(ctx, formats); err != nil {
+		res = append(res, err)
+	}
+
+	if len(res) > 0 {
+		return errors.CompositeValidationError(res...)
+	}
+	return nil
+}
+
+func (m *Post) contextValidateAuthorSummary(ctx context.Context, formats strfmt.Registry) error {
+
+	if m.AuthorSummary != nil {
+
+		if swag.IsZero(m.AuthorSummary) { // not required
+			return nil
+		}
+
+		if err := m.AuthorSummary.ContextValidate(ctx, formats); err != nil {
+			if ve, ok := err.(*errors.Validation); ok {
+				return ve.ValidateName("author_summary")
+			} else if ce, ok := err.(*errors.CompositeError); ok {
+				return ce.ValidateName("author_summary")
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Post) contextValidateBreadcrumb(ctx context.Context, formats strfmt.Registry) error {
+
+	if err := validate.ReadOnly(ctx, "breadcrumb", "body", []*Category(m.Breadcrumb)); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(m.Breadcrumb); i++ {
+
+		if m.Breadcrumb[i] != nil {
+
+			if swag.IsZero(m.Breadcrumb[i]) { // not required
+				return nil
+			}
+
+			if err := m.Breadcrumb[i].ContextValidate(ctx, formats); err != nil {
+				if ve, ok := err.(*errors.Validation); ok {
+					return ve.ValidateName("breadcrumb" + "." + strconv.Itoa(i))
+				} else if ce, ok := err.(*errors.CompositeError); ok {
+					return ce.ValidateName("breadcrumb" + "." + strconv.Itoa(i))
+				}
+				return err
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func (m *Post) contextValidateContentHTML(ctx context.Context, formats strfmt.Registry) error {
+
+	if err := validate.ReadOnly(ctx, "content_html", "body", string(m.ContentHTML)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Post) contextValidateCreatedAt(ctx context.Context, formats strfmt.Registry) error {
+
+	if err := validate.ReadOnly(ctx, "created_at", "body", strfmt.DateTime(m.CreatedAt)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Post) contextValidateUpdatedAt(ctx context.Context, formats strfmt.Registry) error {
+
+	if err := validate.ReadOnly(ctx, "updated_at", "body", strfmt.DateTime(m.UpdatedAt)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (m *Post) contextValidateVersion(ctx context.Context, formats strfmt.Registry) error {
+
+	if err := validate.ReadOnly(ctx, "version", "body", int64(m.Version)); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-openapi/strfmt"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/moderation"
+	"rakia_blog_tt/service"
+)
+
+// GetModerationQueue lists comments of every post with the ?status= moderation status, pending by default
+func (h *Handler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	status := moderation.Pending
+	if value := r.URL.Query().Get("status"); value != "" {
+		status = moderation.Status(value)
+	}
+
+	comments, err := h.service.GetModerationQueue(r.Context(), status)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownModerationStatus):
+			writeProblem(w, r, http.StatusBadRequest, "Unknown moderation status", &models.ProblemFieldError{
+				Name:    "status",
+				In:      "query",
+				Message: "status in query should be one of [pending approved rejected spam]",
+			})
+		case !writeCommentError(w, r, err):
+			h.log(r.Context()).Error("failed to get moderation queue", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to retrieve moderation queue")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, commentMediaTypes, comments)
+}
+
+// ModerateComments sets the moderation status of several comments at once
+func (h *Handler) ModerateComments(w http.ResponseWriter, r *http.Request) {
+	var decision models.CommentModeration
+	if err := decodeBody(r, &decision); err != nil {
+		h.log(r.Context()).Error("moderation decode failed", "error", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	if err := decision.Validate(strfmt.NewFormats()); err != nil {
+		h.log(r.Context()).Error("invalid moderation format", "error", err)
+		writeValidationProblem(w, r, "Invalid moderation format", err)
+		return
+	}
+
+	result, err := h.service.ModerateComments(r.Context(), decision.Ids, moderation.Status(decision.Status))
+	if err != nil {
+		if !writeCommentError(w, r, err) {
+			h.log(r.Context()).Error("failed to moderate comments", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to moderate comments")
+		}
+		return
+	}
+
+	h.respond(w, r, http.StatusOK, moderationTypes, result)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/handler/models"
+)
+
+const (
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// errInvalidPatch is returned when the patch document itself is malformed,
+// as opposed to a well-formed patch which cannot be applied to the post.
+var errInvalidPatch = errors.New("invalid patch document")
+
+// errPatchTestFailed is returned when a test operation of a JSON Patch doesn't hold
+var errPatchTestFailed = errors.New("patch test operation failed")
+
+// applyPatch applies the patch document of the given media type to the post and returns the result.
+// The result is not validated here.
+func applyPatch(mediaType string, post models.Post, patch []byte) (models.Post, error) {
+	original, err := json.Marshal(post)
+	if err != nil {
+		return models.Post{}, errors.Wrap(err, "marshal post")
+	}
+
+	var patched []byte
+	switch mediaType {
+	case mediaTypeMergePatch:
+		if !json.Valid(patch) {
+			return models.Post{}, errInvalidPatch
+		}
+		patched, err = jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return models.Post{}, fmt.Errorf("%w: %w", errInvalidPatch, err)
+		}
+	case mediaTypeJSONPatch:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return models.Post{}, fmt.Errorf("%w: %w", errInvalidPatch, err)
+		}
+		// Operations are applied one by one to tell failed tests from operations which don't apply
+		patched = original
+		for _, operation := range operations {
+			patched, err = jsonpatch.Patch{operation}.Apply(patched)
+			if err != nil && operation.Kind() == "test" {
+				return models.Post{}, fmt.Errorf("%w: %w", errPatchTestFailed, err)
+			}
+			if err != nil {
+				return models.Post{}, errors.Wrapf(err, "apply json patch %s operation", operation.Kind())
+			}
+		}
+	default:
+		return models.Post{}, errors.Errorf("unsupported patch media type %q", mediaType)
+	}
+
+	var result models.Post
+	if err := decodeJSON(patched, &result); err != nil {
+		return models.Post{}, fmt.Errorf("%w: %w", errInvalidPatch, err)
+	}
+
+	return result, nil
+}
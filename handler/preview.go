@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-openapi/strfmt"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/handler/web"
+	"rakia_blog_tt/service"
+)
+
+// writePreviewError maps preview link errors of the service into problem responses and reports whether err was one of them
+func writePreviewError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrPreviewsDisabled):
+		writeProblem(w, r, http.StatusNotImplemented, "Preview links are not enabled")
+	case errors.Is(err, service.ErrPostNotFound):
+		writeProblem(w, r, http.StatusNotFound, "Post not found")
+	case errors.Is(err, service.ErrExpiryInPast):
+		writeProblem(w, r, http.StatusBadRequest, "Invalid preview format", &models.ProblemFieldError{
+			Name:    "expires_at",
+			In:      "body",
+			Message: "expires_at in body must be in the future",
+		})
+	case errors.Is(err, service.ErrPreviewTooLong):
+		writeProblem(w, r, http.StatusBadRequest, "Invalid preview format", &models.ProblemFieldError{
+			Name:    "expires_at",
+			In:      "body",
+			Message: "expires_at in body must be within " + strconv.Itoa(int(service.MaxPreviewTTL/(24*time.Hour))) + " days",
+		})
+	default:
+		return writeAccessError(w, r, err)
+	}
+	return true
+}
+
+// CreatePreview mints a preview link of the post. The body is optional, it may ask for an expiry.
+func (h *Handler) CreatePreview(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	var preview models.Preview
+	if r.ContentLength != 0 {
+		if err := decodeBody(r, &preview); err != nil && !errors.Is(err, io.EOF) {
+			h.log(r.Context()).Error("preview decode failed", "error", err)
+			writeDecodeError(w, r, err)
+			return
+		}
+	}
+	if err := preview.Validate(strfmt.NewFormats()); err != nil {
+		h.log(r.Context()).Error("invalid preview format", "error", err)
+		writeValidationProblem(w, r, "Invalid preview format", err)
+		return
+	}
+
+	if _, err := negotiate(r.Header.Get("Accept"), previewMediaTypes); err != nil {
+		writeProblem(w, r, http.StatusNotAcceptable, "Supported media types: "+strings.Join(previewMediaTypes, ", "))
+		return
+	}
+
+	var expiresAt *time.Time
+	if preview.ExpiresAt != nil {
+		expiry := time.Time(*preview.ExpiresAt)
+		expiresAt = &expiry
+	}
+	created, err := h.service.CreatePreview(r.Context(), id, expiresAt)
+	if err != nil {
+		if !writePreviewError(w, r, err) {
+			h.log(r.Context()).Error("failed to create preview link", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to create preview link")
+		}
+		return
+	}
+	created.URL = web.PreviewPath + "/" + created.Token
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.respond(w, r, http.StatusCreated, previewMediaTypes, created)
+}
+
+// RevokePreviews invalidates every preview link of the post minted so far
+func (h *Handler) RevokePreviews(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid post ID")
+		return
+	}
+
+	if err := h.service.RevokePreviews(r.Context(), id); err != nil {
+		if !writePreviewError(w, r, err) {
+			h.log(r.Context()).Error("failed to revoke preview links", "error", err)
+			writeProblem(w, r, http.StatusInternalServerError, "Failed to revoke preview links")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
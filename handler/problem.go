@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	oaerrors "github.com/go-openapi/errors"
+
+	"rakia_blog_tt/handler/models"
+)
+
+const (
+	mediaTypeProblem = "application/problem+json"
+
+	problemTypeDefault    = "about:blank"
+	problemTypeValidation = "/problems/validation-error"
+)
+
+// writeProblem responds with an RFC 7807 problem document.
+// Title is always the status text, so "about:blank" keeps its meaning for non-validation problems.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, fieldErrs ...*models.ProblemFieldError) {
+	problemType := problemTypeDefault
+	if len(fieldErrs) > 0 {
+		problemType = problemTypeValidation
+	}
+	if fieldErrs == nil {
+		fieldErrs = []*models.ProblemFieldError{}
+	}
+
+	problem := models.Problem{
+		Type:      problemType,
+		Title:     http.StatusText(status),
+		Status:    int64(status),
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: chimiddleware.GetReqID(r.Context()),
+		Errors:    fieldErrs,
+	}
+
+	w.Header().Set("Content-Type", mediaTypeProblem)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem) // nolint:errcheck
+}
+
+// writeValidationProblem responds with 400 and lists every invalid field found in err.
+func writeValidationProblem(w http.ResponseWriter, r *http.Request, detail string, err error) {
+	writeProblem(w, r, http.StatusBadRequest, detail, fieldErrors(err)...)
+}
+
+// writeTooLarge responds with 413 to request bodies over the limit of the BodyLimit middleware.
+func writeTooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", limit))
+}
+
+// fieldErrors flattens validation errors produced by models' Validate and by the JSON decoder.
+func fieldErrors(err error) []*models.ProblemFieldError {
+	var (
+		composite  *oaerrors.CompositeError
+		validation *oaerrors.Validation
+		typeErr    *json.UnmarshalTypeError
+		unknown    *unknownFieldError
+	)
+
+	switch {
+	case errors.As(err, &composite):
+		var result []*models.ProblemFieldError
+		for _, inner := range composite.Errors {
+			result = append(result, fieldErrors(inner)...)
+		}
+		return result
+	case errors.As(err, &validation):
+		return []*models.ProblemFieldError{{
+			Name:    validation.Name,
+			In:      validation.In,
+			Code:    int64(validation.Code()),
+			Message: validation.Error(),
+		}}
+	case errors.As(err, &typeErr):
+		return []*models.ProblemFieldError{{
+			Name:    typeErr.Field,
+			In:      "body",
+			Message: typeErr.Field + " in body must be of type " + typeErr.Type.String(),
+		}}
+	case errors.As(err, &unknown):
+		return []*models.ProblemFieldError{{
+			Name:    unknown.Field,
+			In:      "body",
+			Message: unknown.Field + " in body is not a known field",
+		}}
+	}
+
+	return nil
+}
+
+// NotFound is used by the router for unknown routes.
+func (h *Handler) NotFound(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusNotFound, "Resource not found")
+}
+
+// MethodNotAllowed is used by the router for known routes called with an unsupported method.
+func (h *Handler) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+}
+
+// TooManyRequests is used by the rate limiter for clients over their limit.
+func (h *Handler) TooManyRequests(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusTooManyRequests, "Too many requests, retry after "+w.Header().Get("Retry-After")+" seconds")
+}
@@ -5,24 +5,164 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/rs/cors"
 
+	"rakia_blog_tt/auth"
 	"rakia_blog_tt/handler/middleware"
+	"rakia_blog_tt/handler/web"
 )
 
-func NewRouter(hnd Handler, logger *slog.Logger, metrics middleware.MetricsInterface) http.Handler {
+// DefaultMaxBodySize limits request bodies unless RouterOptions set another limit
+const DefaultMaxBodySize = 1 << 20
+
+// RouterOptions holds the optional parts of the router
+type RouterOptions struct {
+	// RateLimiter limits requests when set, its Rejected handler should be Handler.TooManyRequests
+	RateLimiter *middleware.RateLimiter
+	// MaxBodySize limits request bodies in bytes, DefaultMaxBodySize when zero
+	MaxBodySize int64
+	// CORS lets browsers call the API from the allowed origins when set
+	CORS *cors.Options
+	// Compression compresses responses when set, compressed request bodies are taken either way
+	Compression *middleware.CompressOptions
+	// BodyLog logs bodies and headers of requests when set
+	BodyLog *middleware.BodyLogOptions
+}
+
+// NewRouter routes the API and the pages
+func NewRouter(hnd Handler, pages *web.Handler, logger *slog.Logger, metrics middleware.MetricsInterface, authn *Authenticator,
+	opts RouterOptions) http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(middleware.NewLoggerController(logger, metrics).LoggingMiddleware)
+	limit := func(next http.Handler) http.Handler { return next }
+	limitFailedAuth := limit
+	if opts.RateLimiter != nil {
+		limit = opts.RateLimiter.Limit
+		limitFailedAuth = opts.RateLimiter.LimitFailedAuth
+	}
+	maxBodySize := opts.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	r.Use(middleware.RequestID(logger))
+	if opts.RateLimiter != nil {
+		r.Use(opts.RateLimiter.ResolveClient)
+	}
+	// Preflight requests are answered before routing, so every route takes them. Rejections of the later middlewares
+	// carry the CORS headers, browsers let clients read them.
+	if opts.CORS != nil {
+		r.Use(cors.New(*opts.CORS).Handler)
+	}
+	if opts.Compression != nil {
+		r.Use(middleware.Compress(*opts.Compression))
+	}
+	r.Use(middleware.BodyLimit(maxBodySize, func(w http.ResponseWriter, r *http.Request) {
+		writeTooLarge(w, r, maxBodySize)
+	}))
+	// Decoded bodies are held to the same limit as the others
+	r.Use(middleware.Decompress(maxBodySize, func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "Supported content encodings: "+w.Header().Get("Accept-Encoding"))
+	}))
+	r.Use(middleware.NewLoggerController(logger, metrics, opts.BodyLog).LoggingMiddleware)
 	r.Use(middleware.RemoveTrailingSlash)
 
-	r.Get("/", hnd.DefaultHandler)
+	r.NotFound(hnd.NotFound)
+	r.MethodNotAllowed(hnd.MethodNotAllowed)
+
+	// Clients of the pages are told apart by their address, their sessions are only known to the pages
+	r.Group(func(r chi.Router) {
+		r.Use(limit)
+
+		r.Get("/", hnd.DefaultHandler)
+
+		r.Mount(web.BasePath, pages.Routes()) // HTML pages of the blog
+
+		r.Get("/feed.rss", pages.RSS)                          // GET /feed.rss
+		r.Get("/feed.atom", pages.Atom)                        // GET /feed.atom
+		r.Get("/authors/{author}/feed.atom", pages.AuthorAtom) // GET /authors/{author}/feed.atom
+		r.Get("/sitemap.xml", pages.Sitemap)                   // GET /sitemap.xml
+		r.Get("/sitemaps/{file}", pages.ChildSitemap)          // GET /sitemaps/{file}
+		r.Get(web.PreviewPath+"/{token}", pages.Preview)       // GET /preview/{token}
+	})
+
+	// Whether a client may call the API is up to the policy of the service, the middlewares only
+	// recognize the client and keep scoped credentials within their scopes
+	r.Group(func(r chi.Router) {
+		r.Use(limitFailedAuth) // before Identify, so bad credentials aren't verified again and again
+		r.Use(authn.Identify)
+		r.Use(limit) // after Identify, so authenticated clients are limited by who they are
+
+		r.Route("/tags", func(r chi.Router) {
+			r.Use(RequireScope(auth.ScopePostsRead))
+
+			r.Get("/", hnd.GetTags)                // GET /tags
+			r.Get("/{tag}/posts", hnd.GetTagPosts) // GET /tags/{tag}/posts
+		})
+
+		r.Route("/categories", func(r chi.Router) {
+			r.With(RequireScope(auth.ScopePostsRead)).Get("/", hnd.GetCategories)   // GET /categories
+			r.With(RequireScope(auth.ScopePostsRead)).Get("/{id}", hnd.GetCategory) // GET /categories/{id}
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireScope(auth.ScopeAdmin))
+
+				r.Post("/", hnd.CreateCategory)       // POST /categories
+				r.Put("/{id}", hnd.UpdateCategory)    // PUT /categories/{id}
+				r.Delete("/{id}", hnd.DeleteCategory) // DELETE /categories/{id}
+			})
+		})
+
+		r.Route("/authors", func(r chi.Router) {
+			r.With(RequireScope(auth.ScopePostsRead)).Get("/", hnd.GetAuthors)    // GET /authors
+			r.With(RequireScope(auth.ScopePostsRead)).Get("/{id}", hnd.GetAuthor) // GET /authors/{id}
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireScope(auth.ScopeAdmin))
+
+				r.Post("/", hnd.CreateAuthor)       // POST /authors
+				r.Put("/{id}", hnd.UpdateAuthor)    // PUT /authors/{id}
+				r.Delete("/{id}", hnd.DeleteAuthor) // DELETE /authors/{id}
+			})
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(RequireScope(auth.ScopeAdmin))
+
+			r.Get("/comments", hnd.GetModerationQueue)           // GET /admin/comments
+			r.Post("/comments/moderation", hnd.ModerateComments) // POST /admin/comments/moderation
+
+			r.Get("/api-keys", hnd.GetAPIKeys)           // GET /admin/api-keys
+			r.Post("/api-keys", hnd.CreateAPIKey)        // POST /admin/api-keys
+			r.Delete("/api-keys/{id}", hnd.RevokeAPIKey) // DELETE /admin/api-keys/{id}
+		})
+
+		r.Route("/posts", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(RequireScope(auth.ScopePostsRead))
+
+				r.Get("/", hnd.GetPosts)                    // GET /posts
+				r.Get("/{id}", hnd.GetPost)                 // GET /posts/{id}
+				r.Get("/by-slug/{slug}", hnd.GetPostBySlug) // GET /posts/by-slug/{slug}
+				r.Get("/{id}/comments", hnd.GetComments)    // GET /posts/{id}/comments
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireScope(auth.ScopePostsWrite))
+
+				r.Post("/", hnd.CreatePost)       // POST /posts
+				r.Put("/{id}", hnd.UpdatePost)    // PUT /posts/{id}
+				r.Patch("/{id}", hnd.PatchPost)   // PATCH /posts/{id}
+				r.Delete("/{id}", hnd.DeletePost) // DELETE /posts/{id}
+
+				r.Post("/{id}/previews", hnd.CreatePreview)    // POST /posts/{id}/previews
+				r.Delete("/{id}/previews", hnd.RevokePreviews) // DELETE /posts/{id}/previews
 
-	r.Route("/posts", func(r chi.Router) {
-		r.Get("/", hnd.GetPosts)          // GET /posts
-		r.Post("/", hnd.CreatePost)       // POST /posts
-		r.Get("/{id}", hnd.GetPost)       // GET /posts/{id}
-		r.Put("/{id}", hnd.UpdatePost)    // PUT /posts/{id}
-		r.Delete("/{id}", hnd.DeletePost) // DELETE /posts/{id}
+				r.Post("/{id}/comments", hnd.CreateComment)               // POST /posts/{id}/comments
+				r.Put("/{id}/comments/{commentID}", hnd.UpdateComment)    // PUT /posts/{id}/comments/{commentID}
+				r.Delete("/{id}/comments/{commentID}", hnd.DeleteComment) // DELETE /posts/{id}/comments/{commentID}
+			})
+		})
 	})
 
 	return r
@@ -0,0 +1,246 @@
+package web
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/service"
+)
+
+const (
+	// SessionCookie holds the token of the login session
+	SessionCookie = "session"
+	// csrfCookie holds the token expected in login and signup forms, which are posted before there is a session
+	csrfCookie = "csrf"
+	// csrfField is the form field every posted form carries its CSRF token in
+	csrfField = "csrf_token"
+	// privateCacheControl keeps pages showing the logged-in user out of shared caches
+	privateCacheControl = "private, no-cache"
+)
+
+type sessionKey struct{}
+
+// sessionFrom returns the session the request was made in
+func sessionFrom(ctx context.Context) (service.Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(service.Session)
+	return session, ok
+}
+
+// Sessions resolves the session cookie, requests of a valid session act as its user.
+// Unknown and expired sessions are treated as anonymous and their cookie is cleared.
+func (h *Handler) Sessions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(SessionCookie)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session, err := h.app.AuthenticateSession(r.Context(), cookie.Value)
+		if err != nil {
+			if !errors.Is(err, service.ErrInvalidSession) {
+				h.log(r.Context()).Error("failed to authenticate session", "error", err)
+			}
+			h.clearCookie(w, SessionCookie)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := auth.NewContext(r.Context(), session.Principal)
+		ctx = context.WithValue(ctx, sessionKey{}, session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// SignUpForm shows the form creating an account
+func (h *Handler) SignUpForm(w http.ResponseWriter, r *http.Request) {
+	h.renderAccountForm(w, r, http.StatusOK, "signup", accountForm{})
+}
+
+// SignUp creates an account and logs its user in
+func (h *Handler) SignUp(w http.ResponseWriter, r *http.Request) {
+	if !h.validCSRF(r) {
+		h.renderError(w, r, http.StatusForbidden, "The form expired, please try again")
+		return
+	}
+
+	form := accountForm{Email: strings.TrimSpace(r.PostFormValue("email")), Name: strings.TrimSpace(r.PostFormValue("name"))}
+	user := models.User{Email: strfmt.Email(form.Email), Name: form.Name}
+	if err := user.Validate(strfmt.Default); err != nil {
+		form.Error = "Please enter your name and a valid email"
+		h.renderAccountForm(w, r, http.StatusBadRequest, "signup", form)
+		return
+	}
+
+	password := r.PostFormValue("password")
+	if _, err := h.app.SignUp(r.Context(), user, password); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidPassword):
+			form.Error = "Passwords must be 10 to 128 characters long"
+			h.renderAccountForm(w, r, http.StatusBadRequest, "signup", form)
+		case errors.Is(err, service.ErrEmailTaken):
+			form.Error = "An account with this email already exists"
+			h.renderAccountForm(w, r, http.StatusConflict, "signup", form)
+		default:
+			h.accountError(w, r, err)
+		}
+		return
+	}
+
+	h.login(w, r, form, user.Email.String(), password)
+}
+
+// LoginForm shows the login form
+func (h *Handler) LoginForm(w http.ResponseWriter, r *http.Request) {
+	h.renderAccountForm(w, r, http.StatusOK, "login", accountForm{})
+}
+
+// Login starts a session and sets its cookie
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	if !h.validCSRF(r) {
+		h.renderError(w, r, http.StatusForbidden, "The form expired, please try again")
+		return
+	}
+
+	email := strings.TrimSpace(r.PostFormValue("email"))
+	h.login(w, r, accountForm{Email: email}, email, r.PostFormValue("password"))
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request, form accountForm, email, password string) {
+	session, err := h.app.Login(r.Context(), email, password)
+	if err != nil {
+		var throttled *service.ThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			form.Error = "Too many failed logins, please try again later"
+			h.renderAccountForm(w, r, http.StatusTooManyRequests, "login", form)
+		case errors.Is(err, service.ErrInvalidCredentials):
+			form.Error = "Invalid email or password"
+			h.renderAccountForm(w, r, http.StatusUnauthorized, "login", form)
+		default:
+			h.accountError(w, r, err)
+		}
+		return
+	}
+
+	http.SetCookie(w, h.cookie(SessionCookie, session.Token, session.ExpiresAt))
+	h.clearCookie(w, csrfCookie)
+	http.Redirect(w, r, BasePath, http.StatusSeeOther)
+}
+
+// Logout ends the session, with the everywhere field set every session of the user is ended
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	session, ok := sessionFrom(r.Context())
+	if !ok {
+		h.clearCookie(w, SessionCookie)
+		http.Redirect(w, r, BasePath, http.StatusSeeOther)
+		return
+	}
+	if !h.validCSRF(r) {
+		h.renderError(w, r, http.StatusForbidden, "The form expired, please try again")
+		return
+	}
+
+	var err error
+	if r.PostFormValue("everywhere") != "" {
+		err = h.app.RevokeSessions(r.Context(), session.User.ID)
+	} else {
+		err = h.app.Logout(r.Context(), session.Token)
+	}
+	if err != nil {
+		h.accountError(w, r, err)
+		return
+	}
+
+	h.clearCookie(w, SessionCookie)
+	http.Redirect(w, r, BasePath, http.StatusSeeOther)
+}
+
+type accountForm struct {
+	Email string
+	Name  string
+	Error string
+}
+
+// renderAccountForm renders the login or signup page. Without a session the form is protected by a double-submit
+// token: the same random value goes into a cookie and a hidden field, which other sites can't read to copy.
+func (h *Handler) renderAccountForm(w http.ResponseWriter, r *http.Request, status int, page string, form accountForm) {
+	title := "Log in"
+	if page == "signup" {
+		title = "Sign up"
+	}
+	data := h.data(r, title+" - "+h.site.Title, title, "website")
+	data.Heading = title
+	data.Form = form
+
+	if data.User == nil {
+		token := ""
+		if cookie, err := r.Cookie(csrfCookie); err == nil && cookie.Value != "" {
+			token = cookie.Value
+		} else {
+			generated, err := auth.GenerateToken()
+			if err != nil {
+				h.log(r.Context()).Error("failed to generate CSRF token", "error", err)
+				h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+				return
+			}
+			token = generated
+			http.SetCookie(w, h.cookie(csrfCookie, token, time.Time{}))
+		}
+		data.CSRFToken = token
+	}
+
+	h.renderStatus(w, r, status, page, data)
+}
+
+// validCSRF reports whether the posted form carries the CSRF token of the session, or of the csrf cookie without one
+func (h *Handler) validCSRF(r *http.Request) bool {
+	expected := ""
+	if session, ok := sessionFrom(r.Context()); ok {
+		expected = session.CSRFToken
+	} else if cookie, err := r.Cookie(csrfCookie); err == nil {
+		expected = cookie.Value
+	}
+
+	posted := r.PostFormValue(csrfField)
+	return expected != "" && subtle.ConstantTimeCompare([]byte(posted), []byte(expected)) == 1
+}
+
+func (h *Handler) accountError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, service.ErrUsersDisabled) {
+		h.renderError(w, r, http.StatusNotFound, "Page not found")
+		return
+	}
+	h.log(r.Context()).Error("failed to handle account", "error", err)
+	h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+}
+
+// cookie returns an HttpOnly cookie of the blog, a zero expiry makes it last until the browser is closed
+func (h *Handler) cookie(name, value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     BasePath,
+		Expires:  expires,
+		Secure:   h.site.SecureCookies,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (h *Handler) clearCookie(w http.ResponseWriter, name string) {
+	cookie := h.cookie(name, "", time.Time{})
+	cookie.MaxAge = -1
+	http.SetCookie(w, cookie)
+}
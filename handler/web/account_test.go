@@ -0,0 +1,258 @@
+package web
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var csrfInput = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// browser keeps cookies like a browser does and doesn't follow redirects
+type browser struct {
+	t      *testing.T
+	client *http.Client
+}
+
+func newBrowser(t *testing.T) *browser {
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &browser{t: t, client: &http.Client{
+		Jar:           jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}}
+}
+
+func (b *browser) do(req *http.Request) (*http.Response, string) {
+	b.t.Helper()
+	resp, err := b.client.Do(req)
+	require.NoError(b.t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(b.t, err)
+	return resp, string(body)
+}
+
+func (b *browser) get(url string) (*http.Response, string) {
+	b.t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(b.t, err)
+	return b.do(req)
+}
+
+func (b *browser) post(url string, form url.Values) (*http.Response, string) {
+	b.t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(form.Encode()))
+	require.NoError(b.t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return b.do(req)
+}
+
+// csrfToken returns the CSRF token of the forms on the page
+func (b *browser) csrfToken(url string) string {
+	b.t.Helper()
+	_, body := b.get(url)
+	match := csrfInput.FindStringSubmatch(body)
+	require.NotNil(b.t, match, "page has no CSRF token")
+	return match[1]
+}
+
+func (b *browser) signUp(baseURL, email string) *http.Response {
+	b.t.Helper()
+	resp, _ := b.post(baseURL+"/blog/signup", url.Values{
+		"csrf_token": {b.csrfToken(baseURL + "/blog/signup")},
+		"name":       {"Jane Doe"},
+		"email":      {email},
+		"password":   {"correct horse battery staple"},
+	})
+	return resp
+}
+
+func sessionCookie(resp *http.Response) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == SessionCookie {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestSignUpAndLogin(t *testing.T) {
+	server := setupTestServer(t, testPosts(1)...)
+	defer server.Close()
+
+	jane := newBrowser(t)
+	resp := jane.signUp(server.URL, "jane@example.com")
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	assert.Equal(t, "/blog", resp.Header.Get("Location"))
+	cookie := sessionCookie(resp)
+	require.NotNil(t, cookie, "signing up logs the user in")
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.Equal(t, "/blog", cookie.Path)
+	assert.False(t, cookie.Expires.IsZero())
+
+	resp, body := jane.get(server.URL + "/blog")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "<span>Jane Doe</span>")
+	assert.Contains(t, body, `action="/blog/logout"`)
+	assert.Equal(t, "private, no-cache", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, "Cookie", resp.Header.Get("Vary"))
+
+	t.Run("Anonymous pages stay public", func(t *testing.T) {
+		resp, body := get(t, server.URL+"/blog")
+		assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"))
+		assert.Contains(t, body, `href="/blog/login"`)
+		assert.NotContains(t, body, "Jane Doe")
+	})
+
+	t.Run("Email is taken", func(t *testing.T) {
+		resp := newBrowser(t).signUp(server.URL, "JANE@example.com")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		john := newBrowser(t)
+		resp, body := john.post(server.URL+"/blog/login", url.Values{
+			"csrf_token": {john.csrfToken(server.URL + "/blog/login")},
+			"email":      {"jane@example.com"},
+			"password":   {"wrong password"},
+		})
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(t, body, "Invalid email or password")
+		assert.Contains(t, body, `value="jane@example.com"`)
+		assert.Nil(t, sessionCookie(resp))
+
+		resp, _ = john.post(server.URL+"/blog/login", url.Values{
+			"csrf_token": {john.csrfToken(server.URL + "/blog/login")},
+			"email":      {"jane@example.com"},
+			"password":   {"correct horse battery staple"},
+		})
+		assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+		assert.NotNil(t, sessionCookie(resp))
+	})
+}
+
+func TestSignUp_Invalid(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.Close()
+
+	jane := newBrowser(t)
+	for _, form := range []url.Values{
+		{"name": {"Jane"}, "email": {"not an email"}, "password": {"correct horse battery staple"}},
+		{"name": {""}, "email": {"jane@example.com"}, "password": {"correct horse battery staple"}},
+		{"name": {"Jane"}, "email": {"jane@example.com"}, "password": {"short"}},
+	} {
+		form.Set("csrf_token", jane.csrfToken(server.URL+"/blog/signup"))
+		resp, body := jane.post(server.URL+"/blog/signup", form)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Contains(t, body, `class="form-error"`)
+		assert.Nil(t, sessionCookie(resp))
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.Close()
+
+	jane := newBrowser(t)
+	require.Equal(t, http.StatusSeeOther, jane.signUp(server.URL, "jane@example.com").StatusCode)
+
+	t.Run("Login without the cookie", func(t *testing.T) {
+		forged := newBrowser(t)
+		resp, _ := forged.post(server.URL+"/blog/login", url.Values{
+			"csrf_token": {"guessed"},
+			"email":      {"jane@example.com"},
+			"password":   {"correct horse battery staple"},
+		})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Nil(t, sessionCookie(resp))
+	})
+
+	t.Run("Logout needs the token of the session", func(t *testing.T) {
+		resp, _ := jane.post(server.URL+"/blog/logout", url.Values{"csrf_token": {"guessed"}})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		_, body := jane.get(server.URL + "/blog")
+		assert.Contains(t, body, "Jane Doe", "still logged in")
+	})
+}
+
+func TestLogout(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.Close()
+
+	jane := newBrowser(t)
+	require.Equal(t, http.StatusSeeOther, jane.signUp(server.URL, "jane@example.com").StatusCode)
+
+	resp, _ := jane.post(server.URL+"/blog/logout", url.Values{"csrf_token": {jane.csrfToken(server.URL + "/blog")}})
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	cookie := sessionCookie(resp)
+	require.NotNil(t, cookie)
+	assert.Equal(t, -1, cookie.MaxAge, "the cookie is cleared")
+
+	_, body := jane.get(server.URL + "/blog")
+	assert.NotContains(t, body, "Jane Doe")
+}
+
+func TestLogout_Everywhere(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.Close()
+
+	laptop := newBrowser(t)
+	require.Equal(t, http.StatusSeeOther, laptop.signUp(server.URL, "jane@example.com").StatusCode)
+	phone := newBrowser(t)
+	resp, _ := phone.post(server.URL+"/blog/login", url.Values{
+		"csrf_token": {phone.csrfToken(server.URL + "/blog/login")},
+		"email":      {"jane@example.com"},
+		"password":   {"correct horse battery staple"},
+	})
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	stolen := sessionCookie(resp)
+
+	resp, _ = laptop.post(server.URL+"/blog/logout", url.Values{
+		"csrf_token": {laptop.csrfToken(server.URL + "/blog")},
+		"everywhere": {"1"},
+	})
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	resp, body := phone.get(server.URL + "/blog")
+	assert.NotContains(t, body, "Jane Doe", "other sessions are revoked")
+	assert.Equal(t, -1, sessionCookie(resp).MaxAge, "the revoked cookie is cleared")
+
+	_, body = get(t, server.URL+"/blog", "Cookie", stolen.String())
+	assert.NotContains(t, body, "Jane Doe")
+}
+
+func TestLogin_Throttled(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.Close()
+
+	jane := newBrowser(t)
+	require.Equal(t, http.StatusSeeOther, jane.signUp(server.URL, "jane@example.com").StatusCode)
+
+	attacker := newBrowser(t)
+	login := func(password string) *http.Response {
+		resp, _ := attacker.post(server.URL+"/blog/login", url.Values{
+			"csrf_token": {attacker.csrfToken(server.URL + "/blog/login")},
+			"email":      {"jane@example.com"},
+			"password":   {password},
+		})
+		return resp
+	}
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusUnauthorized, login("wrong password").StatusCode)
+	}
+
+	resp := login("correct horse battery staple")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	assert.Nil(t, sessionCookie(resp))
+}
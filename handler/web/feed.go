@@ -0,0 +1,275 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"time"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/service"
+)
+
+const (
+	defaultFeedItemLimit = 20
+	feedCacheControl     = "public, max-age=300"
+	contentTypeRSS       = "application/rss+xml; charset=utf-8"
+	contentTypeAtom      = "application/atom+xml; charset=utf-8"
+
+	namespaceAtom = "http://www.w3.org/2005/Atom"
+	namespaceDC   = "http://purl.org/dc/elements/1.1/"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      rssSelf   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	Creator     string  `xml:"dc:creator"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Links     []atomLink  `xml:"link"`
+	Author    atomPerson  `xml:"author"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// RSS serves the RSS 2.0 feed of the whole blog
+func (h *Handler) RSS(w http.ResponseWriter, r *http.Request) {
+	posts, ok := h.feedPosts(w, r, "")
+	if !ok {
+		return
+	}
+
+	updated := h.lastModified(posts)
+	feed := rssFeed{
+		Version: "2.0",
+		AtomNS:  namespaceAtom,
+		DCNS:    namespaceDC,
+		Channel: rssChannel{
+			Title:         h.site.Title,
+			Link:          h.absoluteURL(BasePath),
+			Description:   h.site.Description,
+			SelfLink:      rssSelf{Href: h.absoluteURL(r.URL.Path), Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: updated.Format(time.RFC1123Z),
+		},
+	}
+	if feed.Channel.Description == "" {
+		feed.Channel.Description = h.site.Title
+	}
+
+	for _, post := range posts {
+		_, content := h.feedContent(r.Context(), &post)
+		link := h.postURL(post)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			Creator:     post.Author,
+			PubDate:     publishedAt(post).Format(time.RFC1123Z),
+			Description: content,
+		})
+	}
+
+	h.writeFeed(w, r, contentTypeRSS, updated, feed)
+}
+
+// Atom serves the Atom feed of the whole blog
+func (h *Handler) Atom(w http.ResponseWriter, r *http.Request) {
+	posts, ok := h.feedPosts(w, r, "")
+	if !ok {
+		return
+	}
+
+	h.writeAtom(w, r, h.site.Title, h.site.Description, BasePath, posts)
+}
+
+// AuthorAtom serves the Atom feed of a single author
+func (h *Handler) AuthorAtom(w http.ResponseWriter, r *http.Request) {
+	author, err := authorParam(r)
+	if err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid author")
+		return
+	}
+
+	posts, ok := h.feedPosts(w, r, author)
+	if !ok {
+		return
+	}
+	if len(posts) == 0 {
+		h.renderError(w, r, http.StatusNotFound, "Author not found")
+		return
+	}
+
+	h.writeAtom(w, r, author+" - "+h.site.Title, "Posts by "+author, authorURL(BasePath, author), posts)
+}
+
+func (h *Handler) writeAtom(w http.ResponseWriter, r *http.Request, title, subtitle, alternatePath string, posts []models.Post) {
+	updated := h.lastModified(posts)
+	self := h.absoluteURL(r.URL.Path)
+	feed := atomFeed{
+		ID:       self,
+		Title:    title,
+		Subtitle: subtitle,
+		Updated:  updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: h.absoluteURL(alternatePath), Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, post := range posts {
+		contentType, content := h.feedContent(r.Context(), &post)
+		link := h.postURL(post)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        link,
+			Title:     post.Title,
+			Updated:   time.Time(post.UpdatedAt).Format(time.RFC3339),
+			Published: publishedAt(post).Format(time.RFC3339),
+			Links:     []atomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+			Author:    atomPerson{Name: post.Author, URI: h.absoluteURL(authorURL(BasePath, post.Author))},
+			Content:   atomContent{Type: contentType, Body: content},
+		})
+	}
+
+	h.writeFeed(w, r, contentTypeAtom, updated, feed)
+}
+
+// feedPosts returns the newest posts, limited by the configured number of feed items.
+// Empty author means posts of every author.
+func (h *Handler) feedPosts(w http.ResponseWriter, r *http.Request, author string) ([]models.Post, bool) {
+	var (
+		posts []models.Post
+		err   error
+	)
+	if author == "" {
+		posts, err = h.app.GetPosts(r.Context())
+	} else {
+		posts, err = h.app.GetPostsByAuthor(r.Context(), author)
+	}
+	if err != nil {
+		h.log(r.Context()).Error("failed to get feed posts", "error", err, "author", author)
+		h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return nil, false
+	}
+	posts = published(posts)
+
+	sortNewest(posts)
+	if len(posts) > h.site.FeedItemLimit {
+		posts = posts[:h.site.FeedItemLimit]
+	}
+
+	return posts, true
+}
+
+// feedContent returns the post content as HTML when rendering is enabled and as plain text otherwise.
+// Escaping is left to the XML encoder.
+func (h *Handler) feedContent(ctx context.Context, post *models.Post) (string, string) {
+	err := h.app.RenderContent(ctx, post)
+	if err == nil {
+		return "html", post.ContentHTML
+	}
+	if !errors.Is(err, service.ErrRenderingDisabled) {
+		h.log(ctx).Error("failed to render feed item", "error", err, "post_id", post.ID)
+	}
+	return "text", post.Content
+}
+
+// lastModified is the latest update among the feed posts
+func (h *Handler) lastModified(posts []models.Post) time.Time {
+	updated := h.started
+	if len(posts) > 0 {
+		updated = time.Time{}
+	}
+	for _, post := range posts {
+		if t := time.Time(post.UpdatedAt); t.After(updated) {
+			updated = t
+		}
+	}
+	return updated.UTC()
+}
+
+// writeFeed supports conditional requests with both ETag and Last-Modified validators
+func (h *Handler) writeFeed(w http.ResponseWriter, r *http.Request, contentType string, updated time.Time, feed interface{}) {
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	if err := xml.NewEncoder(&body).Encode(feed); err != nil {
+		h.log(r.Context()).Error("failed to encode feed", "error", err)
+		h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", feedCacheControl)
+	w.Header().Set("ETag", etag(body.Bytes()))
+	http.ServeContent(w, r, "", updated, bytes.NewReader(body.Bytes()))
+}
+
+func (h *Handler) absoluteURL(path string) string {
+	return h.site.BaseURL + path
+}
+
+func (h *Handler) postURL(post models.Post) string {
+	return PostURL(h.site.BaseURL, post)
+}
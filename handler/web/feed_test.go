@@ -0,0 +1,249 @@
+package web
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/handler/models"
+)
+
+// Minimal views of the feed formats, covering elements required by RSS 2.0 and RFC 4287
+
+type testRSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel struct {
+		Title string `xml:"title"`
+		// link and atom:link share the local name
+		Links []struct {
+			XMLName xml.Name
+			Href    string `xml:"href,attr"`
+			Rel     string `xml:"rel,attr"`
+			Value   string `xml:",chardata"`
+		} `xml:"link"`
+		Description   string `xml:"description"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		Items         []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			GUID        string `xml:"guid"`
+			PubDate     string `xml:"pubDate"`
+			Description string `xml:"description"`
+			Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type testAtom struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Links   []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		ID        string `xml:"id"`
+		Title     string `xml:"title"`
+		Updated   string `xml:"updated"`
+		Published string `xml:"published"`
+		Author    struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Content struct {
+			Type string `xml:"type,attr"`
+			Body string `xml:",chardata"`
+		} `xml:"content"`
+	} `xml:"entry"`
+}
+
+func TestRSS(t *testing.T) {
+	server := setupTestServer(t, append(testPosts(2), models.Post{
+		Title:   "Tom & Jerry <3",
+		Content: "**bold** & <script>alert(1)</script>",
+		Author:  "Author 1",
+	})...)
+	defer server.Close()
+
+	resp, body := get(t, server.URL+"/feed.rss")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/rss+xml; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.NotEmpty(t, resp.Header.Get("ETag"))
+	assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+	assert.Contains(t, body, "Tom &amp; Jerry &lt;3")
+
+	var feed testRSS
+	require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+	assert.Equal(t, "2.0", feed.Version)
+	assert.Equal(t, "Test Blog", feed.Channel.Title)
+	require.Len(t, feed.Channel.Links, 2)
+	for _, link := range feed.Channel.Links {
+		if link.XMLName.Space == namespaceAtom {
+			assert.Equal(t, "self", link.Rel)
+			assert.Equal(t, "http://blog.test/feed.rss", link.Href)
+		} else {
+			assert.Equal(t, "http://blog.test/blog", link.Value)
+		}
+	}
+	assert.NotEmpty(t, feed.Channel.Description)
+	_, err := time.Parse(time.RFC1123Z, feed.Channel.LastBuildDate)
+	require.NoError(t, err)
+
+	require.Len(t, feed.Channel.Items, 3)
+	item := feed.Channel.Items[0]
+	assert.Equal(t, "Tom & Jerry <3", item.Title)
+	assert.Equal(t, "http://blog.test/blog/posts/3", item.Link)
+	assert.Equal(t, item.Link, item.GUID)
+	assert.Equal(t, "Author 1", item.Creator)
+	assert.Contains(t, item.Description, "<strong>bold</strong>")
+	assert.NotContains(t, item.Description, "<script>")
+	_, err = time.Parse(time.RFC1123Z, item.PubDate)
+	require.NoError(t, err)
+}
+
+func TestAtom(t *testing.T) {
+	server := setupTestServer(t, testPosts(3)...)
+	defer server.Close()
+
+	resp, body := get(t, server.URL+"/feed.atom")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	var feed testAtom
+	require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+	assert.Equal(t, "http://blog.test/feed.atom", feed.ID)
+	assert.Equal(t, "Test Blog", feed.Title)
+	_, err := time.Parse(time.RFC3339, feed.Updated)
+	require.NoError(t, err)
+
+	rels := map[string]string{}
+	for _, link := range feed.Links {
+		rels[link.Rel] = link.Href
+	}
+	assert.Equal(t, "http://blog.test/feed.atom", rels["self"])
+	assert.Equal(t, "http://blog.test/blog", rels["alternate"])
+
+	require.Len(t, feed.Entries, 3)
+	for _, entry := range feed.Entries {
+		assert.True(t, strings.HasPrefix(entry.ID, "http://blog.test/blog/posts/"))
+		assert.NotEmpty(t, entry.Title)
+		assert.NotEmpty(t, entry.Author.Name)
+		assert.Equal(t, "html", entry.Content.Type)
+		assert.Contains(t, entry.Content.Body, "<h2")
+		_, err := time.Parse(time.RFC3339, entry.Updated)
+		require.NoError(t, err)
+	}
+}
+
+func TestAuthorAtom(t *testing.T) {
+	server := setupTestServer(t, testPosts(3)...)
+	defer server.Close()
+
+	resp, body := get(t, server.URL+"/authors/Author%201/feed.atom")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var feed testAtom
+	require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+	require.Len(t, feed.Entries, 2)
+	for _, entry := range feed.Entries {
+		assert.Equal(t, "Author 1", entry.Author.Name)
+	}
+
+	resp, _ = get(t, server.URL+"/authors/Nobody/feed.atom")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	t.Run("Names with escaped characters", func(t *testing.T) {
+		posts := testPosts(1)
+		posts[0].Author = "100% Real"
+		server := setupTestServer(t, posts...)
+		defer server.Close()
+
+		resp, body := get(t, server.URL+"/authors/100%25%20Real/feed.atom")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var feed testAtom
+		require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+		require.Len(t, feed.Entries, 1)
+		assert.Equal(t, "100% Real", feed.Entries[0].Author.Name)
+		var alternate string
+		for _, link := range feed.Links {
+			if link.Rel == "alternate" {
+				alternate = link.Href
+			}
+		}
+		assert.Equal(t, "http://blog.test/blog/authors/100%25%20Real", alternate)
+	})
+}
+
+func TestFeed_ItemLimit(t *testing.T) {
+	server := setupTestServer(t, testPosts(25)...)
+	defer server.Close()
+
+	_, body := get(t, server.URL+"/feed.rss")
+
+	var feed testRSS
+	require.NoError(t, xml.Unmarshal([]byte(body), &feed))
+	assert.Len(t, feed.Channel.Items, defaultFeedItemLimit)
+	assert.Equal(t, "Title 25", feed.Channel.Items[0].Title)
+}
+
+func TestFeed_PublishedLater(t *testing.T) {
+	draft := models.Post{Title: "Written first", Content: "Content", Author: "Author 1", Status: models.PostStatusDraft}
+	site := Site{Title: "Test Blog", BaseURL: "http://blog.test/", PageSize: 2, FeedItemLimit: 2}
+	application, server := setupTestSite(t, site, append([]models.Post{draft}, testPosts(2)...)...)
+	defer server.Close()
+
+	time.Sleep(time.Second) // feeds tell the time to the second
+	post, err := application.GetPostByID(adminCtx(), 1)
+	require.NoError(t, err)
+	post.Status = models.PostStatusPublished
+	require.NoError(t, application.UpdatePost(adminCtx(), post))
+	post, err = application.GetPostByID(adminCtx(), 1)
+	require.NoError(t, err)
+	require.NotNil(t, post.PublishAt)
+	publishedAt := time.Time(*post.PublishAt)
+	require.NotEqual(t, time.Time(post.CreatedAt).Unix(), publishedAt.Unix())
+
+	_, body := get(t, server.URL+"/feed.rss")
+	var rss testRSS
+	require.NoError(t, xml.Unmarshal([]byte(body), &rss))
+	require.Len(t, rss.Channel.Items, 2)
+	assert.Equal(t, "Written first", rss.Channel.Items[0].Title, "the newest post is the one published last")
+	assert.Equal(t, publishedAt.Format(time.RFC1123Z), rss.Channel.Items[0].PubDate)
+
+	_, body = get(t, server.URL+"/feed.atom")
+	var atom testAtom
+	require.NoError(t, xml.Unmarshal([]byte(body), &atom))
+	require.Len(t, atom.Entries, 2)
+	assert.Equal(t, "Written first", atom.Entries[0].Title)
+	assert.Equal(t, publishedAt.Format(time.RFC3339), atom.Entries[0].Published)
+
+	_, body = get(t, server.URL+"/blog")
+	assert.Contains(t, body, "Written first")
+	assert.Contains(t, body, "Title 2")
+	assert.NotContains(t, body, "Title 1", "the first page holds the posts published last")
+}
+
+func TestFeed_ConditionalGet(t *testing.T) {
+	server := setupTestServer(t, testPosts(2)...)
+	defer server.Close()
+
+	resp, _ := get(t, server.URL+"/feed.atom")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp2, _ := get(t, server.URL+"/feed.atom", "If-None-Match", resp.Header.Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, resp2.StatusCode)
+
+	resp3, _ := get(t, server.URL+"/feed.atom", "If-Modified-Since", resp.Header.Get("Last-Modified"))
+	assert.Equal(t, http.StatusNotModified, resp3.StatusCode)
+
+	resp4, _ := get(t, server.URL+"/feed.atom", "If-None-Match", `"stale"`)
+	assert.Equal(t, http.StatusOK, resp4.StatusCode)
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"rakia_blog_tt/service"
+)
+
+// PreviewPath is where preview links of posts are served
+const PreviewPath = "/preview"
+
+// Preview shows the post of a preview link whatever its status. Whoever has the link may read the post, so the page
+// is neither cached, indexed nor does it leak the link through the Referer header.
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+
+	post, err := h.app.PreviewPost(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPreviewExpired):
+			h.renderError(w, r, http.StatusGone, "Preview link expired")
+		case errors.Is(err, service.ErrInvalidPreview), errors.Is(err, service.ErrPreviewsDisabled):
+			h.renderError(w, r, http.StatusNotFound, "Preview not found")
+		default:
+			h.log(r.Context()).Error("failed to get preview", "error", err)
+			h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+		}
+		return
+	}
+
+	if err := h.app.RenderContent(r.Context(), &post); err != nil && !errors.Is(err, service.ErrRenderingDisabled) {
+		h.log(r.Context()).Error("failed to render post", "error", err, "post_id", post.ID)
+		h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := h.data(r, "Preview: "+post.Title+" - "+h.site.Title, excerpt(post.Content), "article")
+	data.Post = post
+	data.Preview = true
+	h.renderStatus(w, r, http.StatusOK, "post", data)
+}
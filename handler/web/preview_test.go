@@ -0,0 +1,71 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/service"
+	"rakia_blog_tt/storage"
+)
+
+func TestPreview(t *testing.T) {
+	logger := loggerMock()
+	application := service.New(storage.NewInMemoryPostRepository(logger), logger,
+		service.WithPreviewSecret([]byte("preview-secret")))
+	ctx := auth.NewContext(context.Background(), auth.Principal{Subject: "admin", Roles: []string{auth.RoleAdmin}})
+	draft, err := application.CreatePost(ctx, models.Post{Title: "Secret draft", Content: "Not yet", Author: "Jane Doe"})
+	require.NoError(t, err)
+	preview, err := application.CreatePreview(ctx, int(draft.ID), nil)
+	require.NoError(t, err)
+
+	pages, err := New(application, logger, Site{Title: "Test Blog", BaseURL: "http://blog.test/"}, nil)
+	require.NoError(t, err)
+	r := chi.NewRouter()
+	r.Mount(BasePath, pages.Routes())
+	r.Get(PreviewPath+"/{token}", pages.Preview)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, body := get(t, server.URL+"/blog/posts/1")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, "drafts are not published")
+
+	resp, body = get(t, server.URL+PreviewPath+"/"+preview.Token)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "<h1>Secret draft</h1>")
+	assert.Contains(t, body, "Preview of a draft post")
+	assert.Contains(t, body, `<meta name="robots" content="noindex, nofollow">`)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, "no-referrer", resp.Header.Get("Referrer-Policy"))
+	assert.Equal(t, "noindex, nofollow", resp.Header.Get("X-Robots-Tag"))
+
+	t.Run("Invalid link", func(t *testing.T) {
+		resp, _ := get(t, server.URL+PreviewPath+"/"+preview.Token+"x")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Expired link", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Second)
+		expiring, err := application.CreatePreview(ctx, int(draft.ID), &expiresAt)
+		require.NoError(t, err)
+		time.Sleep(time.Until(expiresAt.Truncate(time.Second)))
+
+		resp, body := get(t, server.URL+PreviewPath+"/"+expiring.Token)
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+		assert.Contains(t, body, "Preview link expired")
+	})
+
+	t.Run("Revoked link", func(t *testing.T) {
+		require.NoError(t, application.RevokePreviews(ctx, int(draft.ID)))
+		resp, _ := get(t, server.URL+PreviewPath+"/"+preview.Token)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	sitemapCacheControl = "public, max-age=3600"
+	contentTypeSitemap  = "application/xml; charset=utf-8"
+	contentTypeGzip     = "application/gzip"
+)
+
+// SitemapSource provides prebuilt sitemap files, see sitemap.Generator
+type SitemapSource interface {
+	File(name string) (content []byte, modified time.Time, ok bool, err error)
+}
+
+// Sitemap serves /sitemap.xml, which is either a sitemap or a sitemap index
+func (h *Handler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	h.serveSitemap(w, r, "sitemap.xml", contentTypeSitemap)
+}
+
+// ChildSitemap serves gzip-compressed sitemaps referenced from the sitemap index
+func (h *Handler) ChildSitemap(w http.ResponseWriter, r *http.Request) {
+	h.serveSitemap(w, r, chi.URLParam(r, "file"), contentTypeGzip)
+}
+
+func (h *Handler) serveSitemap(w http.ResponseWriter, r *http.Request, name, contentType string) {
+	content, modified, ok, err := h.sitemaps.File(name)
+	if err != nil {
+		h.log(r.Context()).Error("failed to build sitemap", "error", err, "file", name)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", sitemapCacheControl)
+	http.ServeContent(w, r, name, modified, bytes.NewReader(content))
+}
@@ -0,0 +1,43 @@
+package web
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSitemap(t *testing.T) {
+	t.Run("Single sitemap", func(t *testing.T) {
+		server := setupTestServer(t, testPosts(2)...)
+		defer server.Close()
+
+		resp, body := get(t, server.URL+"/sitemap.xml")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/xml; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+		assert.Contains(t, body, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		assert.Contains(t, body, `<loc>http://blog.test/blog/posts/2</loc>`)
+
+		resp, _ = get(t, server.URL+"/sitemap.xml", "If-Modified-Since", resp.Header.Get("Last-Modified"))
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	})
+
+	t.Run("Sitemap index", func(t *testing.T) {
+		server := setupTestServer(t, testPosts(3)...)
+		defer server.Close()
+
+		resp, body := get(t, server.URL+"/sitemap.xml")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		assert.Contains(t, body, `<loc>http://blog.test/sitemaps/sitemap-2.xml.gz</loc>`)
+
+		resp, _ = get(t, server.URL+"/sitemaps/sitemap-2.xml.gz")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/gzip", resp.Header.Get("Content-Type"))
+
+		resp, _ = get(t, server.URL+"/sitemaps/sitemap-9.xml.gz")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -0,0 +1,466 @@
+// Package web serves the public, server-rendered part of the blog.
+// It shares service.Application with the JSON API.
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/logging"
+	"rakia_blog_tt/service"
+)
+
+// BasePath is the path the pages are mounted at
+const BasePath = "/blog"
+
+const (
+	defaultPageSize     = 10
+	excerptLength       = 200
+	pageCacheControl    = "public, max-age=60"
+	staticCacheControl  = "public, max-age=86400"
+	noStoreCacheControl = "no-store"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+//go:embed static
+var staticFS embed.FS
+
+// Site describes the blog itself
+type Site struct {
+	Title       string
+	Description string
+	// BaseURL is the absolute URL the service is reachable at, used for canonical and Open Graph links
+	BaseURL       string
+	PageSize      int
+	FeedItemLimit int
+	// SecureCookies sends the session cookie over HTTPS only, turn it off only for local development
+	SecureCookies bool
+}
+
+type Handler struct {
+	app      *service.Application
+	logger   *slog.Logger
+	site     Site
+	pages    map[string]*template.Template
+	static   map[string]staticFile
+	sitemaps SitemapSource
+	// started is reported as the last modification time of empty feeds
+	started time.Time
+}
+
+// log returns the logger of the request, lines carry its ID
+func (h *Handler) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, h.logger)
+}
+
+type staticFile struct {
+	content []byte
+	etag    string
+}
+
+func New(app *service.Application, logger *slog.Logger, site Site, sitemaps SitemapSource) (*Handler, error) {
+	if site.PageSize <= 0 {
+		site.PageSize = defaultPageSize
+	}
+	if site.FeedItemLimit <= 0 {
+		site.FeedItemLimit = defaultFeedItemLimit
+	}
+	site.BaseURL = strings.TrimSuffix(site.BaseURL, "/")
+
+	pages, err := parseTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	static, err := loadStatic()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{
+		app:      app,
+		logger:   logger,
+		site:     site,
+		pages:    pages,
+		static:   static,
+		sitemaps: sitemaps,
+		started:  time.Now().Truncate(time.Second),
+	}, nil
+}
+
+// Routes returns the router for pages, it is expected to be mounted at BasePath
+func (h *Handler) Routes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(h.Sessions)
+
+	r.Get("/", h.Index)                  // GET /blog
+	r.Get("/posts/{id}", h.Post)         // GET /blog/posts/{id}
+	r.Get("/authors/{author}", h.Author) // GET /blog/authors/{author}
+	r.Get("/static/{file}", h.Static)    // GET /blog/static/{file}
+	r.Get("/signup", h.SignUpForm)       // GET /blog/signup
+	r.Post("/signup", h.SignUp)          // POST /blog/signup
+	r.Get("/login", h.LoginForm)         // GET /blog/login
+	r.Post("/login", h.Login)            // POST /blog/login
+	r.Post("/logout", h.Logout)          // POST /blog/logout
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		h.renderError(w, r, http.StatusNotFound, "Page not found")
+	})
+
+	return r
+}
+
+func parseTemplates() (map[string]*template.Template, error) {
+	funcs := template.FuncMap{
+		"authorURL":  authorURL,
+		"excerpt":    excerpt,
+		"paragraphs": paragraphs,
+		// trusted marks content sanitized by the renderer as safe HTML
+		"trusted": func(s string) template.HTML { return template.HTML(s) }, // nolint:gosec
+	}
+
+	pages := make(map[string]*template.Template)
+	for _, name := range []string{"list", "post", "error", "login", "signup"} {
+		tmpl, err := template.New(name).Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, err
+		}
+		pages[name] = tmpl
+	}
+	return pages, nil
+}
+
+func loadStatic() (map[string]staticFile, error) {
+	files := make(map[string]staticFile)
+	err := fs.WalkDir(staticFS, "static", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := staticFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		files[path.Base(name)] = staticFile{content: content, etag: etag(content)}
+		return nil
+	})
+	return files, err
+}
+
+type siteView struct {
+	Title       string
+	Description string
+	BasePath    string
+}
+
+type meta struct {
+	Title       string
+	Description string
+	Type        string
+	URL         string
+}
+
+type pagination struct {
+	Page  int
+	Pages int
+	Prev  string
+	Next  string
+}
+
+type pageData struct {
+	Site       siteView
+	Meta       meta
+	Heading    string
+	Posts      []models.Post
+	Post       models.Post
+	Pagination pagination
+	// User is the logged-in user, nil for anonymous readers
+	User *models.User
+	// CSRFToken goes into every posted form
+	CSRFToken string
+	Form      accountForm
+	// Preview marks a post shown through a preview link, which may not be published
+	Preview bool
+}
+
+func (h *Handler) data(r *http.Request, title, description, ogType string) pageData {
+	data := pageData{
+		Site: siteView{
+			Title:       h.site.Title,
+			Description: h.site.Description,
+			BasePath:    BasePath,
+		},
+		Meta: meta{
+			Title:       title,
+			Description: description,
+			Type:        ogType,
+			URL:         h.site.BaseURL + r.URL.RequestURI(),
+		},
+	}
+	if session, ok := sessionFrom(r.Context()); ok {
+		data.User = &session.User
+		data.CSRFToken = session.CSRFToken
+	}
+	return data
+}
+
+// Index lists all posts, newest first
+func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
+	posts, err := h.app.GetPosts(r.Context())
+	if err != nil {
+		h.log(r.Context()).Error("failed to get posts", "error", err)
+		h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	posts = published(posts)
+
+	data := h.data(r, h.site.Title, h.site.Description, "website")
+	h.renderList(w, r, data, posts, BasePath)
+}
+
+// Author lists posts of a single author, newest first
+func (h *Handler) Author(w http.ResponseWriter, r *http.Request) {
+	author, err := authorParam(r)
+	if err != nil {
+		h.renderError(w, r, http.StatusBadRequest, "Invalid author")
+		return
+	}
+
+	posts, err := h.app.GetPostsByAuthor(r.Context(), author)
+	if err != nil {
+		h.log(r.Context()).Error("failed to get posts by author", "error", err, "author", author)
+		h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	posts = published(posts)
+	if len(posts) == 0 {
+		h.renderError(w, r, http.StatusNotFound, "Author not found")
+		return
+	}
+
+	data := h.data(r, author+" - "+h.site.Title, "Posts by "+author, "profile")
+	data.Heading = "Posts by " + author
+	h.renderList(w, r, data, posts, authorURL(BasePath, author))
+}
+
+// published keeps the published posts. Pages and feeds are public and shared by caches, so they never show drafts
+// even to those allowed to read them through the API.
+func published(posts []models.Post) []models.Post {
+	var result []models.Post
+	for _, post := range posts {
+		if post.Status == models.PostStatusPublished {
+			result = append(result, post)
+		}
+	}
+	return result
+}
+
+// publishedAt returns when the post went public, the creation time of posts from before the workflow
+func publishedAt(post models.Post) time.Time {
+	if post.PublishAt != nil {
+		return time.Time(*post.PublishAt)
+	}
+	return time.Time(post.CreatedAt)
+}
+
+// sortNewest orders posts by the time they went public, newest first
+func sortNewest(posts []models.Post) {
+	sort.Slice(posts, func(i, j int) bool {
+		pi, pj := publishedAt(posts[i]), publishedAt(posts[j])
+		if !pi.Equal(pj) {
+			return pi.After(pj)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+}
+
+func (h *Handler) renderList(w http.ResponseWriter, r *http.Request, data pageData, posts []models.Post, listURL string) {
+	page := 1
+	if value := r.URL.Query().Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			h.renderError(w, r, http.StatusBadRequest, "Invalid page")
+			return
+		}
+		page = parsed
+	}
+
+	sortNewest(posts)
+
+	pages := max(1, int(math.Ceil(float64(len(posts))/float64(h.site.PageSize))))
+	if page > pages {
+		h.renderError(w, r, http.StatusNotFound, "Page not found")
+		return
+	}
+
+	from := (page - 1) * h.site.PageSize
+	to := min(from+h.site.PageSize, len(posts))
+	data.Posts = posts[from:to]
+	data.Pagination = pagination{Page: page, Pages: pages}
+	if page > 1 {
+		data.Pagination.Prev = listURL + "?page=" + strconv.Itoa(page-1)
+	}
+	if page < pages {
+		data.Pagination.Next = listURL + "?page=" + strconv.Itoa(page+1)
+	}
+
+	h.render(w, r, "list", data)
+}
+
+// Post shows a single post with its content rendered to HTML when rendering is enabled
+func (h *Handler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.renderError(w, r, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	post, err := h.app.GetPostByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			h.renderError(w, r, http.StatusNotFound, "Post not found")
+		} else {
+			h.log(r.Context()).Error("failed to get post", "error", err)
+			h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+		}
+		return
+	}
+	if post.Status != models.PostStatusPublished {
+		h.renderError(w, r, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	if err := h.app.RenderContent(r.Context(), &post); err != nil && !errors.Is(err, service.ErrRenderingDisabled) {
+		h.log(r.Context()).Error("failed to render post", "error", err, "post_id", post.ID)
+		h.renderError(w, r, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	data := h.data(r, post.Title+" - "+h.site.Title, excerpt(post.Content), "article")
+	data.Post = post
+	h.render(w, r, "post", data)
+}
+
+// Static serves embedded assets
+func (h *Handler) Static(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "file")
+	file, ok := h.static[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", staticCacheControl)
+	w.Header().Set("ETag", file.etag)
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(file.content))
+}
+
+// render executes the page template into a buffer first, so a template failure never produces a partial page.
+// ETag is derived from the page itself, letting clients revalidate cheaply with If-None-Match.
+func (h *Handler) render(w http.ResponseWriter, r *http.Request, page string, data pageData) {
+	var body bytes.Buffer
+	if err := h.pages[page].ExecuteTemplate(&body, "layout", data); err != nil {
+		h.log(r.Context()).Error("failed to render page", "error", err, "page", page)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	// Pages differ for logged-in users, who must not get them from shared caches
+	w.Header().Add("Vary", "Cookie")
+	if data.User != nil {
+		w.Header().Set("Cache-Control", privateCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", pageCacheControl)
+	}
+	w.Header().Set("ETag", etag(body.Bytes()))
+	http.ServeContent(w, r, page+".html", time.Time{}, bytes.NewReader(body.Bytes()))
+}
+
+func (h *Handler) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	data := h.data(r, message+" - "+h.site.Title, message, "website")
+	data.Heading = message
+	h.renderStatus(w, r, status, "error", data)
+}
+
+// renderStatus renders a page which is never cached, like errors and forms carrying a CSRF token
+func (h *Handler) renderStatus(w http.ResponseWriter, r *http.Request, status int, page string, data pageData) {
+	var body bytes.Buffer
+	if err := h.pages[page].ExecuteTemplate(&body, "layout", data); err != nil {
+		h.log(r.Context()).Error("failed to render page", "error", err, "page", page)
+		http.Error(w, data.Heading, status)
+		return
+	}
+
+	w.Header().Set("Cache-Control", noStoreCacheControl)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body.Bytes()) // nolint:errcheck
+}
+
+func etag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+func authorURL(basePath, author string) string {
+	return basePath + "/authors/" + url.PathEscape(author)
+}
+
+// authorParam returns the author of author routes, the inverse of authorURL. The router matches the unescaped path,
+// but the escaped one when it has escapes like %2F, parameters of those are still escaped.
+func authorParam(r *http.Request) (string, error) {
+	author := chi.URLParam(r, "author")
+	if r.URL.RawPath == "" {
+		return author, nil
+	}
+	return url.PathUnescape(author)
+}
+
+// PostURL returns the absolute URL of the post page
+func PostURL(baseURL string, post models.Post) string {
+	return strings.TrimSuffix(baseURL, "/") + BasePath + "/posts/" + strconv.FormatInt(post.ID, 10)
+}
+
+// excerpt returns the beginning of the content as plain single line text
+func excerpt(content string) string {
+	text := strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(text) <= excerptLength {
+		return text
+	}
+
+	runes := []rune(text)[:excerptLength]
+	if cut := strings.LastIndex(string(runes), " "); cut > 0 {
+		return string(runes)[:cut] + "…"
+	}
+	return string(runes) + "…"
+}
+
+// paragraphs splits plain text content on blank lines
+func paragraphs(content string) []string {
+	var result []string
+	for _, p := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,239 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/render"
+	"rakia_blog_tt/service"
+	"rakia_blog_tt/sitemap"
+	"rakia_blog_tt/storage"
+)
+
+func loggerMock() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func setupTestServer(t *testing.T, posts ...models.Post) *httptest.Server {
+	_, server := setupTestSite(t, Site{Title: "Test Blog", BaseURL: "http://blog.test/", PageSize: 2}, posts...)
+	return server
+}
+
+// setupTestSite serves the pages of the site, the application returned changes posts as an admin with adminCtx
+func setupTestSite(t *testing.T, site Site, posts ...models.Post) (*service.Application, *httptest.Server) {
+	logger := loggerMock()
+	postRepo := storage.NewInMemoryPostRepository(logger)
+	renderer := render.NewMarkdownRenderer(render.Options{})
+	sitemaps := sitemap.New(sitemap.Options{
+		BaseURL:   "http://blog.test/",
+		PostURL:   func(post models.Post) string { return PostURL("http://blog.test/", post) },
+		ChunkSize: 2,
+	})
+	application := service.New(postRepo, logger, service.WithContentRenderer(renderer), service.WithPostListener(sitemaps),
+		service.WithUserRepo(storage.NewInMemoryUserRepository(logger), storage.NewInMemorySessionRepository(logger)),
+		service.WithLoginThrottle(3, time.Hour), service.WithPreviewSecret([]byte("preview-secret")))
+	for _, post := range posts {
+		if post.Status == "" {
+			post.Status = models.PostStatusPublished
+		}
+		_, err := application.CreatePost(adminCtx(), post)
+		require.NoError(t, err)
+	}
+
+	pages, err := New(application, logger, site, sitemaps)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Mount(BasePath, pages.Routes())
+	r.Get("/feed.rss", pages.RSS)
+	r.Get("/feed.atom", pages.Atom)
+	r.Get("/authors/{author}/feed.atom", pages.AuthorAtom)
+	r.Get("/sitemap.xml", pages.Sitemap)
+	r.Get("/sitemaps/{file}", pages.ChildSitemap)
+	r.Get(PreviewPath+"/{token}", pages.Preview)
+	return application, httptest.NewServer(r)
+}
+
+func adminCtx() context.Context {
+	return auth.NewContext(context.Background(), auth.Principal{Subject: "admin", Roles: []string{auth.RoleAdmin}})
+}
+
+func get(t *testing.T, url string, headers ...string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func testPosts(n int) []models.Post {
+	var posts []models.Post
+	for i := 1; i <= n; i++ {
+		posts = append(posts, models.Post{
+			Title:   fmt.Sprintf("Title %d", i),
+			Content: fmt.Sprintf("## Section\n\nContent of post %d", i),
+			Author:  fmt.Sprintf("Author %d", i%2),
+		})
+	}
+	return posts
+}
+
+func TestIndex(t *testing.T) {
+	server := setupTestServer(t, testPosts(3)...)
+	defer server.Close()
+
+	t.Run("First page shows newest posts", func(t *testing.T) {
+		resp, body := get(t, server.URL+"/blog")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "public, max-age=60", resp.Header.Get("Cache-Control"))
+		assert.NotEmpty(t, resp.Header.Get("ETag"))
+
+		assert.Contains(t, body, `<a href="/blog/posts/3">Title 3</a>`)
+		assert.Contains(t, body, `<a href="/blog/posts/2">Title 2</a>`)
+		assert.NotContains(t, body, "Title 1")
+		assert.Contains(t, body, `<a rel="next" href="/blog?page=2">`)
+		assert.Contains(t, body, `<meta property="og:type" content="website">`)
+		assert.Contains(t, body, `<meta property="og:url" content="http://blog.test/blog">`)
+	})
+
+	t.Run("Second page", func(t *testing.T) {
+		resp, body := get(t, server.URL+"/blog?page=2")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "Title 1")
+		assert.Contains(t, body, `<a rel="prev" href="/blog?page=1">`)
+	})
+
+	t.Run("Page out of range", func(t *testing.T) {
+		resp, _ := get(t, server.URL+"/blog?page=3")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	})
+
+	t.Run("Conditional request", func(t *testing.T) {
+		resp, _ := get(t, server.URL+"/blog")
+		resp, _ = get(t, server.URL+"/blog", "If-None-Match", resp.Header.Get("ETag"))
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	})
+}
+
+func TestPost(t *testing.T) {
+	server := setupTestServer(t, models.Post{
+		Title:   `Escaped <b>"Title"</b>`,
+		Content: "## Heading\n\nSome **markdown** <script>alert(1)</script>",
+		Author:  "Jane Doe",
+	})
+	defer server.Close()
+
+	t.Run("Rendered post", func(t *testing.T) {
+		resp, body := get(t, server.URL+"/blog/posts/1")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Contains(t, body, `<h1>Escaped &lt;b&gt;&#34;Title&#34;&lt;/b&gt;</h1>`)
+		assert.Contains(t, body, `<h2 id="heading">Heading</h2>`)
+		assert.Contains(t, body, `<strong>markdown</strong>`)
+		assert.NotContains(t, body, "<script>")
+		assert.Contains(t, body, `<a href="/blog/authors/Jane%20Doe">Jane Doe</a>`)
+		assert.Contains(t, body, `<meta property="og:type" content="article">`)
+		assert.Contains(t, body, `<meta property="og:title" content="Escaped &lt;b&gt;&#34;Title&#34;&lt;/b&gt; - Test Blog">`)
+		assert.Contains(t, body, `<meta property="og:description" content="## Heading Some **markdown** &lt;script&gt;alert(1)&lt;/script&gt;">`)
+	})
+
+	t.Run("Missing post", func(t *testing.T) {
+		resp, body := get(t, server.URL+"/blog/posts/10")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Contains(t, body, "Post not found")
+	})
+}
+
+func TestAuthor(t *testing.T) {
+	server := setupTestServer(t, testPosts(3)...)
+	defer server.Close()
+
+	resp, body := get(t, server.URL+"/blog/authors/Author%201")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "Posts by Author 1")
+	assert.Contains(t, body, "Title 1")
+	assert.Contains(t, body, "Title 3")
+	assert.NotContains(t, body, "Title 2")
+
+	resp, _ = get(t, server.URL+"/blog/authors/Nobody")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	t.Run("Names with escaped characters", func(t *testing.T) {
+		posts := testPosts(2)
+		posts[0].Author = "100% Real"
+		posts[1].Author = "AC/DC"
+		server := setupTestServer(t, posts...)
+		defer server.Close()
+
+		_, body := get(t, server.URL+"/blog")
+		assert.Contains(t, body, `<a href="/blog/authors/100%25%20Real">100% Real</a>`)
+		assert.Contains(t, body, `<a href="/blog/authors/AC%2FDC">AC/DC</a>`)
+
+		resp, body := get(t, server.URL+"/blog/authors/100%25%20Real")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "Posts by 100% Real")
+
+		resp, body = get(t, server.URL+"/blog/authors/AC%2FDC")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "Posts by AC/DC")
+	})
+}
+
+func TestUnpublishedPosts(t *testing.T) {
+	posts := testPosts(3)
+	posts[1].Status = models.PostStatusDraft
+	posts[2].Status = models.PostStatusArchived
+	server := setupTestServer(t, posts...)
+	defer server.Close()
+
+	_, body := get(t, server.URL+"/blog")
+	assert.Contains(t, body, "Title 1")
+	assert.NotContains(t, body, "Title 2")
+	assert.NotContains(t, body, "Title 3")
+
+	resp, _ := get(t, server.URL+"/blog/posts/2")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = get(t, server.URL+"/blog/authors/Author%200")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "authors without published posts have no page")
+
+	_, body = get(t, server.URL+"/feed.atom")
+	assert.NotContains(t, body, "Title 2")
+}
+
+func TestStatic(t *testing.T) {
+	server := setupTestServer(t)
+	defer server.Close()
+
+	resp, body := get(t, server.URL+"/blog/static/style.css")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/css; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "public, max-age=86400", resp.Header.Get("Cache-Control"))
+	assert.Contains(t, body, ".site-header")
+
+	resp, _ = get(t, server.URL+"/blog/static/style.css", "If-None-Match", resp.Header.Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, _ = get(t, server.URL+"/blog/static/missing.js")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/service"
+)
+
+// postStatuses lists the statuses accepted by the ?status= filter of the post list
+var postStatuses = []string{
+	models.PostStatusDraft,
+	models.PostStatusInReview,
+	models.PostStatusScheduled,
+	models.PostStatusPublished,
+	models.PostStatusArchived,
+}
+
+// writeWorkflowError maps status changes refused by the post workflow into problem responses and reports whether
+// err was one of them.
+func writeWorkflowError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var transition *service.TransitionError
+	switch {
+	case errors.As(err, &transition):
+		writeProblem(w, r, http.StatusConflict, "Posts can't change from "+transition.From+" to "+transition.To)
+	case errors.Is(err, service.ErrInvalidTransition):
+		writeProblem(w, r, http.StatusConflict, "Invalid post status change")
+	case errors.Is(err, service.ErrInvalidPublishAt):
+		writeProblem(w, r, http.StatusBadRequest, "Invalid publication time", &models.ProblemFieldError{
+			Name:    "publish_at",
+			In:      "body",
+			Message: "publish_at in body must be in the future to schedule a post",
+		})
+	default:
+		return writeAccessError(w, r, err)
+	}
+	return true
+}
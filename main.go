@@ -2,20 +2,30 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/cors"
 
+	"rakia_blog_tt/auth"
 	"rakia_blog_tt/config"
 	"rakia_blog_tt/handler"
+	"rakia_blog_tt/handler/middleware"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/handler/web"
+	"rakia_blog_tt/moderation"
+	"rakia_blog_tt/render"
 	"rakia_blog_tt/service"
+	"rakia_blog_tt/sitemap"
 	"rakia_blog_tt/storage"
 )
 
@@ -36,18 +46,135 @@ func main() {
 	postRepo := storage.NewInMemoryPostRepository(logger)
 	repoMetric := storage.NewStorageMetricDecorator(postRepo, metrics)
 
-	application := service.New(repoMetric, logger)
+	categoryRepo := storage.NewInMemoryCategoryRepository(logger)
+	commentRepo := storage.NewInMemoryCommentRepository(logger)
+	authorRepo := storage.NewInMemoryAuthorRepository(logger)
+	apiKeyRepo := storage.NewInMemoryAPIKeyRepository(logger)
+	userRepo := storage.NewInMemoryUserRepository(logger)
+	sessionRepo := storage.NewInMemorySessionRepository(logger)
+	appOpts := []service.Option{
+		service.WithCategoryRepo(storage.NewCategoryMetricDecorator(categoryRepo, metrics)),
+		service.WithAuthorRepo(storage.NewAuthorMetricDecorator(authorRepo, metrics)),
+		service.WithCommentRepo(storage.NewCommentMetricDecorator(commentRepo, metrics)),
+		service.WithAPIKeyRepo(storage.NewAPIKeyMetricDecorator(apiKeyRepo, metrics)),
+		service.WithUserRepo(
+			storage.NewUserMetricDecorator(userRepo, metrics),
+			storage.NewSessionMetricDecorator(sessionRepo, metrics),
+		),
+	}
+	if cfg.Session.TTL > 0 {
+		appOpts = append(appOpts, service.WithSessionTTL(cfg.Session.TTL))
+	}
+	if cfg.Session.MaxFailedLogins > 0 && cfg.Session.LoginWindow > 0 {
+		appOpts = append(appOpts, service.WithLoginThrottle(cfg.Session.MaxFailedLogins, cfg.Session.LoginWindow))
+	}
+	if cfg.Preview.Secret != "" {
+		appOpts = append(appOpts, service.WithPreviewSecret([]byte(cfg.Preview.Secret)))
+		if cfg.Preview.TTL > 0 {
+			appOpts = append(appOpts, service.WithPreviewTTL(cfg.Preview.TTL))
+		}
+	}
+	if cfg.Moderation.Enabled {
+		appOpts = append(appOpts, service.WithCommentModerator(newModerator(cfg.Moderation)))
+	}
+	if cfg.Markdown.Enabled {
+		renderer := render.NewMarkdownRenderer(render.Options{
+			TableOfContents: cfg.Markdown.TableOfContents,
+			CacheSize:       cfg.Markdown.CacheSize,
+		})
+		appOpts = append(appOpts, service.WithContentRenderer(renderer))
+	}
+
+	if cfg.Auth.PolicyFile != "" {
+		policy, err := loadPolicy(cfg.Auth.PolicyFile)
+		if err != nil {
+			slog.Error("Policy initialization failed", "error", err)
+			return
+		}
+		appOpts = append(appOpts, service.WithPolicy(policy))
+	}
+
+	sitemaps := sitemap.New(sitemap.Options{
+		BaseURL: cfg.Site.BaseURL,
+		PostURL: func(post models.Post) string { return web.PostURL(cfg.Site.BaseURL, post) },
+	})
+	appOpts = append(appOpts, service.WithPostListener(sitemaps))
+
+	application := service.New(repoMetric, logger, appOpts...)
+
+	// The sitemap is built by the server itself, whatever the policy grants anonymous clients
+	system := auth.NewContext(ctx, auth.Principal{Subject: "system", Roles: []string{auth.RoleAdmin}})
+	posts, err := application.FindPosts(system, service.PostFilter{Status: models.PostStatusPublished})
+	if err != nil {
+		slog.Error("Sitemap initialization failed", "error", err)
+		return
+	}
+	sitemaps.Reset(posts)
 
 	hndl := handler.New(
 		application, logger,
 	)
 
+	pages, err := web.New(application, logger, web.Site{
+		Title:         cfg.Site.Title,
+		Description:   cfg.Site.Description,
+		BaseURL:       cfg.Site.BaseURL,
+		PageSize:      cfg.Site.PageSize,
+		FeedItemLimit: cfg.Site.FeedItemLimit,
+		SecureCookies: cfg.Session.SecureCookie,
+	}, sitemaps)
+	if err != nil {
+		slog.Error("Pages initialization failed", "error", err)
+		return
+	}
+
+	verifier, err := newVerifier(cfg.Auth)
+	if err != nil {
+		slog.Error("Token verifier initialization failed", "error", err)
+		return
+	}
+
+	limiter, err := newRateLimiter(cfg.RateLimit, &hndl, metrics)
+	if err != nil {
+		slog.Error("Rate limiter initialization failed", "error", err)
+		return
+	}
+
+	corsOpts, err := newCORS(cfg.CORS)
+	if err != nil {
+		slog.Error("CORS initialization failed", "error", err)
+		return
+	}
+
+	var compression *middleware.CompressOptions
+	if cfg.Compression.Enabled {
+		compression = &middleware.CompressOptions{MinSize: cfg.Compression.MinSize, ContentTypes: cfg.Compression.ContentTypes}
+	}
+
+	bodyLog, err := newBodyLog(cfg.HttpLog)
+	if err != nil {
+		slog.Error("Body logging initialization failed", "error", err)
+		return
+	}
+
 	server := http.Server{
-		Addr:        fmt.Sprintf(":%s", cfg.App.Port),
-		Handler:     handler.NewRouter(hndl, logger, metrics),
+		Addr: fmt.Sprintf(":%s", cfg.App.Port),
+		Handler: handler.NewRouter(hndl, pages, logger, metrics, handler.NewAuthenticator(verifier, application, logger),
+			handler.RouterOptions{RateLimiter: limiter, MaxBodySize: cfg.Http.MaxBodySize, CORS: corsOpts,
+				Compression: compression, BodyLog: bodyLog}),
 		ReadTimeout: cfg.Http.ReadTimeout,
 	}
 
+	interval := cfg.Scheduler.Interval
+	if interval <= 0 {
+		interval = service.DefaultSchedulerInterval
+	}
+	schedulerDone := make(chan struct{})
+	go func() {
+		application.RunScheduler(ctx, interval)
+		close(schedulerDone)
+	}()
+
 	srvErr := make(chan error)
 
 	go func() {
@@ -73,10 +200,153 @@ func main() {
 		slog.Error("Server Shutdown Failed", "error", err)
 		return
 	}
+	<-schedulerDone
 
 	slog.Info("Server gracefully shutdown")
 }
 
+// loadPolicy reads the permission policy of the roles from a YAML or JSON file
+func loadPolicy(path string) (*service.Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return service.LoadPolicy(data)
+}
+
+// newModerator builds the spam checks turned on in the config
+func newModerator(cfg *config.Moderation) *moderation.Moderator {
+	var checks []moderation.Check
+	if cfg.MaxLinks > 0 {
+		checks = append(checks, moderation.MaxLinks(cfg.MaxLinks))
+	}
+	if len(cfg.BannedWords) > 0 {
+		checks = append(checks, moderation.BannedWords(cfg.BannedWords))
+	}
+	if cfg.DuplicateWindow > 0 {
+		checks = append(checks, moderation.Duplicates(cfg.DuplicateWindow))
+	}
+	if cfg.RateLimit > 0 && cfg.RateWindow > 0 {
+		checks = append(checks, moderation.RateLimit(cfg.RateLimit, cfg.RateWindow))
+	}
+	return moderation.New(checks...)
+}
+
+// newVerifier builds the JWT verifier from the keys given in the config
+func newVerifier(cfg *config.Auth) (*auth.Verifier, error) {
+	opts := []auth.VerifierOption{
+		auth.WithIssuer(cfg.Issuer),
+		auth.WithAudience(cfg.Audience),
+		auth.WithLeeway(cfg.Leeway),
+	}
+	if cfg.HS256Secret != "" {
+		opts = append(opts, auth.WithHS256Secret([]byte(cfg.HS256Secret)))
+	}
+	if cfg.RS256PublicKey != "" {
+		key, err := auth.ParseRSAPublicKey([]byte(cfg.RS256PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, auth.WithRS256Keys(map[string]*rsa.PublicKey{"": key}))
+	}
+	if cfg.JWKSFile != "" {
+		data, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("read JWKS: %w", err)
+		}
+		keys, err := auth.ParseJWKS(data)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, auth.WithRS256Keys(keys))
+	}
+	return auth.NewVerifier(opts...)
+}
+
+// newRateLimiter builds the rate limiter from the config, there is none without limits or trusted proxies
+func newRateLimiter(cfg *config.RateLimit, hndl *handler.Handler, metrics *config.Metrics) (*middleware.RateLimiter, error) {
+	if cfg == nil || (cfg.Default == "" && len(cfg.Routes) == 0 && cfg.FailedAuth == "" && len(cfg.TrustedProxies) == 0) {
+		return nil, nil
+	}
+	opts := middleware.RateLimitOptions{
+		Routes:   make(map[string]middleware.Limit, len(cfg.Routes)),
+		Metrics:  metrics,
+		Rejected: hndl.TooManyRequests,
+	}
+	if cfg.Default != "" {
+		limit, err := middleware.ParseLimit(cfg.Default)
+		if err != nil {
+			return nil, err
+		}
+		opts.Default = limit
+	}
+	if cfg.FailedAuth != "" {
+		limit, err := middleware.ParseLimit(cfg.FailedAuth)
+		if err != nil {
+			return nil, fmt.Errorf("failed authentications: %w", err)
+		}
+		opts.FailedAuth = limit
+	}
+	for route, value := range cfg.Routes {
+		limit, err := middleware.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", route, err)
+		}
+		opts.Routes[route] = limit
+	}
+	proxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	opts.TrustedProxies = proxies
+	return middleware.NewRateLimiter(opts), nil
+}
+
+// newCORS builds the CORS options from the config, CORS is off when no origin is allowed
+func newCORS(cfg *config.CORS) (*cors.Options, error) {
+	if cfg == nil || len(cfg.AllowedOrigins) == 0 {
+		return nil, nil
+	}
+	if cfg.AllowCredentials && slices.Contains(cfg.AllowedOrigins, "*") {
+		return nil, errors.New("credentials can't be allowed to any origin, list the origins instead of *")
+	}
+	return &cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge.Seconds()),
+	}, nil
+}
+
+// newBodyLog builds the body logging options from the config, bodies aren't logged in the off mode
+func newBodyLog(cfg *config.HttpLog) (*middleware.BodyLogOptions, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	switch cfg.Mode {
+	case middleware.BodyLogOff:
+		return nil, nil
+	case middleware.BodyLogErrors, middleware.BodyLogSampled:
+	default:
+		return nil, fmt.Errorf("unknown mode %q, expected off, errors or sampled", cfg.Mode)
+	}
+	if cfg.SamplePercent < 0 || cfg.SamplePercent > 100 {
+		return nil, fmt.Errorf("sample percent %v is not between 0 and 100", cfg.SamplePercent)
+	}
+	if cfg.MaxBodySize <= 0 {
+		return nil, fmt.Errorf("max body size %d is not positive", cfg.MaxBodySize)
+	}
+	return &middleware.BodyLogOptions{
+		Mode:          cfg.Mode,
+		SamplePercent: cfg.SamplePercent,
+		MaxSize:       cfg.MaxBodySize,
+		RedactFields:  cfg.RedactFields,
+		RedactHeaders: cfg.RedactHeaders,
+	}, nil
+}
+
 func runMetricServer(cfg *config.Monitoring) {
 	mh := chi.NewRouter()
 	mh.HandleFunc("/metrics", promhttp.Handler().ServeHTTP)
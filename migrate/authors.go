@@ -0,0 +1,90 @@
+// Package migrate converts data files of the blog between format versions
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/storage"
+)
+
+// dataFile is the layout of blog_data.json. Posts are kept raw, so fields unknown to the migration survive it.
+type dataFile struct {
+	Authors []author                     `json:"authors"`
+	Posts   []map[string]json.RawMessage `json:"posts"`
+}
+
+type author struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Bio       string `json:"bio,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+// AuthorsReport tells what a migration changed
+type AuthorsReport struct {
+	AuthorsCreated int
+	PostsLinked    int
+}
+
+// Authors turns free text post authors of a data file into author records and links posts to them with author_id.
+// Names differing only in case or spacing become one author named as in the first post using it.
+// Authors and links already in the file are kept, so running the migration again changes nothing.
+func Authors(data []byte) ([]byte, AuthorsReport, error) {
+	var (
+		file   dataFile
+		report AuthorsReport
+	)
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, report, errors.Wrap(err, "decode data file")
+	}
+
+	byKey := make(map[string]int64, len(file.Authors))
+	var nextID int64 = 1
+	for _, a := range file.Authors {
+		byKey[storage.AuthorKey(a.Name)] = a.ID
+		if a.ID >= nextID {
+			nextID = a.ID + 1
+		}
+	}
+
+	for i, post := range file.Posts {
+		if _, linked := post["author_id"]; linked {
+			continue
+		}
+
+		var name string
+		if raw, ok := post["author"]; ok {
+			if err := json.Unmarshal(raw, &name); err != nil {
+				return nil, report, errors.Wrapf(err, "decode author of post %d", i)
+			}
+		}
+		key := storage.AuthorKey(name)
+		if key == "" {
+			continue
+		}
+
+		id, ok := byKey[key]
+		if !ok {
+			id = nextID
+			nextID++
+			byKey[key] = id
+			file.Authors = append(file.Authors, author{ID: id, Name: name})
+			report.AuthorsCreated++
+		}
+		post["author_id"] = json.RawMessage(strconv.FormatInt(id, 10))
+		report.PostsLinked++
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return nil, report, errors.Wrap(err, "encode data file")
+	}
+	return buf.Bytes(), report, nil
+}
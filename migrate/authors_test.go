@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthors(t *testing.T) {
+	input := `{
+  "posts": [
+    {"id": 1, "title": "Title 1", "author": "Author 1", "slug": "title-1"},
+    {"id": 2, "title": "Title 2", "author": "author  1"},
+    {"id": 3, "title": "Title 3", "author": "Author 2"},
+    {"id": 4, "title": "Title 4", "author": ""}
+  ]
+}`
+
+	migrated, report, err := Authors([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, AuthorsReport{AuthorsCreated: 2, PostsLinked: 3}, report)
+
+	var file struct {
+		Authors []author `json:"authors"`
+		Posts   []struct {
+			ID       int64  `json:"id"`
+			Author   string `json:"author"`
+			AuthorID int64  `json:"author_id"`
+			Slug     string `json:"slug"`
+		} `json:"posts"`
+	}
+	require.NoError(t, json.Unmarshal(migrated, &file))
+	assert.Equal(t, []author{{ID: 1, Name: "Author 1"}, {ID: 2, Name: "Author 2"}}, file.Authors)
+	require.Len(t, file.Posts, 4)
+	assert.Equal(t, int64(1), file.Posts[0].AuthorID)
+	assert.Equal(t, int64(1), file.Posts[1].AuthorID, "names differing in case and spacing are one author")
+	assert.Equal(t, int64(2), file.Posts[2].AuthorID)
+	assert.Zero(t, file.Posts[3].AuthorID, "posts without author stay unlinked")
+	assert.Equal(t, "title-1", file.Posts[0].Slug, "other fields are kept")
+
+	again, report, err := Authors(migrated)
+	require.NoError(t, err)
+	assert.Equal(t, AuthorsReport{}, report)
+	assert.JSONEq(t, string(migrated), string(again))
+}
+
+func TestAuthors_InvalidFile(t *testing.T) {
+	_, _, err := Authors([]byte(`{"posts": [{"author": 1}]}`))
+	assert.Error(t, err)
+
+	_, _, err = Authors([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package moderation
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// minDuplicateLength is the normalized length from which repeated texts count as duplicates,
+// so short replies like "Thanks!" can be posted by many readers
+const minDuplicateLength = 20
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// MaxLinks flags submissions with more than max links
+func MaxLinks(max int) Check {
+	return CheckFunc(func(sub Submission) string {
+		if count := len(linkPattern.FindAllStringIndex(sub.Content, -1)); count > max {
+			return fmt.Sprintf("contains %d links, at most %d allowed", count, max)
+		}
+		return ""
+	})
+}
+
+// BannedWords flags submissions containing any of the words or phrases. Matching ignores case and punctuation,
+// and only whole words match, so banning "ass" doesn't flag "class".
+func BannedWords(words []string) Check {
+	var phrases []string
+	for _, word := range words {
+		if phrase := normalizeWords(word); phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+	}
+
+	return CheckFunc(func(sub Submission) string {
+		text := " " + normalizeWords(sub.Author+" "+sub.Content) + " "
+		for _, phrase := range phrases {
+			if strings.Contains(text, " "+phrase+" ") {
+				return fmt.Sprintf("contains banned phrase %q", phrase)
+			}
+		}
+		return ""
+	})
+}
+
+// normalizeWords lower cases the text and separates its words with single spaces
+func normalizeWords(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// DuplicateCheck flags texts that were already submitted within the window, whoever sent them
+type DuplicateCheck struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[[sha256.Size]byte]time.Time
+}
+
+func Duplicates(window time.Duration) *DuplicateCheck {
+	return &DuplicateCheck{
+		window: window,
+		seen:   make(map[[sha256.Size]byte]time.Time),
+	}
+}
+
+func (c *DuplicateCheck) Check(sub Submission) string {
+	text := normalizeWords(sub.Content)
+	if len(text) < minDuplicateLength {
+		return ""
+	}
+	key := sha256.Sum256([]byte(text))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, at := range c.seen {
+		if sub.At.Sub(at) > c.window {
+			delete(c.seen, k)
+		}
+	}
+
+	_, duplicate := c.seen[key]
+	c.seen[key] = sub.At
+	if duplicate {
+		return "duplicates a recent comment"
+	}
+	return ""
+}
+
+// RateCheck flags submissions from an IP that sent more than the limit within the window.
+// Submissions without an IP are not limited.
+type RateCheck struct {
+	limit  int
+	window time.Duration
+
+	mu   sync.Mutex
+	sent map[string][]time.Time // IP -> times of its submissions within the window, oldest first
+}
+
+func RateLimit(limit int, window time.Duration) *RateCheck {
+	return &RateCheck{
+		limit:  limit,
+		window: window,
+		sent:   make(map[string][]time.Time),
+	}
+}
+
+func (c *RateCheck) Check(sub Submission) string {
+	if sub.IP == "" {
+		return ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for ip, times := range c.sent {
+		c.sent[ip] = recent(times, sub.At.Add(-c.window))
+		if len(c.sent[ip]) == 0 {
+			delete(c.sent, ip)
+		}
+	}
+
+	c.sent[sub.IP] = append(c.sent[sub.IP], sub.At)
+	if count := len(c.sent[sub.IP]); count > c.limit {
+		return fmt.Sprintf("%d comments from %s within %s, at most %d allowed", count, sub.IP, c.window, c.limit)
+	}
+	return ""
+}
+
+// recent drops the times before since
+func recent(times []time.Time, since time.Time) []time.Time {
+	for i, at := range times {
+		if !at.Before(since) {
+			return times[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Package moderation decides whether new comments look like spam before they enter the moderation queue
+package moderation
+
+import (
+	"time"
+)
+
+// Status is the moderation state of a comment
+type Status string
+
+const (
+	// Pending comments wait for a moderator and aren't shown publicly
+	Pending Status = "pending"
+	// Approved comments are shown publicly
+	Approved Status = "approved"
+	// Rejected comments were turned down by a moderator
+	Rejected Status = "rejected"
+	// Spam comments were flagged by a check or a moderator
+	Spam Status = "spam"
+)
+
+// Statuses lists every moderation status
+var Statuses = []Status{Pending, Approved, Rejected, Spam}
+
+// Valid reports whether s is one of the known statuses
+func (s Status) Valid() bool {
+	for _, status := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Submission is a comment as seen by the checks
+type Submission struct {
+	Author  string
+	Content string
+	// IP is the address the comment was sent from, empty when unknown
+	IP string
+	At time.Time
+}
+
+// Check inspects a submission and returns the reason it looks like spam, or an empty string when it looks fine
+type Check interface {
+	Check(sub Submission) string
+}
+
+// CheckFunc adapts a function to the Check interface
+type CheckFunc func(sub Submission) string
+
+func (f CheckFunc) Check(sub Submission) string {
+	return f(sub)
+}
+
+// Result is the outcome of a review
+type Result struct {
+	Status Status
+	// Reasons explain why the submission was flagged
+	Reasons []string
+}
+
+// Moderator runs the configured checks over new comments
+type Moderator struct {
+	checks []Check
+}
+
+func New(checks ...Check) *Moderator {
+	return &Moderator{checks: checks}
+}
+
+// Review runs every check, so stateful checks record the submission even when an earlier one flagged it.
+// Flagged submissions are marked as spam, the rest wait in the queue as pending.
+func (m *Moderator) Review(sub Submission) Result {
+	if sub.At.IsZero() {
+		sub.At = time.Now()
+	}
+
+	result := Result{Status: Pending}
+	for _, check := range m.checks {
+		if reason := check.Check(sub); reason != "" {
+			result.Reasons = append(result.Reasons, reason)
+		}
+	}
+	if len(result.Reasons) > 0 {
+		result.Status = Spam
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package moderation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxLinks(t *testing.T) {
+	check := MaxLinks(1)
+
+	assert.Empty(t, check.Check(Submission{Content: "See https://example.com for details"}))
+	assert.NotEmpty(t, check.Check(Submission{Content: "Buy at http://a.example and www.b.example"}))
+}
+
+func TestBannedWords(t *testing.T) {
+	check := BannedWords([]string{"casino", "Cheap Pills"})
+
+	tests := []struct {
+		content string
+		flagged bool
+	}{
+		{content: "Best CASINO in town!", flagged: true},
+		{content: "cheap   pills, today", flagged: true},
+		{content: "Casinos are a different word", flagged: false},
+		{content: "Pills are cheap", flagged: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			assert.Equal(t, tt.flagged, check.Check(Submission{Content: tt.content}) != "")
+		})
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	check := Duplicates(time.Hour)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	text := "Check out my amazing website for more"
+
+	assert.Empty(t, check.Check(Submission{Content: text, At: start}))
+	assert.NotEmpty(t, check.Check(Submission{Content: "check out my AMAZING website, for more!", At: start.Add(time.Minute)}))
+	assert.Empty(t, check.Check(Submission{Content: "Thanks!", At: start}))
+	assert.Empty(t, check.Check(Submission{Content: "Thanks!", At: start}), "short texts are not compared")
+	assert.Empty(t, check.Check(Submission{Content: text, At: start.Add(3 * time.Hour)}), "window has passed")
+}
+
+func TestRateLimit(t *testing.T) {
+	check := RateLimit(2, time.Minute)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Empty(t, check.Check(Submission{IP: "192.0.2.1", At: start}))
+	assert.Empty(t, check.Check(Submission{IP: "192.0.2.1", At: start.Add(10 * time.Second)}))
+	assert.NotEmpty(t, check.Check(Submission{IP: "192.0.2.1", At: start.Add(20 * time.Second)}))
+	assert.Empty(t, check.Check(Submission{IP: "192.0.2.2", At: start.Add(20 * time.Second)}), "limits are per IP")
+	assert.Empty(t, check.Check(Submission{IP: "192.0.2.1", At: start.Add(2 * time.Minute)}), "window has passed")
+	assert.Empty(t, check.Check(Submission{At: start}), "unknown IP is not limited")
+}
+
+func TestModerator_Review(t *testing.T) {
+	calls := 0
+	counting := CheckFunc(func(Submission) string {
+		calls++
+		return ""
+	})
+	moderator := New(MaxLinks(0), counting)
+
+	result := moderator.Review(Submission{Content: "Nice post"})
+	assert.Equal(t, Pending, result.Status)
+	assert.Empty(t, result.Reasons)
+
+	result = moderator.Review(Submission{Content: "Visit https://spam.example"})
+	assert.Equal(t, Spam, result.Status)
+	assert.Len(t, result.Reasons, 1)
+	assert.Equal(t, 2, calls, "every check runs even after one flagged the submission")
+}
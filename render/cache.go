@@ -0,0 +1,72 @@
+package render
+
+import (
+	"container/list"
+	"sync"
+)
+
+type cacheKey struct {
+	id      int64
+	version int64
+}
+
+type cacheEntry struct {
+	key   cacheKey
+	value string
+}
+
+// lruCache keeps the most recently used rendered posts
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[cacheKey]*list.Element
+	order    *list.List
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		items:    make(map[cacheKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *lruCache) get(key cacheKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).value, true
+}
+
+func (c *lruCache) add(key cacheKey, value string) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		element.Value.(*cacheEntry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
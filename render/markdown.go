@@ -0,0 +1,165 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/pkg/errors"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// minTOCHeadings is the number of headings starting from which a table of contents is worth rendering
+const minTOCHeadings = 2
+
+type Options struct {
+	// TableOfContents prepends a navigation list of the post headings
+	TableOfContents bool
+	// CacheSize is the number of rendered post versions kept in memory, 0 disables caching
+	CacheSize int
+}
+
+// MarkdownRenderer converts CommonMark with GFM tables into sanitized HTML
+type MarkdownRenderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+	toc    bool
+	cache  *lruCache
+}
+
+func NewMarkdownRenderer(opts Options) *MarkdownRenderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.Linkify, extension.TaskList),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+
+	return &MarkdownRenderer{
+		md:     md,
+		policy: newPolicy(),
+		toc:    opts.TableOfContents,
+		cache:  newLRUCache(opts.CacheSize),
+	}
+}
+
+// newPolicy is the allow-list applied to every rendered document. Raw HTML is never trusted,
+// only markup produced by markdown itself is kept.
+func newPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("id").OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowAttrs("type", "checked", "disabled").OnElements("input")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	policy.RequireNoReferrerOnLinks(true)
+	return policy
+}
+
+// Render returns HTML for the post content. Results are cached per post version,
+// so an updated post is rendered again while unchanged ones are served from the cache.
+func (r *MarkdownRenderer) Render(id, version int64, content string) (string, error) {
+	key := cacheKey{id: id, version: version}
+	if rendered, ok := r.cache.get(key); ok {
+		return rendered, nil
+	}
+
+	source := []byte(content)
+	doc := r.md.Parser().Parse(text.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, source, doc); err != nil {
+		return "", errors.Wrap(err, "render markdown")
+	}
+
+	rendered := r.policy.Sanitize(buf.String())
+	// The table of contents is built from escaped heading texts only, so it doesn't go through the policy
+	if r.toc {
+		rendered = tableOfContents(doc, source) + rendered
+	}
+	r.cache.add(key, rendered)
+
+	return rendered, nil
+}
+
+type heading struct {
+	level int
+	id    string
+	text  string
+}
+
+// tableOfContents builds a nested list linking to heading anchors
+func tableOfContents(doc ast.Node, source []byte) string {
+	var headings []heading
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) { // nolint:errcheck
+		h, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		id, _ := h.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		headings = append(headings, heading{level: h.Level, id: string(idBytes), text: nodeText(h, source)})
+		return ast.WalkSkipChildren, nil
+	})
+
+	if len(headings) < minTOCHeadings {
+		return ""
+	}
+
+	base := headings[0].level
+	for _, h := range headings {
+		if h.level < base {
+			base = h.level
+		}
+	}
+
+	// Depth never grows by more than one level at a time, so skipped heading levels don't produce empty lists
+	var sb strings.Builder
+	sb.WriteString(`<nav class="toc"><ul>`)
+	depth := 0
+	for i, h := range headings {
+		level := min(h.level-base, depth+1)
+		switch {
+		case i == 0:
+			level = 0
+			sb.WriteString("<li>")
+		case level > depth:
+			sb.WriteString("<ul><li>")
+		default:
+			sb.WriteString("</li>")
+			for ; depth > level; depth-- {
+				sb.WriteString("</ul></li>")
+			}
+			sb.WriteString("<li>")
+		}
+		depth = level
+		fmt.Fprintf(&sb, `<a href="#%s">%s</a>`, html.EscapeString(h.id), html.EscapeString(h.text))
+	}
+	sb.WriteString("</li>")
+	for ; depth > 0; depth-- {
+		sb.WriteString("</ul></li>")
+	}
+	sb.WriteString("</ul></nav>")
+
+	return sb.String()
+}
+
+func nodeText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) { // nolint:errcheck
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := child.(type) {
+		case *ast.Text:
+			sb.Write(t.Segment.Value(source))
+		case *ast.String:
+			sb.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
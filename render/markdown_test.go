@@ -0,0 +1,85 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownRenderer_Render(t *testing.T) {
+	renderer := NewMarkdownRenderer(Options{TableOfContents: true, CacheSize: 10})
+
+	t.Run("CommonMark and GFM", func(t *testing.T) {
+		content := "# Title\n\nSome *text*.\n\n## Table\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n```go\nfmt.Println(\"hi\")\n```\n"
+
+		rendered, err := renderer.Render(1, 1, content)
+		require.NoError(t, err)
+
+		assert.Contains(t, rendered, `<h1 id="title">Title</h1>`)
+		assert.Contains(t, rendered, `<h2 id="table">Table</h2>`)
+		assert.Contains(t, rendered, `<em>text</em>`)
+		assert.Contains(t, rendered, `<table>`)
+		assert.Contains(t, rendered, `<td>1</td>`)
+		assert.Contains(t, rendered, `<pre><code class="language-go">`)
+		assert.Contains(t, rendered,
+			`<nav class="toc"><ul><li><a href="#title">Title</a><ul><li><a href="#table">Table</a></li></ul></li></ul></nav>`)
+	})
+
+	t.Run("No table of contents for a single heading", func(t *testing.T) {
+		rendered, err := renderer.Render(2, 1, "# Only\n\ntext")
+		require.NoError(t, err)
+		assert.NotContains(t, rendered, "<nav")
+	})
+
+	t.Run("Sanitized", func(t *testing.T) {
+		content := "<script>alert(1)</script>\n\n<img src=x onerror=alert(1)>\n\n[link](javascript:alert(1))\n\n<a href=\"#\" onclick=\"alert(1)\">raw</a>"
+
+		rendered, err := renderer.Render(3, 1, content)
+		require.NoError(t, err)
+
+		assert.NotContains(t, rendered, "<script")
+		assert.NotContains(t, rendered, "onerror")
+		assert.NotContains(t, rendered, "onclick")
+		assert.NotContains(t, rendered, "javascript:")
+	})
+
+	t.Run("Cached per version", func(t *testing.T) {
+		first, err := renderer.Render(4, 1, "first")
+		require.NoError(t, err)
+
+		cached, err := renderer.Render(4, 1, "changed content of the same version")
+		require.NoError(t, err)
+		assert.Equal(t, first, cached)
+
+		updated, err := renderer.Render(4, 2, "second")
+		require.NoError(t, err)
+		assert.Contains(t, updated, "second")
+	})
+}
+
+func TestTableOfContents_SkippedLevels(t *testing.T) {
+	renderer := NewMarkdownRenderer(Options{TableOfContents: true})
+
+	rendered, err := renderer.Render(1, 1, "## A\n\n#### B\n\n## C\n")
+	require.NoError(t, err)
+	assert.Contains(t, rendered,
+		`<nav class="toc"><ul><li><a href="#a">A</a><ul><li><a href="#b">B</a></li></ul></li><li><a href="#c">C</a></li></ul></nav>`)
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache(2)
+
+	cache.add(cacheKey{id: 1}, "one")
+	cache.add(cacheKey{id: 2}, "two")
+	_, ok := cache.get(cacheKey{id: 1})
+	require.True(t, ok)
+
+	cache.add(cacheKey{id: 3}, "three")
+	assert.Equal(t, 2, cache.len())
+
+	_, ok = cache.get(cacheKey{id: 2})
+	assert.False(t, ok, "least recently used entry must be evicted")
+	_, ok = cache.get(cacheKey{id: 1})
+	assert.True(t, ok)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var (
+	jane   = auth.Principal{Subject: "jane", Name: "Jane Doe", AuthorID: 1, Roles: []string{auth.RoleAuthor}}
+	john   = auth.Principal{Subject: "john", Name: "John Roe", AuthorID: 2, Roles: []string{auth.RoleAuthor}}
+	editor = auth.Principal{Subject: "ed", Name: "Ed Itor", AuthorID: 3, Roles: []string{auth.RoleEditor}}
+	admin  = auth.Principal{Subject: "root", Roles: []string{auth.RoleAdmin}}
+)
+
+func TestApplication_WritesRequirePrincipal(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+	ctx := context.Background()
+
+	_, err := app.CreatePost(ctx, models.Post{Title: "Title", Author: "Jane Doe"})
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	assert.ErrorIs(t, app.UpdatePost(ctx, models.Post{ID: 1, Title: "Title", Author: "Jane Doe"}), ErrUnauthenticated)
+	assert.ErrorIs(t, app.DeletePost(ctx, 1), ErrUnauthenticated)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+}
+
+func TestApplication_PostOwnership(t *testing.T) {
+	janePost := storage.Post{ID: 1, Title: "Title", Author: "Jane Doe", AuthorID: 1, Slug: "title"}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		write     func(app *Application, ctx context.Context) error
+		wantErr   error
+	}{
+		{
+			name:      "author creates own post",
+			principal: jane,
+			write: func(app *Application, ctx context.Context) error {
+				_, err := app.CreatePost(ctx, models.Post{Title: "Title", AuthorID: 1})
+				return err
+			},
+		},
+		{
+			name:      "author creates post by name ignoring case",
+			principal: jane,
+			write: func(app *Application, ctx context.Context) error {
+				_, err := app.CreatePost(ctx, models.Post{Title: "Title", Author: "jane doe"})
+				return err
+			},
+		},
+		{
+			name:      "author can't create post of another author",
+			principal: john,
+			write: func(app *Application, ctx context.Context) error {
+				_, err := app.CreatePost(ctx, models.Post{Title: "Title", Author: "Jane Doe"})
+				return err
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:      "admin creates post of any author",
+			principal: admin,
+			write: func(app *Application, ctx context.Context) error {
+				_, err := app.CreatePost(ctx, models.Post{Title: "Title", Author: "Jane Doe"})
+				return err
+			},
+		},
+		{
+			name:      "author updates own post",
+			principal: jane,
+			write: func(app *Application, ctx context.Context) error {
+				return app.UpdatePost(ctx, models.Post{ID: 1, Title: "Title", AuthorID: 1})
+			},
+		},
+		{
+			name:      "author can't update another author's post",
+			principal: john,
+			write: func(app *Application, ctx context.Context) error {
+				return app.UpdatePost(ctx, models.Post{ID: 1, Title: "Title", AuthorID: 2})
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:      "author can't hand the post over",
+			principal: jane,
+			write: func(app *Application, ctx context.Context) error {
+				return app.UpdatePost(ctx, models.Post{ID: 1, Title: "Title", AuthorID: 2})
+			},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:      "admin hands the post over",
+			principal: admin,
+			write: func(app *Application, ctx context.Context) error {
+				return app.UpdatePost(ctx, models.Post{ID: 1, Title: "Title", AuthorID: 2})
+			},
+		},
+		{
+			name:      "editor updates another author's post",
+			principal: editor,
+			write: func(app *Application, ctx context.Context) error {
+				return app.UpdatePost(ctx, models.Post{ID: 1, Title: "Title", AuthorID: 1})
+			},
+		},
+		{
+			name:      "editor can't delete another author's post",
+			principal: editor,
+			write:     func(app *Application, ctx context.Context) error { return app.DeletePost(ctx, 1) },
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "author deletes own post",
+			principal: jane,
+			write:     func(app *Application, ctx context.Context) error { return app.DeletePost(ctx, 1) },
+		},
+		{
+			name:      "author can't delete another author's post",
+			principal: john,
+			write:     func(app *Application, ctx context.Context) error { return app.DeletePost(ctx, 1) },
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "admin deletes any post",
+			principal: admin,
+			write:     func(app *Application, ctx context.Context) error { return app.DeletePost(ctx, 1) },
+		},
+		{
+			name:      "author role is needed to write own posts",
+			principal: auth.Principal{Subject: "jane", Name: "Jane Doe", AuthorID: 1, Roles: []string{auth.RoleReader}},
+			write:     func(app *Application, ctx context.Context) error { return app.DeletePost(ctx, 1) },
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "principal without roles is a reader",
+			principal: auth.Principal{Subject: "reader"},
+			write: func(app *Application, ctx context.Context) error {
+				_, err := app.CreatePost(ctx, models.Post{Title: "Title", Author: "Reader"})
+				return err
+			},
+			wantErr: ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockRepo, mockAuthors := newAuthorTestApp()
+			mockRepo.On("GetByID", 1).Return(janePost, nil).Maybe()
+			mockRepo.On("Create", mock.Anything).Return(janePost, nil).Maybe()
+			mockRepo.On("Update", mock.Anything).Return(janePost, nil).Maybe()
+			mockRepo.On("Delete", 1).Return(nil).Maybe()
+			mockAuthors.On("GetByID", int64(1)).Return(storage.Author{ID: 1, Name: "Jane Doe"}, nil).Maybe()
+			mockAuthors.On("GetByID", int64(2)).Return(storage.Author{ID: 2, Name: "John Roe"}, nil).Maybe()
+			mockAuthors.On("GetByName", mock.Anything).Return(storage.Author{ID: 1, Name: "Jane Doe"}, nil).Maybe()
+			mockAuthors.On("GetAll").Return([]storage.Author{{ID: 1, Name: "Jane Doe"}, {ID: 2, Name: "John Roe"}}, nil).Maybe()
+
+			err := tt.write(app, auth.NewContext(context.Background(), tt.principal))
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+			mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+			mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+		})
+	}
+}
+
+func TestApplication_CreatePost_NoRecordForForeignName(t *testing.T) {
+	app, mockRepo, mockAuthors := newAuthorTestApp()
+
+	_, err := app.CreatePost(auth.NewContext(context.Background(), john), models.Post{Title: "Title", Author: "Someone Else"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	mockAuthors.AssertNotCalled(t, "Create", mock.Anything)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var (
+	ErrAPIKeysDisabled = errors.New("API keys are not configured")
+	ErrAPIKeyNotFound  = errors.New("API key not found")
+	ErrInvalidAPIKey   = errors.New("invalid or expired API key")
+	ErrExpiryInPast    = errors.New("expiry is in the past")
+)
+
+// apiKeyPrefixLength is how much of a key is kept in clear to tell keys apart
+const apiKeyPrefixLength = 10
+
+// APIKeyRepo stores API keys by the hash of the key
+type APIKeyRepo interface {
+	Create(key storage.APIKey) (storage.APIKey, error)
+	GetAll() ([]storage.APIKey, error)
+	GetByHash(hash string) (storage.APIKey, error)
+	Touch(id int64, at time.Time) error
+	Delete(id int64) error
+}
+
+// WithAPIKeyRepo lets machine clients authenticate with API keys minted by admins
+func WithAPIKeyRepo(repo APIKeyRepo) Option {
+	return func(app *Application) {
+		app.apiKeys = repo
+	}
+}
+
+// CreateAPIKey mints a new key. The returned model is the only place the key itself shows up, only its hash is stored.
+func (app *Application) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	app.log(ctx).Debug("Creating a new API key")
+
+	if _, err := app.authorize(ctx, ActionManageAPIKeys); err != nil {
+		return models.APIKey{}, err
+	}
+	if app.apiKeys == nil {
+		return models.APIKey{}, ErrAPIKeysDisabled
+	}
+	if key.ExpiresAt != nil && !time.Time(*key.ExpiresAt).After(time.Now()) {
+		return models.APIKey{}, ErrExpiryInPast
+	}
+	if key.AuthorID != 0 {
+		if app.authors == nil {
+			return models.APIKey{}, ErrAuthorsDisabled
+		}
+		if _, err := app.authors.GetByID(key.AuthorID); err != nil {
+			if errors.Is(err, storage.ErrAuthorNotFound) {
+				return models.APIKey{}, ErrUnknownAuthor
+			}
+			return models.APIKey{}, err
+		}
+	}
+
+	secret, err := auth.GenerateAPIKey()
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	dbKey := storage.APIKey{
+		Name:     key.Name,
+		Prefix:   secret[:apiKeyPrefixLength],
+		Hash:     auth.HashAPIKey(secret),
+		Scopes:   key.Scopes,
+		AuthorID: key.AuthorID,
+	}
+	if key.ExpiresAt != nil {
+		dbKey.ExpiresAt = time.Time(*key.ExpiresAt).UTC()
+	}
+
+	created, err := app.apiKeys.Create(dbKey)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	result := toModelAPIKey(created)
+	result.Key = secret
+	return result, nil
+}
+
+func (app *Application) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	app.log(ctx).Debug("Retrieving all API keys")
+
+	if _, err := app.authorize(ctx, ActionManageAPIKeys); err != nil {
+		return nil, err
+	}
+	if app.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+
+	dbKeys, err := app.apiKeys.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]models.APIKey, 0, len(dbKeys))
+	for _, dbKey := range dbKeys {
+		keys = append(keys, toModelAPIKey(dbKey))
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey deletes the key, requests using it fail right away
+func (app *Application) RevokeAPIKey(ctx context.Context, id int64) error {
+	app.log(ctx).Debug("Revoking API key", slog.Int64("id", id))
+
+	if _, err := app.authorize(ctx, ActionManageAPIKeys); err != nil {
+		return err
+	}
+	if app.apiKeys == nil {
+		return ErrAPIKeysDisabled
+	}
+
+	if err := app.apiKeys.Delete(id); err != nil {
+		return mapStorageError(err)
+	}
+	return nil
+}
+
+// AuthenticateAPIKey returns the principal of a valid key and records its use.
+// Unknown and expired keys are reported as ErrInvalidAPIKey.
+func (app *Application) AuthenticateAPIKey(ctx context.Context, key string) (auth.Principal, error) {
+	if app.apiKeys == nil {
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+
+	dbKey, err := app.apiKeys.GetByHash(auth.HashAPIKey(key))
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	now := time.Now().UTC()
+	if !dbKey.ExpiresAt.IsZero() && !now.Before(dbKey.ExpiresAt) {
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+	// A failed bookkeeping write must not lock the client out
+	if err := app.apiKeys.Touch(dbKey.ID, now); err != nil {
+		app.log(ctx).Error("Failed to record API key use", "error", err, slog.Int64("id", dbKey.ID))
+	}
+
+	p := auth.Principal{
+		Subject:  "api-key:" + strconv.FormatInt(dbKey.ID, 10),
+		AuthorID: dbKey.AuthorID,
+		Scopes:   append([]string{}, dbKey.Scopes...),
+	}
+	if p.AuthorID != 0 && app.authors != nil {
+		if author, err := app.authors.GetByID(p.AuthorID); err == nil {
+			p.Name = author.Name
+		}
+	}
+	switch {
+	case hasScope(dbKey.Scopes, auth.ScopeAdmin):
+		p.Roles = []string{auth.RoleAdmin}
+	case p.AuthorID != 0:
+		p.Roles = []string{auth.RoleAuthor}
+	default:
+		p.Roles = []string{auth.RoleReader}
+	}
+	return p, nil
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func toModelAPIKey(dbKey storage.APIKey) models.APIKey {
+	key := models.APIKey{
+		ID:        dbKey.ID,
+		Name:      dbKey.Name,
+		Prefix:    dbKey.Prefix,
+		Scopes:    dbKey.Scopes,
+		AuthorID:  dbKey.AuthorID,
+		CreatedAt: strfmt.DateTime(dbKey.CreatedAt),
+	}
+	if !dbKey.ExpiresAt.IsZero() {
+		expiresAt := strfmt.DateTime(dbKey.ExpiresAt)
+		key.ExpiresAt = &expiresAt
+	}
+	if !dbKey.LastUsedAt.IsZero() {
+		lastUsedAt := strfmt.DateTime(dbKey.LastUsedAt)
+		key.LastUsedAt = &lastUsedAt
+	}
+	return key
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"rakia_blog_tt/storage"
+)
+
+// MockAPIKeyRepo is a mock implementation of the APIKeyRepo interface
+type MockAPIKeyRepo struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepo) Create(key storage.APIKey) (storage.APIKey, error) {
+	args := m.Called(key)
+	return args.Get(0).(storage.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepo) GetAll() ([]storage.APIKey, error) {
+	args := m.Called()
+	return args.Get(0).([]storage.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepo) GetByHash(hash string) (storage.APIKey, error) {
+	args := m.Called(hash)
+	return args.Get(0).(storage.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepo) Touch(id int64, at time.Time) error {
+	args := m.Called(id, at)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepo) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+func newAPIKeyTestApp() (*Application, *MockAPIKeyRepo, *MockAuthorRepo) {
+	mockKeys := new(MockAPIKeyRepo)
+	mockAuthors := new(MockAuthorRepo)
+	return New(new(MockRepo), loggerMock(), WithAPIKeyRepo(mockKeys), WithAuthorRepo(mockAuthors)), mockKeys, mockAuthors
+}
+
+func TestApplication_CreateAPIKey(t *testing.T) {
+	app, mockKeys, mockAuthors := newAPIKeyTestApp()
+	mockAuthors.On("GetByID", int64(1)).Return(storage.Author{ID: 1, Name: "Author 1"}, nil)
+
+	var stored storage.APIKey
+	mockKeys.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(storage.APIKey)
+	}).Return(storage.APIKey{ID: 1, Name: "CI", Prefix: "bk_1234567", Scopes: []string{auth.ScopePostsWrite}, AuthorID: 1}, nil)
+
+	expiresAt := strfmt.DateTime(time.Now().Add(time.Hour))
+	created, err := app.CreateAPIKey(adminCtx(), models.APIKey{
+		Name:      "CI",
+		Scopes:    []string{auth.ScopePostsWrite},
+		AuthorID:  1,
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, auth.IsAPIKey(created.Key))
+	assert.Equal(t, auth.HashAPIKey(created.Key), stored.Hash, "only the hash is stored")
+	assert.NotContains(t, stored.Hash, strings.TrimPrefix(created.Key, auth.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(created.Key, stored.Prefix))
+	assert.Equal(t, time.Time(expiresAt).UTC(), stored.ExpiresAt)
+	assert.Equal(t, int64(1), created.ID)
+}
+
+func TestApplication_CreateAPIKey_Errors(t *testing.T) {
+	past := strfmt.DateTime(time.Now().Add(-time.Minute))
+	author := auth.NewContext(context.Background(), auth.Principal{Subject: "jane", Name: "Jane Doe", Roles: []string{auth.RoleAuthor}})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		key     models.APIKey
+		wantErr error
+	}{
+		{name: "anonymous", ctx: context.Background(), key: models.APIKey{Name: "CI"}, wantErr: ErrUnauthenticated},
+		{name: "not an admin", ctx: author, key: models.APIKey{Name: "CI"}, wantErr: ErrForbidden},
+		{name: "expired", ctx: adminCtx(), key: models.APIKey{Name: "CI", ExpiresAt: &past}, wantErr: ErrExpiryInPast},
+		{name: "unknown author", ctx: adminCtx(), key: models.APIKey{Name: "CI", AuthorID: 42}, wantErr: ErrUnknownAuthor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockKeys, mockAuthors := newAPIKeyTestApp()
+			mockAuthors.On("GetByID", int64(42)).Return(storage.Author{}, storage.ErrAuthorNotFound).Maybe()
+
+			_, err := app.CreateAPIKey(tt.ctx, tt.key)
+			assert.ErrorIs(t, err, tt.wantErr)
+			mockKeys.AssertNotCalled(t, "Create", mock.Anything)
+		})
+	}
+
+	_, err := New(new(MockRepo), loggerMock()).CreateAPIKey(adminCtx(), models.APIKey{Name: "CI"})
+	assert.ErrorIs(t, err, ErrAPIKeysDisabled)
+}
+
+func TestApplication_AuthenticateAPIKey(t *testing.T) {
+	app, mockKeys, mockAuthors := newAPIKeyTestApp()
+	mockAuthors.On("GetByID", int64(1)).Return(storage.Author{ID: 1, Name: "Author 1"}, nil)
+
+	mockKeys.On("GetByHash", auth.HashAPIKey("bk_writer")).
+		Return(storage.APIKey{ID: 1, Scopes: []string{auth.ScopePostsWrite}, AuthorID: 1}, nil)
+	mockKeys.On("GetByHash", auth.HashAPIKey("bk_admin")).
+		Return(storage.APIKey{ID: 2, Scopes: []string{auth.ScopeAdmin}, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mockKeys.On("GetByHash", auth.HashAPIKey("bk_expired")).
+		Return(storage.APIKey{ID: 3, Scopes: []string{auth.ScopeAdmin}, ExpiresAt: time.Now().Add(-time.Hour)}, nil)
+	mockKeys.On("GetByHash", auth.HashAPIKey("bk_reader")).
+		Return(storage.APIKey{ID: 4, Scopes: []string{auth.ScopePostsRead}}, nil)
+	mockKeys.On("GetByHash", auth.HashAPIKey("bk_unknown")).Return(storage.APIKey{}, storage.ErrAPIKeyNotFound)
+	mockKeys.On("Touch", mock.Anything, mock.Anything).Return(nil)
+
+	p, err := app.AuthenticateAPIKey(context.Background(), "bk_writer")
+	require.NoError(t, err)
+	assert.Equal(t, auth.Principal{
+		Subject:  "api-key:1",
+		Name:     "Author 1",
+		AuthorID: 1,
+		Roles:    []string{auth.RoleAuthor},
+		Scopes:   []string{auth.ScopePostsWrite},
+	}, p)
+	assert.False(t, p.IsAdmin())
+	mockKeys.AssertCalled(t, "Touch", int64(1), mock.Anything)
+
+	p, err = app.AuthenticateAPIKey(context.Background(), "bk_admin")
+	require.NoError(t, err)
+	assert.True(t, p.IsAdmin(), "admin scope grants the admin role")
+
+	p, err = app.AuthenticateAPIKey(context.Background(), "bk_reader")
+	require.NoError(t, err)
+	assert.Equal(t, []string{auth.RoleReader}, p.Roles, "keys without an author only read")
+
+	_, err = app.AuthenticateAPIKey(context.Background(), "bk_expired")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	mockKeys.AssertNotCalled(t, "Touch", int64(3), mock.Anything)
+
+	_, err = app.AuthenticateAPIKey(context.Background(), "bk_unknown")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestApplication_RevokeAPIKey(t *testing.T) {
+	app, mockKeys, _ := newAPIKeyTestApp()
+	mockKeys.On("Delete", int64(1)).Return(nil)
+	mockKeys.On("Delete", int64(2)).Return(storage.ErrAPIKeyNotFound)
+
+	require.NoError(t, app.RevokeAPIKey(adminCtx(), 1))
+	assert.ErrorIs(t, app.RevokeAPIKey(adminCtx(), 2), ErrAPIKeyNotFound)
+	assert.ErrorIs(t, app.RevokeAPIKey(context.Background(), 1), ErrUnauthenticated)
+	mockKeys.AssertNumberOfCalls(t, "Delete", 2)
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var (
+	ErrAuthorsDisabled = errors.New("authors are not configured")
+	ErrAuthorNotFound  = errors.New("author not found")
+	ErrUnknownAuthor   = errors.New("referenced author does not exist")
+	ErrAuthorNameTaken = errors.New("author name is used by another author")
+	ErrAuthorInUse     = errors.New("author has posts")
+)
+
+// AuthorRepo stores authors. Names are unique ignoring case.
+type AuthorRepo interface {
+	Create(author storage.Author) (storage.Author, error)
+	GetAll() ([]storage.Author, error)
+	GetByID(id int64) (storage.Author, error)
+	GetByName(name string) (storage.Author, error)
+	Update(author storage.Author) (storage.Author, error)
+	Delete(id int64) error
+}
+
+// WithAuthorRepo links posts to author records, so renaming an author renames them on every post
+func WithAuthorRepo(repo AuthorRepo) Option {
+	return func(app *Application) {
+		app.authors = repo
+	}
+}
+
+func (app *Application) CreateAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	app.log(ctx).Debug("Creating a new author")
+
+	if _, err := app.authorize(ctx, ActionManageAuthors); err != nil {
+		return models.Author{}, err
+	}
+	if app.authors == nil {
+		return models.Author{}, ErrAuthorsDisabled
+	}
+
+	created, err := app.authors.Create(toStorageAuthor(author))
+	if err != nil {
+		return models.Author{}, mapStorageError(err)
+	}
+
+	return toModelAuthor(created), nil
+}
+
+func (app *Application) GetAuthors(ctx context.Context) ([]models.Author, error) {
+	app.log(ctx).Debug("Retrieving all authors")
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return nil, err
+	}
+	if app.authors == nil {
+		return nil, ErrAuthorsDisabled
+	}
+
+	dbAuthors, err := app.authors.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make([]models.Author, 0, len(dbAuthors))
+	for _, dbAuthor := range dbAuthors {
+		authors = append(authors, toModelAuthor(dbAuthor))
+	}
+
+	return authors, nil
+}
+
+func (app *Application) GetAuthorByID(ctx context.Context, id int64) (models.Author, error) {
+	app.log(ctx).Debug("Retrieving author by ID", slog.Int64("id", id))
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return models.Author{}, err
+	}
+	if app.authors == nil {
+		return models.Author{}, ErrAuthorsDisabled
+	}
+
+	dbAuthor, err := app.authors.GetByID(id)
+	if err != nil {
+		return models.Author{}, mapStorageError(err)
+	}
+
+	return toModelAuthor(dbAuthor), nil
+}
+
+// UpdateAuthor replaces the author profile, posts show the new name right away
+func (app *Application) UpdateAuthor(ctx context.Context, author models.Author) (models.Author, error) {
+	app.log(ctx).Debug("Updating author", "author_id", author.ID)
+
+	if _, err := app.authorize(ctx, ActionManageAuthors); err != nil {
+		return models.Author{}, err
+	}
+	if app.authors == nil {
+		return models.Author{}, ErrAuthorsDisabled
+	}
+
+	updated, err := app.authors.Update(toStorageAuthor(author))
+	if err != nil {
+		return models.Author{}, mapStorageError(err)
+	}
+
+	return toModelAuthor(updated), nil
+}
+
+// DeleteAuthor removes an author without posts
+func (app *Application) DeleteAuthor(ctx context.Context, id int64) error {
+	app.log(ctx).Debug("Deleting author", slog.Int64("id", id))
+
+	if _, err := app.authorize(ctx, ActionManageAuthors); err != nil {
+		return err
+	}
+	if app.authors == nil {
+		return ErrAuthorsDisabled
+	}
+
+	if _, err := app.authors.GetByID(id); err != nil {
+		return mapStorageError(err)
+	}
+
+	posts, err := app.repository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if post.AuthorID == id {
+			return ErrAuthorInUse
+		}
+	}
+
+	return mapStorageError(app.authors.Delete(id))
+}
+
+// resolveAuthor links the post to its author record. AuthorID takes precedence, otherwise the author is looked up
+// by name ignoring case and created when missing, so clients sending plain names keep working.
+func (app *Application) resolveAuthor(post *storage.Post) error {
+	if app.authors == nil {
+		if post.AuthorID != 0 {
+			return ErrAuthorsDisabled
+		}
+		return nil
+	}
+
+	if post.AuthorID != 0 {
+		author, err := app.authors.GetByID(post.AuthorID)
+		if errors.Is(err, storage.ErrAuthorNotFound) {
+			return ErrUnknownAuthor
+		}
+		if err != nil {
+			return err
+		}
+		post.Author = author.Name
+		return nil
+	}
+
+	author, err := app.authors.GetByName(post.Author)
+	if errors.Is(err, storage.ErrAuthorNotFound) {
+		author, err = app.authors.Create(storage.Author{Name: post.Author})
+		if errors.Is(err, storage.ErrAuthorNameTaken) {
+			// Created concurrently by another request
+			author, err = app.authors.GetByName(post.Author)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	post.AuthorID = author.ID
+	post.Author = author.Name
+	return nil
+}
+
+// withAuthors fills the current author names and summaries of posts linked to author records
+func (app *Application) withAuthors(posts []models.Post) ([]models.Post, error) {
+	if app.authors == nil {
+		return posts, nil
+	}
+
+	var authors map[int64]storage.Author
+	for i := range posts {
+		if posts[i].AuthorID == 0 {
+			continue
+		}
+		if authors == nil {
+			dbAuthors, err := app.authors.GetAll()
+			if err != nil {
+				return nil, err
+			}
+			authors = make(map[int64]storage.Author, len(dbAuthors))
+			for _, dbAuthor := range dbAuthors {
+				authors[dbAuthor.ID] = dbAuthor
+			}
+		}
+
+		author, ok := authors[posts[i].AuthorID]
+		if !ok {
+			continue
+		}
+		posts[i].Author = author.Name
+		posts[i].AuthorSummary = &models.AuthorSummary{
+			ID:        author.ID,
+			Name:      author.Name,
+			AvatarURL: strfmt.URI(author.AvatarURL),
+		}
+	}
+
+	return posts, nil
+}
+
+func toStorageAuthor(author models.Author) storage.Author {
+	return storage.Author{
+		ID:        author.ID,
+		Name:      author.Name,
+		Bio:       author.Bio,
+		AvatarURL: author.AvatarURL.String(),
+	}
+}
+
+func toModelAuthor(dbAuthor storage.Author) models.Author {
+	return models.Author{
+		ID:        dbAuthor.ID,
+		Name:      dbAuthor.Name,
+		Bio:       dbAuthor.Bio,
+		AvatarURL: strfmt.URI(dbAuthor.AvatarURL),
+		CreatedAt: strfmt.DateTime(dbAuthor.CreatedAt),
+		UpdatedAt: strfmt.DateTime(dbAuthor.UpdatedAt),
+	}
+}
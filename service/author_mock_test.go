@@ -0,0 +1,41 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+	"rakia_blog_tt/storage"
+)
+
+// MockAuthorRepo is a mock implementation of the AuthorRepo interface
+type MockAuthorRepo struct {
+	mock.Mock
+}
+
+func (m *MockAuthorRepo) Create(author storage.Author) (storage.Author, error) {
+	args := m.Called(author)
+	return args.Get(0).(storage.Author), args.Error(1)
+}
+
+func (m *MockAuthorRepo) GetAll() ([]storage.Author, error) {
+	args := m.Called()
+	return args.Get(0).([]storage.Author), args.Error(1)
+}
+
+func (m *MockAuthorRepo) GetByID(id int64) (storage.Author, error) {
+	args := m.Called(id)
+	return args.Get(0).(storage.Author), args.Error(1)
+}
+
+func (m *MockAuthorRepo) GetByName(name string) (storage.Author, error) {
+	args := m.Called(name)
+	return args.Get(0).(storage.Author), args.Error(1)
+}
+
+func (m *MockAuthorRepo) Update(author storage.Author) (storage.Author, error) {
+	args := m.Called(author)
+	return args.Get(0).(storage.Author), args.Error(1)
+}
+
+func (m *MockAuthorRepo) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var testAuthors = []storage.Author{
+	{ID: 1, Name: "Author 1", AvatarURL: "https://example.com/1.png"},
+	{ID: 2, Name: "Author 2"},
+}
+
+func newAuthorTestApp() (*Application, *MockRepo, *MockAuthorRepo) {
+	mockRepo := new(MockRepo)
+	mockAuthors := new(MockAuthorRepo)
+	return New(mockRepo, loggerMock(), WithAuthorRepo(mockAuthors)), mockRepo, mockAuthors
+}
+
+func TestApplication_CreatePost_ResolvesAuthor(t *testing.T) {
+	tests := []struct {
+		name       string
+		post       models.Post
+		setup      func(authors *MockAuthorRepo)
+		wantID     int64
+		wantAuthor string
+		wantErr    error
+	}{
+		{
+			name:       "by ID",
+			post:       models.Post{Title: "Title", Content: "Content", Author: "Ignored", AuthorID: 2},
+			setup:      func(authors *MockAuthorRepo) { authors.On("GetByID", int64(2)).Return(testAuthors[1], nil) },
+			wantID:     2,
+			wantAuthor: "Author 2",
+		},
+		{
+			name: "unknown ID",
+			post: models.Post{Title: "Title", Content: "Content", AuthorID: 42},
+			setup: func(authors *MockAuthorRepo) {
+				authors.On("GetByID", int64(42)).Return(storage.Author{}, storage.ErrAuthorNotFound)
+			},
+			wantErr: ErrUnknownAuthor,
+		},
+		{
+			name:       "by name ignoring case",
+			post:       models.Post{Title: "Title", Content: "Content", Author: "author 1"},
+			setup:      func(authors *MockAuthorRepo) { authors.On("GetByName", "author 1").Return(testAuthors[0], nil) },
+			wantID:     1,
+			wantAuthor: "Author 1",
+		},
+		{
+			name: "new name creates the author",
+			post: models.Post{Title: "Title", Content: "Content", Author: "Newcomer"},
+			setup: func(authors *MockAuthorRepo) {
+				authors.On("GetByName", "Newcomer").Return(storage.Author{}, storage.ErrAuthorNotFound)
+				authors.On("Create", storage.Author{Name: "Newcomer"}).Return(storage.Author{ID: 3, Name: "Newcomer"}, nil)
+			},
+			wantID:     3,
+			wantAuthor: "Newcomer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockRepo, mockAuthors := newAuthorTestApp()
+			tt.setup(mockAuthors)
+			mockAuthors.On("GetAll").Return(append(testAuthors, storage.Author{ID: 3, Name: "Newcomer"}), nil)
+			mockRepo.On("Create", mock.Anything).Return(storage.Post{ID: 1, Author: tt.wantAuthor, AuthorID: tt.wantID}, nil).Maybe()
+
+			created, err := app.CreatePost(adminCtx(), tt.post)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantID, created.AuthorID)
+			assert.Equal(t, tt.wantAuthor, created.Author)
+			require.NotNil(t, created.AuthorSummary)
+			assert.Equal(t, tt.wantID, created.AuthorSummary.ID)
+			mockRepo.AssertCalled(t, "Create", mock.MatchedBy(func(post storage.Post) bool {
+				return post.AuthorID == tt.wantID && post.Author == tt.wantAuthor
+			}))
+		})
+	}
+}
+
+func TestApplication_GetPosts_CurrentAuthorNames(t *testing.T) {
+	app, mockRepo, mockAuthors := newAuthorTestApp()
+
+	mockRepo.On("GetAll").Return([]storage.Post{
+		{ID: 1, Title: "Linked", Author: "Former name", AuthorID: 1},
+		{ID: 2, Title: "Unlinked", Author: "Guest"},
+	}, nil)
+	mockAuthors.On("GetAll").Return(testAuthors, nil)
+
+	posts, err := app.GetPosts(context.Background())
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, "Author 1", posts[0].Author, "renames show up on every post")
+	require.NotNil(t, posts[0].AuthorSummary)
+	assert.Equal(t, "https://example.com/1.png", posts[0].AuthorSummary.AvatarURL.String())
+	assert.Equal(t, "Guest", posts[1].Author)
+	assert.Nil(t, posts[1].AuthorSummary)
+}
+
+func TestApplication_DeleteAuthor(t *testing.T) {
+	app, mockRepo, mockAuthors := newAuthorTestApp()
+
+	mockAuthors.On("GetByID", int64(1)).Return(testAuthors[0], nil)
+	mockAuthors.On("GetByID", int64(2)).Return(testAuthors[1], nil)
+	mockAuthors.On("GetByID", int64(42)).Return(storage.Author{}, storage.ErrAuthorNotFound)
+	mockAuthors.On("Delete", int64(2)).Return(nil)
+	mockRepo.On("GetAll").Return([]storage.Post{{ID: 1, AuthorID: 1}}, nil)
+
+	assert.ErrorIs(t, app.DeleteAuthor(adminCtx(), 1), ErrAuthorInUse)
+	assert.ErrorIs(t, app.DeleteAuthor(adminCtx(), 42), ErrAuthorNotFound)
+	require.NoError(t, app.DeleteAuthor(adminCtx(), 2))
+	mockAuthors.AssertNumberOfCalls(t, "Delete", 1)
+}
+
+func TestApplication_AuthorsDisabled(t *testing.T) {
+	app := New(new(MockRepo), loggerMock())
+
+	_, err := app.GetAuthors(context.Background())
+	assert.ErrorIs(t, err, ErrAuthorsDisabled)
+
+	_, err = app.CreatePost(adminCtx(), models.Post{Title: "Title", Content: "Content", AuthorID: 1})
+	assert.ErrorIs(t, err, ErrAuthorsDisabled)
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var (
+	ErrCategoriesDisabled = errors.New("categories are not configured")
+	ErrCategoryNotFound   = errors.New("category not found")
+	ErrUnknownCategory    = errors.New("referenced category does not exist")
+	ErrCategoryCycle      = errors.New("category cannot be its own ancestor")
+	ErrCategoryInUse      = errors.New("category has subcategories or posts")
+)
+
+// CategoryRepo stores the category tree. Tree consistency is checked by the Application.
+type CategoryRepo interface {
+	Create(category storage.Category) (storage.Category, error)
+	GetAll() ([]storage.Category, error)
+	GetByID(id int64) (storage.Category, error)
+	Update(category storage.Category) (storage.Category, error)
+	Delete(id int64) error
+}
+
+// WithCategoryRepo enables hierarchical categories of posts
+func WithCategoryRepo(repo CategoryRepo) Option {
+	return func(app *Application) {
+		app.categories = repo
+	}
+}
+
+// PostFilter narrows down post lists, zero values match every post
+type PostFilter struct {
+	Tags         []string
+	MatchAllTags bool
+	// CategoryID matches posts of the category and all of its descendants
+	CategoryID int64
+	// Status matches posts in the workflow status
+	Status string
+}
+
+// categoryTree maps category IDs to categories
+type categoryTree map[int64]storage.Category
+
+// isDescendant reports whether id is ancestorID or lies below it.
+// Walking up is bounded by the tree size, so corrupted data with cycles can't hang it.
+func (tree categoryTree) isDescendant(id, ancestorID int64) bool {
+	for steps := 0; id != 0 && steps <= len(tree); steps++ {
+		if id == ancestorID {
+			return true
+		}
+		id = tree[id].ParentID
+	}
+	return false
+}
+
+// breadcrumb returns the path from the root category to the category
+func (tree categoryTree) breadcrumb(id int64) []*models.Category {
+	var path []*models.Category
+	for steps := 0; steps <= len(tree); steps++ {
+		category, ok := tree[id]
+		if !ok {
+			break
+		}
+		path = append([]*models.Category{toModelCategory(category)}, path...)
+		id = category.ParentID
+	}
+	return path
+}
+
+func (app *Application) loadCategoryTree() (categoryTree, error) {
+	categories, err := app.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(categoryTree, len(categories))
+	for _, category := range categories {
+		tree[category.ID] = category
+	}
+	return tree, nil
+}
+
+func (app *Application) CreateCategory(ctx context.Context, category models.Category) (models.Category, error) {
+	app.log(ctx).Debug("Creating a new category")
+
+	if _, err := app.authorize(ctx, ActionManageCategories); err != nil {
+		return models.Category{}, err
+	}
+	if app.categories == nil {
+		return models.Category{}, ErrCategoriesDisabled
+	}
+
+	app.categoryMu.Lock()
+	defer app.categoryMu.Unlock()
+
+	if err := app.checkCategory(category.ParentID); err != nil {
+		return models.Category{}, err
+	}
+
+	created, err := app.categories.Create(toStorageCategory(category))
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	return *toModelCategory(created), nil
+}
+
+func (app *Application) GetCategories(ctx context.Context) ([]models.Category, error) {
+	app.log(ctx).Debug("Retrieving all categories")
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return nil, err
+	}
+	if app.categories == nil {
+		return nil, ErrCategoriesDisabled
+	}
+
+	dbCategories, err := app.categories.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]models.Category, 0, len(dbCategories))
+	for _, dbCategory := range dbCategories {
+		categories = append(categories, *toModelCategory(dbCategory))
+	}
+
+	return categories, nil
+}
+
+func (app *Application) GetCategoryByID(ctx context.Context, id int64) (models.Category, error) {
+	app.log(ctx).Debug("Retrieving category by ID", slog.Int64("id", id))
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return models.Category{}, err
+	}
+	if app.categories == nil {
+		return models.Category{}, ErrCategoriesDisabled
+	}
+
+	dbCategory, err := app.categories.GetByID(id)
+	if err != nil {
+		return models.Category{}, mapStorageError(err)
+	}
+
+	return *toModelCategory(dbCategory), nil
+}
+
+// UpdateCategory renames or moves the category. Moving it below itself or one of its descendants is rejected.
+func (app *Application) UpdateCategory(ctx context.Context, category models.Category) (models.Category, error) {
+	app.log(ctx).Debug("Updating category", "category_id", category.ID)
+
+	if _, err := app.authorize(ctx, ActionManageCategories); err != nil {
+		return models.Category{}, err
+	}
+	if app.categories == nil {
+		return models.Category{}, ErrCategoriesDisabled
+	}
+
+	app.categoryMu.Lock()
+	defer app.categoryMu.Unlock()
+
+	tree, err := app.loadCategoryTree()
+	if err != nil {
+		return models.Category{}, err
+	}
+	if _, ok := tree[category.ID]; !ok {
+		return models.Category{}, ErrCategoryNotFound
+	}
+	if category.ParentID != 0 {
+		if _, ok := tree[category.ParentID]; !ok {
+			return models.Category{}, ErrUnknownCategory
+		}
+		if tree.isDescendant(category.ParentID, category.ID) {
+			return models.Category{}, ErrCategoryCycle
+		}
+	}
+
+	updated, err := app.categories.Update(toStorageCategory(category))
+	if err != nil {
+		return models.Category{}, mapStorageError(err)
+	}
+
+	return *toModelCategory(updated), nil
+}
+
+// DeleteCategory removes a category that has neither subcategories nor posts
+func (app *Application) DeleteCategory(ctx context.Context, id int64) error {
+	app.log(ctx).Debug("Deleting category", slog.Int64("id", id))
+
+	if _, err := app.authorize(ctx, ActionManageCategories); err != nil {
+		return err
+	}
+	if app.categories == nil {
+		return ErrCategoriesDisabled
+	}
+
+	app.categoryMu.Lock()
+	defer app.categoryMu.Unlock()
+
+	tree, err := app.loadCategoryTree()
+	if err != nil {
+		return err
+	}
+	if _, ok := tree[id]; !ok {
+		return ErrCategoryNotFound
+	}
+	for _, category := range tree {
+		if category.ParentID == id {
+			return ErrCategoryInUse
+		}
+	}
+
+	posts, err := app.repository.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if post.CategoryID == id {
+			return ErrCategoryInUse
+		}
+	}
+
+	return mapStorageError(app.categories.Delete(id))
+}
+
+// FindPosts returns posts matching the filter
+func (app *Application) FindPosts(ctx context.Context, filter PostFilter) ([]models.Post, error) {
+	var (
+		posts []models.Post
+		err   error
+	)
+	if len(filter.Tags) > 0 {
+		posts, err = app.GetPostsByTags(ctx, filter.Tags, filter.MatchAllTags)
+	} else {
+		posts, err = app.GetPosts(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if filter.Status != "" {
+		var result []models.Post
+		for _, post := range posts {
+			if post.Status == filter.Status {
+				result = append(result, post)
+			}
+		}
+		posts = result
+	}
+	if filter.CategoryID == 0 {
+		return posts, nil
+	}
+
+	app.log(ctx).Debug("Filtering posts by category", "category_id", filter.CategoryID)
+
+	if app.categories == nil {
+		return nil, ErrCategoriesDisabled
+	}
+	tree, err := app.loadCategoryTree()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := tree[filter.CategoryID]; !ok {
+		return nil, ErrUnknownCategory
+	}
+
+	var result []models.Post
+	for _, post := range posts {
+		if tree.isDescendant(post.CategoryID, filter.CategoryID) {
+			result = append(result, post)
+		}
+	}
+
+	return result, nil
+}
+
+// checkCategory verifies a category referenced by ID exists, 0 references no category
+func (app *Application) checkCategory(id int64) error {
+	if id == 0 {
+		return nil
+	}
+	if app.categories == nil {
+		return ErrCategoriesDisabled
+	}
+
+	_, err := app.categories.GetByID(id)
+	if errors.Is(err, storage.ErrCategoryNotFound) {
+		return ErrUnknownCategory
+	}
+	return err
+}
+
+// withBreadcrumbs fills the category breadcrumb of categorized posts
+func (app *Application) withBreadcrumbs(posts []models.Post) ([]models.Post, error) {
+	if app.categories == nil {
+		return posts, nil
+	}
+
+	var tree categoryTree
+	for i := range posts {
+		if posts[i].CategoryID == 0 {
+			continue
+		}
+		if tree == nil {
+			var err error
+			if tree, err = app.loadCategoryTree(); err != nil {
+				return nil, err
+			}
+		}
+		posts[i].Breadcrumb = tree.breadcrumb(posts[i].CategoryID)
+	}
+
+	return posts, nil
+}
+
+func toStorageCategory(category models.Category) storage.Category {
+	return storage.Category{
+		ID:       category.ID,
+		Name:     category.Name,
+		ParentID: category.ParentID,
+	}
+}
+
+func toModelCategory(dbCategory storage.Category) *models.Category {
+	return &models.Category{
+		ID:       dbCategory.ID,
+		Name:     dbCategory.Name,
+		ParentID: dbCategory.ParentID,
+	}
+}
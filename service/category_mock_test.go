@@ -0,0 +1,36 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+	"rakia_blog_tt/storage"
+)
+
+// MockCategoryRepo is a mock implementation of the CategoryRepo interface
+type MockCategoryRepo struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepo) Create(category storage.Category) (storage.Category, error) {
+	args := m.Called(category)
+	return args.Get(0).(storage.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepo) GetAll() ([]storage.Category, error) {
+	args := m.Called()
+	return args.Get(0).([]storage.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepo) GetByID(id int64) (storage.Category, error) {
+	args := m.Called(id)
+	return args.Get(0).(storage.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepo) Update(category storage.Category) (storage.Category, error) {
+	args := m.Called(category)
+	return args.Get(0).(storage.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepo) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
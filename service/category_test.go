@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+// testCategories is Engineering > Backend > Go, plus a separate root Design
+var testCategories = []storage.Category{
+	{ID: 1, Name: "Engineering"},
+	{ID: 2, Name: "Backend", ParentID: 1},
+	{ID: 3, Name: "Go", ParentID: 2},
+	{ID: 4, Name: "Design"},
+}
+
+func TestApplication_UpdateCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		category models.Category
+		wantErr  error
+	}{
+		{name: "rename", category: models.Category{ID: 2, Name: "Back end", ParentID: 1}},
+		{name: "move to another root", category: models.Category{ID: 2, Name: "Backend", ParentID: 4}},
+		{name: "make root", category: models.Category{ID: 3, Name: "Go"}},
+		{name: "parent is itself", category: models.Category{ID: 2, Name: "Backend", ParentID: 2}, wantErr: ErrCategoryCycle},
+		{name: "parent is a child", category: models.Category{ID: 2, Name: "Backend", ParentID: 3}, wantErr: ErrCategoryCycle},
+		{name: "parent is a grandchild", category: models.Category{ID: 1, Name: "Engineering", ParentID: 3}, wantErr: ErrCategoryCycle},
+		{name: "unknown parent", category: models.Category{ID: 2, Name: "Backend", ParentID: 42}, wantErr: ErrUnknownCategory},
+		{name: "unknown category", category: models.Category{ID: 42, Name: "Missing"}, wantErr: ErrCategoryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCategories := new(MockCategoryRepo)
+			app := New(new(MockRepo), loggerMock(), WithCategoryRepo(mockCategories))
+
+			mockCategories.On("GetAll").Return(testCategories, nil)
+			if tt.wantErr == nil {
+				mockCategories.On("Update", toStorageCategory(tt.category)).Return(toStorageCategory(tt.category), nil)
+			}
+
+			updated, err := app.UpdateCategory(adminCtx(), tt.category)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockCategories.AssertNotCalled(t, "Update", mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.category, updated)
+			mockCategories.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_CreateCategory_UnknownParent(t *testing.T) {
+	mockCategories := new(MockCategoryRepo)
+	app := New(new(MockRepo), loggerMock(), WithCategoryRepo(mockCategories))
+
+	mockCategories.On("GetByID", int64(42)).Return(storage.Category{}, storage.ErrCategoryNotFound)
+
+	_, err := app.CreateCategory(adminCtx(), models.Category{Name: "Orphan", ParentID: 42})
+	assert.ErrorIs(t, err, ErrUnknownCategory)
+	mockCategories.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestApplication_DeleteCategory(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int64
+		posts   []storage.Post
+		wantErr error
+	}{
+		{name: "leaf without posts", id: 3},
+		{name: "has subcategories", id: 2, wantErr: ErrCategoryInUse},
+		{name: "has posts", id: 3, posts: []storage.Post{{ID: 1, CategoryID: 3}}, wantErr: ErrCategoryInUse},
+		{name: "unknown", id: 42, wantErr: ErrCategoryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			mockCategories := new(MockCategoryRepo)
+			app := New(mockRepo, loggerMock(), WithCategoryRepo(mockCategories))
+
+			mockCategories.On("GetAll").Return(testCategories, nil)
+			mockRepo.On("GetAll").Return(tt.posts, nil).Maybe()
+			mockCategories.On("Delete", tt.id).Return(nil).Maybe()
+
+			err := app.DeleteCategory(adminCtx(), tt.id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockCategories.AssertNotCalled(t, "Delete", mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			mockCategories.AssertCalled(t, "Delete", tt.id)
+		})
+	}
+}
+
+func TestApplication_FindPosts_Category(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockCategories := new(MockCategoryRepo)
+	app := New(mockRepo, loggerMock(), WithCategoryRepo(mockCategories))
+
+	mockCategories.On("GetAll").Return(testCategories, nil)
+	mockRepo.On("GetAll").Return([]storage.Post{
+		{ID: 1, CategoryID: 1},
+		{ID: 2, CategoryID: 3},
+		{ID: 3, CategoryID: 4},
+		{ID: 4},
+	}, nil)
+
+	ids := func(posts []models.Post) []int64 {
+		var result []int64
+		for _, post := range posts {
+			result = append(result, post.ID)
+		}
+		return result
+	}
+
+	posts, err := app.FindPosts(context.Background(), PostFilter{CategoryID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids(posts), "descendant categories are included")
+
+	posts, err = app.FindPosts(context.Background(), PostFilter{CategoryID: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids(posts))
+
+	posts, err = app.FindPosts(context.Background(), PostFilter{})
+	require.NoError(t, err)
+	assert.Len(t, posts, 4)
+
+	_, err = app.FindPosts(context.Background(), PostFilter{CategoryID: 42})
+	assert.ErrorIs(t, err, ErrUnknownCategory)
+}
+
+func TestApplication_GetPostByID_Breadcrumb(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockCategories := new(MockCategoryRepo)
+	app := New(mockRepo, loggerMock(), WithCategoryRepo(mockCategories))
+
+	mockCategories.On("GetAll").Return(testCategories, nil)
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1, CategoryID: 3}, nil)
+
+	post, err := app.GetPostByID(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Category{
+		{ID: 1, Name: "Engineering"},
+		{ID: 2, Name: "Backend", ParentID: 1},
+		{ID: 3, Name: "Go", ParentID: 2},
+	}, post.Breadcrumb)
+}
+
+func TestApplication_CreatePost_UnknownCategory(t *testing.T) {
+	mockRepo := new(MockRepo)
+
+	_, err := New(mockRepo, loggerMock()).CreatePost(adminCtx(), models.Post{Title: "Title", CategoryID: 1})
+	assert.ErrorIs(t, err, ErrCategoriesDisabled)
+
+	mockCategories := new(MockCategoryRepo)
+	mockCategories.On("GetByID", int64(1)).Return(storage.Category{}, storage.ErrCategoryNotFound)
+
+	_, err = New(mockRepo, loggerMock(), WithCategoryRepo(mockCategories)).CreatePost(adminCtx(), models.Post{Title: "Title", CategoryID: 1})
+	assert.ErrorIs(t, err, ErrUnknownCategory)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestApplication_CreatePost_CategoryDeletedMeanwhile(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockCategories := new(MockCategoryRepo)
+	app := New(mockRepo, loggerMock(), WithCategoryRepo(mockCategories))
+
+	stored := storage.Post{ID: 1, Title: "Title", Slug: "title", CategoryID: 3, Status: models.PostStatusDraft}
+	writing, release := make(chan struct{}), make(chan struct{})
+	mockCategories.On("GetByID", int64(3)).Return(testCategories[2], nil)
+	mockCategories.On("GetAll").Return(testCategories, nil)
+	mockRepo.On("Create", mock.Anything).Run(func(mock.Arguments) {
+		close(writing)
+		<-release
+	}).Return(stored, nil)
+	mockRepo.On("GetAll").Return([]storage.Post{stored}, nil)
+
+	created := make(chan error)
+	go func() {
+		_, err := app.CreatePost(adminCtx(), models.Post{Title: "Title", Author: "Author", CategoryID: 3})
+		created <- err
+	}()
+	<-writing
+
+	deleted := make(chan error)
+	go func() { deleted <- app.DeleteCategory(adminCtx(), 3) }()
+	select {
+	case err := <-deleted:
+		t.Fatalf("category deleted while a post was written to it: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-created)
+	assert.ErrorIs(t, <-deleted, ErrCategoryInUse)
+	mockCategories.AssertNotCalled(t, "Delete", mock.Anything)
+}
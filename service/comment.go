@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/moderation"
+	"rakia_blog_tt/storage"
+)
+
+var (
+	ErrCommentsDisabled     = errors.New("comments are not configured")
+	ErrCommentNotFound      = errors.New("comment not found")
+	ErrUnknownParentComment = errors.New("parent comment does not exist")
+	ErrNotCommentAuthor     = errors.New("comment belongs to another author")
+)
+
+// CommentRepo stores comments of posts
+type CommentRepo interface {
+	Create(comment storage.Comment) (storage.Comment, error)
+	GetByID(id int64) (storage.Comment, error)
+	GetByPost(postID int64) ([]storage.Comment, error)
+	GetByStatus(status string) ([]storage.Comment, error)
+	Update(comment storage.Comment) (storage.Comment, error)
+	Delete(id int64) error
+	DeleteByPost(postID int64) error
+}
+
+// WithCommentRepo enables comments on posts
+func WithCommentRepo(repo CommentRepo) Option {
+	return func(app *Application) {
+		app.comments = repo
+	}
+}
+
+// CommentPage is a page of comment threads of a post
+type CommentPage struct {
+	// Threads are top level approved comments, oldest first, with their approved replies nested
+	Threads []models.Comment
+	// Total is the number of top level approved comments of the post
+	Total int
+}
+
+// CreateComment adds a comment to the post, a non-zero ParentID makes it a reply to another comment of the post.
+// With a moderator configured the comment waits in the moderation queue, otherwise it is approved right away.
+// clientIP is the address the comment was sent from, it may be empty.
+func (app *Application) CreateComment(ctx context.Context, postID int64, comment models.Comment, clientIP string) (models.Comment, error) {
+	app.log(ctx).Debug("Creating a new comment", slog.Int64("post_id", postID))
+
+	p, err := app.authorize(ctx, ActionWriteComments)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if app.comments == nil {
+		return models.Comment{}, ErrCommentsDisabled
+	}
+	if _, err := app.getVisiblePost(ctx, int(postID)); err != nil {
+		return models.Comment{}, err
+	}
+
+	if comment.ParentID != 0 {
+		parent, err := app.comments.GetByID(comment.ParentID)
+		if errors.Is(err, storage.ErrCommentNotFound) || (err == nil && (parent.PostID != postID || parent.Deleted)) {
+			return models.Comment{}, ErrUnknownParentComment
+		}
+		if err != nil {
+			return models.Comment{}, err
+		}
+		if parent.Status != string(moderation.Approved) {
+			return models.Comment{}, ErrUnknownParentComment
+		}
+	}
+
+	dbComment := toStorageComment(comment)
+	dbComment.PostID = postID
+	dbComment.Owner = p.Subject
+	dbComment.Status = string(moderation.Approved)
+	if app.moderator != nil {
+		app.review(ctx, &dbComment, clientIP)
+	}
+
+	created, err := app.comments.Create(dbComment)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	// Flags are for moderators, they would tell spammers which checks to get past
+	created.Flags = nil
+	return *toModelComment(created), nil
+}
+
+// GetComments returns a page of public comment threads of the post. Pages are 1-based and count top level comments only,
+// every thread is returned with all of its replies. Only approved comments are public, replies to hidden comments are hidden too.
+func (app *Application) GetComments(ctx context.Context, postID int64, page, perPage int) (CommentPage, error) {
+	app.log(ctx).Debug("Retrieving comments", slog.Int64("post_id", postID), slog.Int("page", page))
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return CommentPage{}, err
+	}
+	if app.comments == nil {
+		return CommentPage{}, ErrCommentsDisabled
+	}
+	if _, err := app.getVisiblePost(ctx, int(postID)); err != nil {
+		return CommentPage{}, err
+	}
+
+	dbComments, err := app.comments.GetByPost(postID)
+	if err != nil {
+		return CommentPage{}, err
+	}
+
+	threads := commentThreads(publicComments(dbComments))
+	result := CommentPage{Threads: []models.Comment{}, Total: len(threads)}
+
+	start := (page - 1) * perPage
+	if start >= len(threads) {
+		return result, nil
+	}
+	end := start + perPage
+	if end > len(threads) {
+		end = len(threads)
+	}
+	for _, thread := range threads[start:end] {
+		result.Threads = append(result.Threads, *thread)
+	}
+
+	return result, nil
+}
+
+// UpdateComment changes the content of a comment. Only the principal who wrote the comment may edit it.
+// With a moderator configured the edited comment is reviewed again like a new one, so it leaves the public
+// comments until approved. clientIP is the address the edit was sent from, it may be empty.
+func (app *Application) UpdateComment(ctx context.Context, postID int64, comment models.Comment, clientIP string) (models.Comment, error) {
+	app.log(ctx).Debug("Updating comment", slog.Int64("post_id", postID), slog.Int64("comment_id", comment.ID))
+
+	stored, err := app.ownComment(ctx, postID, comment.ID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	stored.Content = comment.Content
+	if app.moderator != nil {
+		app.review(ctx, &stored, clientIP)
+	}
+	updated, err := app.comments.Update(stored)
+	if err != nil {
+		return models.Comment{}, mapStorageError(err)
+	}
+
+	updated.Flags = nil
+	return *toModelComment(updated), nil
+}
+
+// DeleteComment removes a comment of the principal. A comment with replies is replaced with a placeholder
+// so the replies stay in place, placeholders are removed once their last reply is gone.
+func (app *Application) DeleteComment(ctx context.Context, postID, id int64) error {
+	app.log(ctx).Debug("Deleting comment", slog.Int64("post_id", postID), slog.Int64("comment_id", id))
+
+	stored, err := app.ownComment(ctx, postID, id)
+	if err != nil {
+		return err
+	}
+
+	comments, err := app.comments.GetByPost(postID)
+	if err != nil {
+		return err
+	}
+	replies := make(map[int64]int)
+	for _, comment := range comments {
+		replies[comment.ParentID]++
+	}
+
+	if replies[id] > 0 {
+		stored.Deleted = true
+		stored.Author = ""
+		stored.Content = ""
+		_, err := app.comments.Update(stored)
+		return mapStorageError(err)
+	}
+
+	if err := app.comments.Delete(id); err != nil {
+		return mapStorageError(err)
+	}
+
+	// Walk up removing placeholders which were kept only for this reply
+	byID := make(map[int64]storage.Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+	replies[stored.ParentID]--
+	for parent, ok := byID[stored.ParentID]; ok && parent.Deleted && replies[parent.ID] == 0; parent, ok = byID[parent.ParentID] {
+		if err := app.comments.Delete(parent.ID); err != nil {
+			return mapStorageError(err)
+		}
+		replies[parent.ParentID]--
+	}
+
+	return nil
+}
+
+// review runs a new or edited comment through the moderator and sets its status and flags
+func (app *Application) review(ctx context.Context, comment *storage.Comment, clientIP string) {
+	result := app.moderator.Review(moderation.Submission{
+		Author:  comment.Author,
+		Content: comment.Content,
+		IP:      clientIP,
+		At:      time.Now(),
+	})
+	comment.Status = string(result.Status)
+	comment.Flags = result.Reasons
+	if result.Status == moderation.Spam {
+		app.log(ctx).Info("Comment flagged as spam", slog.Int64("post_id", comment.PostID), slog.Any("reasons", result.Reasons))
+	}
+}
+
+// ownComment returns the comment of the post if the principal of the request wrote it. Changing comments requires
+// authentication even where the policy lets anonymous clients write them, anonymous comments can't be changed.
+func (app *Application) ownComment(ctx context.Context, postID, id int64) (storage.Comment, error) {
+	if _, ok := auth.FromContext(ctx); !ok {
+		return storage.Comment{}, ErrUnauthenticated
+	}
+	p, err := app.authorize(ctx, ActionWriteComments)
+	if err != nil {
+		return storage.Comment{}, err
+	}
+	if app.comments == nil {
+		return storage.Comment{}, ErrCommentsDisabled
+	}
+
+	stored, err := app.comments.GetByID(id)
+	if err != nil {
+		return storage.Comment{}, mapStorageError(err)
+	}
+	if stored.PostID != postID || stored.Deleted {
+		return storage.Comment{}, ErrCommentNotFound
+	}
+	if stored.Owner == "" || stored.Owner != p.Subject {
+		return storage.Comment{}, ErrNotCommentAuthor
+	}
+
+	return stored, nil
+}
+
+// publicComments returns approved comments whose ancestors are approved too, without moderation details.
+// Comments are expected in creation order, so parents come before their replies.
+func publicComments(dbComments []storage.Comment) []storage.Comment {
+	visible := make(map[int64]bool, len(dbComments))
+	var result []storage.Comment
+	for _, comment := range dbComments {
+		if comment.Status != string(moderation.Approved) || (comment.ParentID != 0 && !visible[comment.ParentID]) {
+			continue
+		}
+		visible[comment.ID] = true
+		comment.Flags = nil
+		result = append(result, comment)
+	}
+	return result
+}
+
+// commentThreads nests replies into their parents and returns top level comments in the given order
+func commentThreads(dbComments []storage.Comment) []*models.Comment {
+	nodes := make(map[int64]*models.Comment, len(dbComments))
+	for _, dbComment := range dbComments {
+		nodes[dbComment.ID] = toModelComment(dbComment)
+	}
+
+	var threads []*models.Comment
+	for _, dbComment := range dbComments {
+		node := nodes[dbComment.ID]
+		if parent, ok := nodes[dbComment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			threads = append(threads, node)
+		}
+	}
+	return threads
+}
+
+func toStorageComment(comment models.Comment) storage.Comment {
+	return storage.Comment{
+		ID:       comment.ID,
+		ParentID: comment.ParentID,
+		Author:   comment.Author,
+		Content:  comment.Content,
+	}
+}
+
+func toModelComment(dbComment storage.Comment) *models.Comment {
+	return &models.Comment{
+		ID:        dbComment.ID,
+		PostID:    dbComment.PostID,
+		ParentID:  dbComment.ParentID,
+		Author:    dbComment.Author,
+		Content:   dbComment.Content,
+		Deleted:   dbComment.Deleted,
+		Status:    dbComment.Status,
+		Flags:     dbComment.Flags,
+		CreatedAt: strfmt.DateTime(dbComment.CreatedAt),
+		UpdatedAt: strfmt.DateTime(dbComment.UpdatedAt),
+	}
+}
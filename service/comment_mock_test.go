@@ -0,0 +1,57 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+	"rakia_blog_tt/moderation"
+	"rakia_blog_tt/storage"
+)
+
+// MockCommentRepo is a mock implementation of the CommentRepo interface
+type MockCommentRepo struct {
+	mock.Mock
+}
+
+func (m *MockCommentRepo) Create(comment storage.Comment) (storage.Comment, error) {
+	args := m.Called(comment)
+	return args.Get(0).(storage.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepo) GetByID(id int64) (storage.Comment, error) {
+	args := m.Called(id)
+	return args.Get(0).(storage.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepo) GetByPost(postID int64) ([]storage.Comment, error) {
+	args := m.Called(postID)
+	return args.Get(0).([]storage.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepo) GetByStatus(status string) ([]storage.Comment, error) {
+	args := m.Called(status)
+	return args.Get(0).([]storage.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepo) Update(comment storage.Comment) (storage.Comment, error) {
+	args := m.Called(comment)
+	return args.Get(0).(storage.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepo) Delete(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockCommentRepo) DeleteByPost(postID int64) error {
+	args := m.Called(postID)
+	return args.Error(0)
+}
+
+// MockModerator is a mock implementation of the CommentModerator interface
+type MockModerator struct {
+	mock.Mock
+}
+
+func (m *MockModerator) Review(sub moderation.Submission) moderation.Result {
+	args := m.Called(sub)
+	return args.Get(0).(moderation.Result)
+}
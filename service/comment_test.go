@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+// testComments of post 1:
+//
+//	1 Alice
+//	├── 2 Bob
+//	│   └── 3 Alice
+//	4 Carol
+//	5 Dave (deleted, kept for its reply)
+//	└── 6 Erin
+var testComments = []storage.Comment{
+	{ID: 1, PostID: 1, Author: "Alice", Owner: "alice", Content: "First", Status: "approved"},
+	{ID: 2, PostID: 1, ParentID: 1, Author: "Bob", Owner: "bob", Content: "Reply", Status: "approved"},
+	{ID: 3, PostID: 1, ParentID: 2, Author: "Alice", Owner: "alice", Content: "Reply to reply", Status: "approved"},
+	{ID: 4, PostID: 1, Author: "Carol", Content: "Second", Status: "approved"},
+	{ID: 5, PostID: 1, Deleted: true, Status: "approved"},
+	{ID: 6, PostID: 1, ParentID: 5, Author: "Erin", Owner: "erin", Content: "Orphan to be", Status: "approved"},
+}
+
+// commenter returns the context of a request of a reader with the subject
+func commenter(subject string) context.Context {
+	return auth.NewContext(context.Background(), auth.Principal{Subject: subject})
+}
+
+func newCommentTestApp() (*Application, *MockRepo, *MockCommentRepo) {
+	mockRepo := new(MockRepo)
+	mockComments := new(MockCommentRepo)
+	return New(mockRepo, loggerMock(), WithCommentRepo(mockComments)), mockRepo, mockComments
+}
+
+func TestApplication_GetComments(t *testing.T) {
+	app, mockRepo, mockComments := newCommentTestApp()
+
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1}, nil)
+	mockRepo.On("GetByID", 2).Return(storage.Post{}, storage.ErrPostNotFound)
+	mockComments.On("GetByPost", int64(1)).Return(testComments, nil)
+
+	page, err := app.GetComments(context.Background(), 1, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, page.Total)
+	require.Len(t, page.Threads, 2)
+	assert.Equal(t, int64(1), page.Threads[0].ID)
+	require.Len(t, page.Threads[0].Replies, 1)
+	require.Len(t, page.Threads[0].Replies[0].Replies, 1)
+	assert.Equal(t, int64(3), page.Threads[0].Replies[0].Replies[0].ID)
+	assert.Equal(t, int64(4), page.Threads[1].ID)
+
+	page, err = app.GetComments(context.Background(), 1, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Threads, 1)
+	assert.Equal(t, int64(5), page.Threads[0].ID)
+
+	page, err = app.GetComments(context.Background(), 1, 3, 2)
+	require.NoError(t, err)
+	assert.Empty(t, page.Threads)
+
+	_, err = app.GetComments(context.Background(), 2, 1, 2)
+	assert.ErrorIs(t, err, ErrPostNotFound)
+}
+
+func TestApplication_CreateComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		comment  models.Comment
+		parent   storage.Comment
+		parentOK bool
+		wantErr  error
+	}{
+		{name: "top level", comment: models.Comment{Author: "Alice", Content: "Hi"}},
+		{name: "reply", comment: models.Comment{ParentID: 1, Author: "Bob", Content: "Hi"}, parent: testComments[0], parentOK: true},
+		{name: "missing parent", comment: models.Comment{ParentID: 42, Author: "Bob", Content: "Hi"}, wantErr: ErrUnknownParentComment},
+		{
+			name:     "parent of another post",
+			comment:  models.Comment{ParentID: 7, Author: "Bob", Content: "Hi"},
+			parent:   storage.Comment{ID: 7, PostID: 2},
+			parentOK: true,
+			wantErr:  ErrUnknownParentComment,
+		},
+		{
+			name:     "pending parent",
+			comment:  models.Comment{ParentID: 8, Author: "Bob", Content: "Hi"},
+			parent:   storage.Comment{ID: 8, PostID: 1, Status: "pending"},
+			parentOK: true,
+			wantErr:  ErrUnknownParentComment,
+		},
+		{name: "deleted parent", comment: models.Comment{ParentID: 5, Author: "Bob", Content: "Hi"}, parent: testComments[4], parentOK: true, wantErr: ErrUnknownParentComment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockRepo, mockComments := newCommentTestApp()
+
+			mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1}, nil)
+			if tt.parentOK {
+				mockComments.On("GetByID", tt.comment.ParentID).Return(tt.parent, nil)
+			} else {
+				mockComments.On("GetByID", mock.Anything).Return(storage.Comment{}, storage.ErrCommentNotFound)
+			}
+			mockComments.On("Create", mock.Anything).Return(storage.Comment{ID: 10, PostID: 1, ParentID: tt.comment.ParentID}, nil).Maybe()
+
+			created, err := app.CreateComment(commenter("alice"), 1, tt.comment, "")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockComments.AssertNotCalled(t, "Create", mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), created.ID)
+			mockComments.AssertCalled(t, "Create", mock.MatchedBy(func(c storage.Comment) bool {
+				return c.PostID == 1 && c.Owner == "alice" && c.Status == "approved"
+			}))
+		})
+	}
+}
+
+func TestApplication_UpdateComment(t *testing.T) {
+	app, _, mockComments := newCommentTestApp()
+
+	mockComments.On("GetByID", int64(2)).Return(testComments[1], nil)
+	mockComments.On("GetByID", int64(4)).Return(testComments[3], nil)
+	mockComments.On("GetByID", int64(5)).Return(testComments[4], nil)
+	mockComments.On("Update", mock.MatchedBy(func(c storage.Comment) bool {
+		return c.ID == 2 && c.Author == "Bob" && c.Content == "Edited"
+	})).Return(storage.Comment{ID: 2, PostID: 1, Author: "Bob", Content: "Edited"}, nil)
+
+	updated, err := app.UpdateComment(commenter("bob"), 1, models.Comment{ID: 2, Author: "Mallory", Content: "Edited"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Edited", updated.Content)
+	assert.Equal(t, "Bob", updated.Author, "author can't be changed")
+
+	_, err = app.UpdateComment(commenter("mallory"), 1, models.Comment{ID: 2, Author: "Bob", Content: "Edited"}, "")
+	assert.ErrorIs(t, err, ErrNotCommentAuthor, "the author name doesn't matter")
+
+	_, err = app.UpdateComment(context.Background(), 1, models.Comment{ID: 2, Author: "Bob", Content: "Edited"}, "")
+	assert.ErrorIs(t, err, ErrUnauthenticated, "anonymous clients may write comments but not change them")
+
+	_, err = app.UpdateComment(commenter("carol"), 1, models.Comment{ID: 4, Content: "Edited"}, "")
+	assert.ErrorIs(t, err, ErrNotCommentAuthor, "anonymous comments can't be changed")
+
+	_, err = app.UpdateComment(commenter("bob"), 2, models.Comment{ID: 2, Content: "Edited"}, "")
+	assert.ErrorIs(t, err, ErrCommentNotFound, "comment of another post")
+
+	_, err = app.UpdateComment(commenter("bob"), 1, models.Comment{ID: 5, Content: "Edited"}, "")
+	assert.ErrorIs(t, err, ErrCommentNotFound, "deleted comment")
+}
+
+func TestApplication_DeleteComment(t *testing.T) {
+	t.Run("comment with replies becomes a placeholder", func(t *testing.T) {
+		app, _, mockComments := newCommentTestApp()
+
+		mockComments.On("GetByID", int64(2)).Return(testComments[1], nil)
+		mockComments.On("GetByPost", int64(1)).Return(testComments, nil)
+		mockComments.On("Update", storage.Comment{ID: 2, PostID: 1, ParentID: 1, Owner: "bob", Deleted: true, Status: "approved"}).Return(storage.Comment{}, nil)
+
+		require.NoError(t, app.DeleteComment(commenter("bob"), 1, 2))
+		mockComments.AssertNotCalled(t, "Delete", mock.Anything)
+		mockComments.AssertExpectations(t)
+	})
+
+	t.Run("leaf comment is removed", func(t *testing.T) {
+		app, _, mockComments := newCommentTestApp()
+
+		mockComments.On("GetByID", int64(3)).Return(testComments[2], nil)
+		mockComments.On("GetByPost", int64(1)).Return(testComments, nil)
+		mockComments.On("Delete", int64(3)).Return(nil)
+
+		require.NoError(t, app.DeleteComment(commenter("alice"), 1, 3))
+		mockComments.AssertNumberOfCalls(t, "Delete", 1)
+	})
+
+	t.Run("last reply removes the placeholder", func(t *testing.T) {
+		app, _, mockComments := newCommentTestApp()
+
+		mockComments.On("GetByID", int64(6)).Return(testComments[5], nil)
+		mockComments.On("GetByPost", int64(1)).Return(testComments, nil)
+		mockComments.On("Delete", int64(6)).Return(nil)
+		mockComments.On("Delete", int64(5)).Return(nil)
+
+		require.NoError(t, app.DeleteComment(commenter("erin"), 1, 6))
+		mockComments.AssertExpectations(t)
+	})
+
+	t.Run("only the author may delete", func(t *testing.T) {
+		app, _, mockComments := newCommentTestApp()
+
+		mockComments.On("GetByID", int64(3)).Return(testComments[2], nil)
+
+		assert.ErrorIs(t, app.DeleteComment(commenter("bob"), 1, 3), ErrNotCommentAuthor)
+		assert.ErrorIs(t, app.DeleteComment(context.Background(), 1, 3), ErrUnauthenticated)
+		mockComments.AssertNotCalled(t, "Delete", mock.Anything)
+	})
+}
+
+func TestApplication_DeletePost_DeletesComments(t *testing.T) {
+	app, mockRepo, mockComments := newCommentTestApp()
+
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1}, nil)
+	mockRepo.On("Delete", 1).Return(nil)
+	mockComments.On("DeleteByPost", int64(1)).Return(nil)
+
+	require.NoError(t, app.DeletePost(adminCtx(), 1))
+	mockComments.AssertExpectations(t)
+}
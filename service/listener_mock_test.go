@@ -0,0 +1,20 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"rakia_blog_tt/handler/models"
+)
+
+// MockListener is a mock implementation of the PostListener interface
+type MockListener struct {
+	mock.Mock
+}
+
+func (m *MockListener) PostSaved(post models.Post) {
+	m.Called(post)
+}
+
+func (m *MockListener) PostDeleted(id int64) {
+	m.Called(id)
+}
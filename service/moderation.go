@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/moderation"
+	"rakia_blog_tt/storage"
+)
+
+var ErrUnknownModerationStatus = errors.New("unknown moderation status")
+
+// CommentModerator reviews new comments before they are stored
+type CommentModerator interface {
+	Review(sub moderation.Submission) moderation.Result
+}
+
+// WithCommentModerator puts new comments into the moderation queue instead of publishing them right away
+func WithCommentModerator(moderator CommentModerator) Option {
+	return func(app *Application) {
+		app.moderator = moderator
+	}
+}
+
+// GetModerationQueue returns comments of every post with the moderation status, oldest first
+func (app *Application) GetModerationQueue(ctx context.Context, status moderation.Status) ([]models.Comment, error) {
+	app.log(ctx).Debug("Retrieving moderation queue", slog.String("status", string(status)))
+
+	if _, err := app.authorize(ctx, ActionModerateComments); err != nil {
+		return nil, err
+	}
+	if app.comments == nil {
+		return nil, ErrCommentsDisabled
+	}
+	if !status.Valid() {
+		return nil, ErrUnknownModerationStatus
+	}
+
+	dbComments, err := app.comments.GetByStatus(string(status))
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]models.Comment, 0, len(dbComments))
+	for _, dbComment := range dbComments {
+		comments = append(comments, *toModelComment(dbComment))
+	}
+
+	return comments, nil
+}
+
+// ModerateComments sets the moderation status of the comments. Missing comments don't stop the others from being updated,
+// they are reported in the result instead.
+func (app *Application) ModerateComments(ctx context.Context, ids []int64, status moderation.Status) (models.CommentModerationResult, error) {
+	app.log(ctx).Debug("Moderating comments", slog.Any("ids", ids), slog.String("status", string(status)))
+
+	result := models.CommentModerationResult{Updated: []int64{}, NotFound: []int64{}}
+	if _, err := app.authorize(ctx, ActionModerateComments); err != nil {
+		return result, err
+	}
+	if app.comments == nil {
+		return result, ErrCommentsDisabled
+	}
+	if !status.Valid() {
+		return result, ErrUnknownModerationStatus
+	}
+
+	for _, id := range ids {
+		stored, err := app.comments.GetByID(id)
+		if errors.Is(err, storage.ErrCommentNotFound) {
+			result.NotFound = append(result.NotFound, id)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		stored.Status = string(status)
+		if _, err := app.comments.Update(stored); err != nil {
+			return result, mapStorageError(err)
+		}
+		result.Updated = append(result.Updated, id)
+	}
+
+	return result, nil
+}
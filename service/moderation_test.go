@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/moderation"
+	"rakia_blog_tt/storage"
+)
+
+func TestApplication_CreateComment_Moderated(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     moderation.Result
+		wantStatus string
+	}{
+		{name: "clean comment waits for a moderator", result: moderation.Result{Status: moderation.Pending}, wantStatus: "pending"},
+		{
+			name:       "flagged comment is spam",
+			result:     moderation.Result{Status: moderation.Spam, Reasons: []string{"contains 3 links, at most 2 allowed"}},
+			wantStatus: "spam",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			mockComments := new(MockCommentRepo)
+			mockModerator := new(MockModerator)
+			app := New(mockRepo, loggerMock(), WithCommentRepo(mockComments), WithCommentModerator(mockModerator))
+
+			mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1}, nil)
+			mockModerator.On("Review", mock.MatchedBy(func(sub moderation.Submission) bool {
+				return sub.IP == "192.0.2.1" && sub.Content == "Hi" && !sub.At.IsZero()
+			})).Return(tt.result)
+			mockComments.On("Create", mock.MatchedBy(func(c storage.Comment) bool {
+				return c.Status == tt.wantStatus && assert.ObjectsAreEqual(tt.result.Reasons, c.Flags)
+			})).Return(storage.Comment{ID: 1, PostID: 1, Status: tt.wantStatus, Flags: tt.result.Reasons}, nil)
+
+			created, err := app.CreateComment(context.Background(), 1, models.Comment{Author: "Alice", Content: "Hi"}, "192.0.2.1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, created.Status)
+			assert.Empty(t, created.Flags, "spam checks aren't revealed to the submitter")
+			mockComments.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_GetComments_OnlyApproved(t *testing.T) {
+	app, mockRepo, mockComments := newCommentTestApp()
+
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1}, nil)
+	mockComments.On("GetByPost", int64(1)).Return([]storage.Comment{
+		{ID: 1, PostID: 1, Author: "Alice", Content: "Approved", Status: "approved", Flags: []string{"approved anyway"}},
+		{ID: 2, PostID: 1, Author: "Spammer", Content: "Spam", Status: "spam"},
+		{ID: 3, PostID: 1, Author: "Bob", Content: "Rejected", Status: "rejected"},
+		{ID: 4, PostID: 1, ParentID: 3, Author: "Carol", Content: "Reply to rejected", Status: "approved"},
+		{ID: 5, PostID: 1, ParentID: 1, Author: "Dave", Content: "Pending reply", Status: "pending"},
+	}, nil)
+
+	page, err := app.GetComments(context.Background(), 1, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+	require.Len(t, page.Threads, 1)
+	assert.Equal(t, int64(1), page.Threads[0].ID)
+	assert.Empty(t, page.Threads[0].Replies)
+	assert.Empty(t, page.Threads[0].Flags, "moderation details are not public")
+}
+
+func TestApplication_GetModerationQueue(t *testing.T) {
+	app, _, mockComments := newCommentTestApp()
+
+	mockComments.On("GetByStatus", "pending").Return([]storage.Comment{
+		{ID: 7, PostID: 2, Author: "Alice", Content: "Waiting", Status: "pending"},
+	}, nil)
+
+	queue, err := app.GetModerationQueue(adminCtx(), moderation.Pending)
+	require.NoError(t, err)
+	require.Len(t, queue, 1)
+	assert.Equal(t, int64(7), queue[0].ID)
+
+	_, err = app.GetModerationQueue(adminCtx(), "unknown")
+	assert.ErrorIs(t, err, ErrUnknownModerationStatus)
+}
+
+func TestApplication_ModerateComments(t *testing.T) {
+	app, _, mockComments := newCommentTestApp()
+
+	mockComments.On("GetByID", int64(1)).Return(storage.Comment{ID: 1, Status: "pending"}, nil)
+	mockComments.On("GetByID", int64(2)).Return(storage.Comment{ID: 2, Status: "spam"}, nil)
+	mockComments.On("GetByID", int64(3)).Return(storage.Comment{}, storage.ErrCommentNotFound)
+	mockComments.On("Update", mock.MatchedBy(func(c storage.Comment) bool { return c.Status == "approved" })).Return(storage.Comment{}, nil)
+
+	result, err := app.ModerateComments(adminCtx(), []int64{1, 2, 3}, moderation.Approved)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, result.Updated)
+	assert.Equal(t, []int64{3}, result.NotFound)
+	mockComments.AssertNumberOfCalls(t, "Update", 2)
+
+	_, err = app.ModerateComments(adminCtx(), []int64{1}, "deleted")
+	assert.ErrorIs(t, err, ErrUnknownModerationStatus)
+}
+
+func TestApplication_UpdateComment_Moderated(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockComments := new(MockCommentRepo)
+	mockModerator := new(MockModerator)
+	app := New(mockRepo, loggerMock(), WithCommentRepo(mockComments), WithCommentModerator(mockModerator))
+
+	reasons := []string{"contains 3 links, at most 2 allowed"}
+	mockComments.On("GetByID", int64(2)).Return(storage.Comment{
+		ID: 2, PostID: 1, Author: "Bob", Owner: "bob", Content: "Harmless", Status: "approved",
+	}, nil)
+	mockModerator.On("Review", mock.MatchedBy(func(sub moderation.Submission) bool {
+		return sub.IP == "192.0.2.1" && sub.Author == "Bob" && sub.Content == "Links" && !sub.At.IsZero()
+	})).Return(moderation.Result{Status: moderation.Spam, Reasons: reasons})
+	mockComments.On("Update", mock.MatchedBy(func(c storage.Comment) bool {
+		return c.Content == "Links" && c.Status == "spam" && assert.ObjectsAreEqual(reasons, c.Flags)
+	})).Return(storage.Comment{ID: 2, PostID: 1, Content: "Links", Status: "spam", Flags: reasons}, nil)
+
+	updated, err := app.UpdateComment(commenter("bob"), 1, models.Comment{ID: 2, Content: "Links"}, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, "spam", updated.Status, "an approved comment doesn't stay public after an edit")
+	assert.Empty(t, updated.Flags)
+	mockComments.AssertExpectations(t)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"rakia_blog_tt/auth"
+)
+
+// Action is an operation the authorization policy grants to roles
+type Action string
+
+const (
+	// ActionReadPosts covers reading posts with everything shown along them: tags, categories, authors and comments
+	ActionReadPosts     Action = "posts.read"
+	ActionCreatePost    Action = "posts.create"
+	ActionEditOwnPost   Action = "posts.edit_own"
+	ActionEditAnyPost   Action = "posts.edit_any"
+	ActionDeleteOwnPost Action = "posts.delete_own"
+	ActionDeleteAnyPost Action = "posts.delete_any"
+	// ActionPublishPost allows making posts public, other writers may only prepare them
+	ActionPublishPost Action = "posts.publish"
+	// ActionWriteComments covers posting comments and changing own ones
+	ActionWriteComments    Action = "comments.write"
+	ActionModerateComments Action = "comments.moderate"
+	ActionManageCategories Action = "categories.manage"
+	ActionManageAuthors    Action = "authors.manage"
+	ActionManageAPIKeys    Action = "api_keys.manage"
+	// ActionManageUsers covers the accounts of other users, everyone may manage their own
+	ActionManageUsers Action = "users.manage"
+)
+
+// Actions lists every action known to policies
+var Actions = []Action{
+	ActionReadPosts, ActionCreatePost, ActionEditOwnPost, ActionEditAnyPost, ActionDeleteOwnPost, ActionDeleteAnyPost,
+	ActionPublishPost, ActionWriteComments, ActionModerateComments, ActionManageCategories, ActionManageAuthors,
+	ActionManageAPIKeys, ActionManageUsers,
+}
+
+// actionWildcard grants every action
+const actionWildcard = "*"
+
+//go:embed policy.yaml
+var defaultPolicy []byte
+
+// Policy is the permission matrix telling which roles may perform which actions
+type Policy struct {
+	grants map[string]map[Action]bool // role -> granted actions
+}
+
+// policyFile is the layout of a policy file, see policy.yaml
+type policyFile struct {
+	Roles map[string][]string `yaml:"roles"`
+}
+
+// DefaultPolicy returns the built-in policy of policy.yaml
+func DefaultPolicy() *Policy {
+	policy, err := LoadPolicy(defaultPolicy)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// LoadPolicy parses a YAML or JSON policy. Unknown actions are refused, so a typo doesn't silently deny access.
+func LoadPolicy(data []byte) (*Policy, error) {
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "decode policy")
+	}
+	if len(file.Roles) == 0 {
+		return nil, errors.New("policy grants no roles")
+	}
+
+	known := make(map[Action]bool, len(Actions))
+	for _, action := range Actions {
+		known[action] = true
+	}
+
+	policy := &Policy{grants: make(map[string]map[Action]bool, len(file.Roles))}
+	for role, actions := range file.Roles {
+		granted := make(map[Action]bool, len(actions))
+		for _, name := range actions {
+			if name == actionWildcard {
+				for _, action := range Actions {
+					granted[action] = true
+				}
+				continue
+			}
+			if !known[Action(name)] {
+				return nil, fmt.Errorf("role %q: unknown action %q", role, name)
+			}
+			granted[Action(name)] = true
+		}
+		policy.grants[role] = granted
+	}
+	return policy, nil
+}
+
+// Allowed reports whether any of the roles grants the action
+func (p *Policy) Allowed(roles []string, action Action) bool {
+	for _, role := range roles {
+		if p.grants[role][action] {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPolicy replaces the default authorization policy
+func WithPolicy(policy *Policy) Option {
+	return func(app *Application) {
+		app.policy = policy
+	}
+}
+
+// rolesOf returns the roles the policy is consulted with, requests without a principal are anonymous
+func rolesOf(p auth.Principal, authenticated bool) []string {
+	switch {
+	case !authenticated:
+		return []string{auth.RoleAnonymous}
+	case len(p.Roles) == 0:
+		return []string{auth.RoleReader}
+	default:
+		return p.Roles
+	}
+}
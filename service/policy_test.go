@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/moderation"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	tests := []struct {
+		role    string
+		granted []Action
+	}{
+		{role: auth.RoleAdmin, granted: Actions},
+		{role: auth.RoleEditor, granted: []Action{
+			ActionReadPosts, ActionCreatePost, ActionEditOwnPost, ActionEditAnyPost, ActionDeleteOwnPost, ActionPublishPost,
+			ActionWriteComments, ActionModerateComments, ActionManageCategories, ActionManageAuthors,
+		}},
+		{role: auth.RoleAuthor, granted: []Action{
+			ActionReadPosts, ActionCreatePost, ActionEditOwnPost, ActionDeleteOwnPost, ActionWriteComments,
+		}},
+		{role: auth.RoleReader, granted: []Action{ActionReadPosts, ActionWriteComments}},
+		{role: auth.RoleAnonymous, granted: []Action{ActionReadPosts, ActionWriteComments}},
+		{role: "unknown"},
+	}
+
+	policy := DefaultPolicy()
+	for _, tt := range tests {
+		granted := make(map[Action]bool, len(tt.granted))
+		for _, action := range tt.granted {
+			granted[action] = true
+		}
+		for _, action := range Actions {
+			t.Run(tt.role+"/"+string(action), func(t *testing.T) {
+				assert.Equal(t, granted[action], policy.Allowed([]string{tt.role}, action))
+			})
+		}
+	}
+}
+
+func TestPolicy_AllowedAnyRole(t *testing.T) {
+	policy := DefaultPolicy()
+
+	assert.True(t, policy.Allowed([]string{auth.RoleReader, auth.RoleEditor}, ActionModerateComments))
+	assert.False(t, policy.Allowed([]string{auth.RoleReader, auth.RoleAuthor}, ActionModerateComments))
+	assert.False(t, policy.Allowed(nil, ActionReadPosts))
+}
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := LoadPolicy([]byte(`
+roles:
+  admin: ["*"]
+  reader: []
+  anonymous: [posts.read]
+`))
+	require.NoError(t, err)
+	assert.True(t, policy.Allowed([]string{auth.RoleAdmin}, ActionManageAPIKeys))
+	assert.False(t, policy.Allowed([]string{auth.RoleReader}, ActionReadPosts))
+	assert.True(t, policy.Allowed([]string{auth.RoleAnonymous}, ActionReadPosts))
+	assert.False(t, policy.Allowed([]string{auth.RoleAnonymous}, ActionWriteComments))
+
+	policy, err = LoadPolicy([]byte(`{"roles": {"author": ["posts.create"]}}`))
+	require.NoError(t, err, "JSON is valid YAML")
+	assert.True(t, policy.Allowed([]string{auth.RoleAuthor}, ActionCreatePost))
+}
+
+func TestLoadPolicy_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"malformed":      `roles: [`,
+		"no roles":       `roles: {}`,
+		"unknown action": `roles: {author: [posts.craete]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadPolicy([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApplication_ConsultsPolicy(t *testing.T) {
+	policy, err := LoadPolicy([]byte(`roles: {admin: ["*"], reader: [posts.read]}`))
+	require.NoError(t, err)
+	app := New(new(MockRepo), loggerMock(), WithPolicy(policy), WithCommentRepo(new(MockCommentRepo)))
+	reader := auth.NewContext(context.Background(), auth.Principal{Subject: "reader", Roles: []string{auth.RoleReader}})
+
+	_, err = app.GetPosts(context.Background())
+	assert.ErrorIs(t, err, ErrUnauthenticated, "anonymous clients may read no more")
+
+	_, err = app.CreateComment(reader, 1, models.Comment{Author: "Reader", Content: "Hello"}, "")
+	var denied *PermissionError
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, ActionWriteComments, denied.Action)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = app.GetModerationQueue(reader, moderation.Pending)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
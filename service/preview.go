@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var (
+	ErrPreviewsDisabled = errors.New("preview links are not configured")
+	ErrInvalidPreview   = errors.New("invalid or revoked preview link")
+	ErrPreviewExpired   = errors.New("preview link expired")
+	ErrPreviewTooLong   = errors.New("preview link outlives the maximum lifetime")
+)
+
+const (
+	// DefaultPreviewTTL is how long preview links last unless an expiry is asked for
+	DefaultPreviewTTL = 7 * 24 * time.Hour
+	// MaxPreviewTTL limits how far in the future preview links may expire
+	MaxPreviewTTL = 30 * 24 * time.Hour
+)
+
+// WithPreviewSecret enables preview links of posts signed with the secret. Changing the secret revokes every link.
+func WithPreviewSecret(secret []byte) Option {
+	return func(app *Application) {
+		app.previewSecret = secret
+	}
+}
+
+// WithPreviewTTL sets how long preview links last unless an expiry is asked for, at most MaxPreviewTTL
+func WithPreviewTTL(ttl time.Duration) Option {
+	return func(app *Application) {
+		app.previewTTL = min(ttl, MaxPreviewTTL)
+	}
+}
+
+// CreatePreview mints a link showing the post whatever its status to anyone having it, until expiresAt or the
+// default preview lifetime when nil. Minting needs the right to edit the post. The token is signed, not stored.
+func (app *Application) CreatePreview(ctx context.Context, id int, expiresAt *time.Time) (models.Preview, error) {
+	app.log(ctx).Debug("Creating a preview link", "post_id", id)
+
+	if err := app.authorizeEither(ctx, ActionEditOwnPost, ActionEditAnyPost); err != nil {
+		return models.Preview{}, err
+	}
+	if app.previewSecret == nil {
+		return models.Preview{}, ErrPreviewsDisabled
+	}
+
+	now := time.Now().UTC()
+	expiry := now.Add(app.previewTTL)
+	if expiresAt != nil {
+		expiry = expiresAt.UTC()
+	}
+	if !expiry.After(now) {
+		return models.Preview{}, ErrExpiryInPast
+	}
+	if expiry.After(now.Add(MaxPreviewTTL)) {
+		return models.Preview{}, ErrPreviewTooLong
+	}
+	// The token carries whole seconds only
+	expiry = expiry.Truncate(time.Second)
+
+	app.previewMu.Lock()
+	defer app.previewMu.Unlock()
+
+	post, err := app.repository.GetByID(ctx, id)
+	if err != nil {
+		return models.Preview{}, mapStorageError(err)
+	}
+	if err := app.authorizePost(ctx, post, ActionEditOwnPost, ActionEditAnyPost); err != nil {
+		return models.Preview{}, err
+	}
+
+	nonce := post.PreviewNonce
+	if nonce == "" {
+		if nonce, err = app.rotatePreviewNonce(ctx, id); err != nil {
+			return models.Preview{}, err
+		}
+	}
+
+	token := auth.SignPreview(app.previewSecret, auth.PreviewToken{PostID: post.ID, ExpiresAt: expiry}, nonce)
+	app.log(ctx).Info("Preview link created", slog.Int64("post_id", post.ID), slog.Time("expires_at", expiry))
+
+	expires := strfmt.DateTime(expiry)
+	return models.Preview{PostID: post.ID, Token: token, ExpiresAt: &expires}, nil
+}
+
+// RevokePreviews invalidates every preview link of the post minted so far, it needs the right to edit the post
+func (app *Application) RevokePreviews(ctx context.Context, id int) error {
+	app.log(ctx).Debug("Revoking preview links", "post_id", id)
+
+	if err := app.authorizeEither(ctx, ActionEditOwnPost, ActionEditAnyPost); err != nil {
+		return err
+	}
+	if app.previewSecret == nil {
+		return ErrPreviewsDisabled
+	}
+
+	app.previewMu.Lock()
+	defer app.previewMu.Unlock()
+
+	post, err := app.repository.GetByID(ctx, id)
+	if err != nil {
+		return mapStorageError(err)
+	}
+	if err := app.authorizePost(ctx, post, ActionEditOwnPost, ActionEditAnyPost); err != nil {
+		return err
+	}
+
+	if _, err := app.rotatePreviewNonce(ctx, id); err != nil {
+		return err
+	}
+	app.log(ctx).Info("Preview links revoked", slog.Int("post_id", id))
+	return nil
+}
+
+// PreviewPost returns the post of a preview link whatever its status. The link is the credential, so the
+// principal of the request doesn't matter. Links of deleted posts, with a bad signature or revoked are
+// ErrInvalidPreview, valid ones past their expiry ErrPreviewExpired.
+func (app *Application) PreviewPost(ctx context.Context, token string) (models.Post, error) {
+	if app.previewSecret == nil {
+		return models.Post{}, ErrPreviewsDisabled
+	}
+
+	preview, err := auth.ParsePreview(token)
+	if err != nil {
+		return models.Post{}, ErrInvalidPreview
+	}
+	post, err := app.repository.GetByID(ctx, int(preview.PostID))
+	if err != nil {
+		if errors.Is(err, storage.ErrPostNotFound) {
+			return models.Post{}, ErrInvalidPreview
+		}
+		return models.Post{}, err
+	}
+	if !auth.VerifyPreview(app.previewSecret, token, post.PreviewNonce) {
+		return models.Post{}, ErrInvalidPreview
+	}
+	if !time.Now().Before(preview.ExpiresAt) {
+		return models.Post{}, ErrPreviewExpired
+	}
+
+	app.log(ctx).Debug("Previewing post", "post_id", post.ID)
+	return app.withReference(toModelPost(post))
+}
+
+// rotatePreviewNonce gives the post a new preview nonce and returns it. Must be called with previewMu held.
+func (app *Application) rotatePreviewNonce(ctx context.Context, id int) (string, error) {
+	nonce, err := auth.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	if err := app.repository.SetPreviewNonce(ctx, id, nonce); err != nil {
+		return "", mapStorageError(err)
+	}
+	return nonce, nil
+}
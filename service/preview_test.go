@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var previewSecret = []byte("preview-secret")
+
+func TestApplication_CreatePreview(t *testing.T) {
+	draft := storage.Post{ID: 1, Title: "Draft", Author: "Jane Doe", AuthorID: 1, Status: models.PostStatusDraft}
+
+	t.Run("Preview of a draft", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		app := New(mockRepo, loggerMock(), WithPreviewSecret(previewSecret))
+		mockRepo.On("GetByID", 1).Return(draft, nil).Once()
+		var nonce string
+		mockRepo.On("SetPreviewNonce", 1, mock.Anything).Run(func(args mock.Arguments) {
+			nonce = args.String(1)
+		}).Return(nil).Once()
+
+		ctx := auth.NewContext(context.Background(), jane)
+		preview, err := app.CreatePreview(ctx, 1, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), preview.PostID)
+		require.NotNil(t, preview.ExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(DefaultPreviewTTL), time.Time(*preview.ExpiresAt), time.Minute)
+
+		withNonce := draft
+		withNonce.PreviewNonce = nonce
+		mockRepo.On("GetByID", 1).Return(withNonce, nil)
+		post, err := app.PreviewPost(context.Background(), preview.Token)
+		require.NoError(t, err)
+		assert.Equal(t, "Draft", post.Title)
+		assert.Equal(t, models.PostStatusDraft, post.Status)
+
+		_, err = app.CreatePreview(ctx, 1, nil)
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t) // the nonce is created once
+	})
+
+	t.Run("Only those editing the post", func(t *testing.T) {
+		mockRepo := new(MockRepo)
+		app := New(mockRepo, loggerMock(), WithPreviewSecret(previewSecret))
+		mockRepo.On("GetByID", 1).Return(draft, nil)
+
+		_, err := app.CreatePreview(auth.NewContext(context.Background(), john), 1, nil)
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = app.CreatePreview(context.Background(), 1, nil)
+		assert.ErrorIs(t, err, ErrUnauthenticated)
+		err = app.RevokePreviews(auth.NewContext(context.Background(), john), 1)
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockRepo.AssertNotCalled(t, "SetPreviewNonce", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		app := New(new(MockRepo), loggerMock(), WithPreviewSecret(previewSecret))
+
+		past := time.Now().Add(-time.Minute)
+		_, err := app.CreatePreview(adminCtx(), 1, &past)
+		assert.ErrorIs(t, err, ErrExpiryInPast)
+		distant := time.Now().Add(MaxPreviewTTL + time.Hour)
+		_, err = app.CreatePreview(adminCtx(), 1, &distant)
+		assert.ErrorIs(t, err, ErrPreviewTooLong)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		app := New(new(MockRepo), loggerMock())
+		_, err := app.CreatePreview(adminCtx(), 1, nil)
+		assert.ErrorIs(t, err, ErrPreviewsDisabled)
+		_, err = app.PreviewPost(context.Background(), "1.2.3")
+		assert.ErrorIs(t, err, ErrPreviewsDisabled)
+	})
+}
+
+func TestApplication_PreviewPost(t *testing.T) {
+	post := storage.Post{ID: 1, Title: "Draft", Status: models.PostStatusDraft, PreviewNonce: "nonce"}
+	sign := func(expiresAt time.Time, nonce string) string {
+		return auth.SignPreview(previewSecret, auth.PreviewToken{PostID: 1, ExpiresAt: expiresAt}, nonce)
+	}
+
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock(), WithPreviewSecret(previewSecret))
+	mockRepo.On("GetByID", 1).Return(post, nil)
+	mockRepo.On("GetByID", 2).Return(storage.Post{}, storage.ErrPostNotFound)
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	_, err := app.PreviewPost(context.Background(), sign(tomorrow, "nonce"))
+	assert.NoError(t, err)
+
+	_, err = app.PreviewPost(context.Background(), sign(time.Now().Add(-time.Minute), "nonce"))
+	assert.ErrorIs(t, err, ErrPreviewExpired)
+
+	for name, token := range map[string]string{
+		"revoked":      sign(tomorrow, "rotated"),
+		"other secret": auth.SignPreview([]byte("other"), auth.PreviewToken{PostID: 1, ExpiresAt: tomorrow}, "nonce"),
+		"deleted post": auth.SignPreview(previewSecret, auth.PreviewToken{PostID: 2, ExpiresAt: tomorrow}, "nonce"),
+		"malformed":    "not-a-token",
+	} {
+		_, err := app.PreviewPost(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidPreview, name)
+	}
+}
+
+func TestApplication_RevokePreviews(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock(), WithPreviewSecret(previewSecret))
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1, AuthorID: 1, PreviewNonce: "nonce"}, nil)
+	mockRepo.On("SetPreviewNonce", 1, mock.MatchedBy(func(nonce string) bool { return nonce != "nonce" && nonce != "" })).Return(nil)
+
+	require.NoError(t, app.RevokePreviews(auth.NewContext(context.Background(), editor), 1))
+	mockRepo.AssertExpectations(t)
+}
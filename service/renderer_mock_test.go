@@ -0,0 +1,15 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRenderer is a mock implementation of the ContentRenderer interface
+type MockRenderer struct {
+	mock.Mock
+}
+
+func (m *MockRenderer) Render(id, version int64, content string) (string, error) {
+	args := m.Called(id, version, content)
+	return args.String(0), args.Error(1)
+}
@@ -1,101 +1,528 @@
 package service
 
 import (
+	"context"
 	"log/slog"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/go-openapi/strfmt"
 	"github.com/pkg/errors"
 
 	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/logging"
+	"rakia_blog_tt/slug"
 	"rakia_blog_tt/storage"
 )
 
-var ErrPostNotFound = errors.New("post not found")
+var (
+	ErrPostNotFound      = errors.New("post not found")
+	ErrRenderingDisabled = errors.New("content rendering disabled")
+	ErrSlugTaken         = errors.New("slug is used by another post")
+	ErrPostChanged       = errors.New("post was changed since it was read")
+)
 
-func New(repo Repo, logger *slog.Logger) *Application {
-	return &Application{
+func New(repo Repo, logger *slog.Logger, opts ...Option) *Application {
+	app := &Application{
 		repository: repo,
+		policy:     DefaultPolicy(),
 		logger:     logger,
+		sessionTTL: defaultSessionTTL,
+		previewTTL: DefaultPreviewTTL,
+		throttle:   newLoginThrottle(defaultMaxFailedLogins, defaultLoginWindow),
+	}
+	for _, opt := range opts {
+		opt(app)
 	}
+	return app
 }
 
 type Application struct {
 	repository Repo
+	categories CategoryRepo
+	comments   CommentRepo
+	authors    AuthorRepo
+	apiKeys    APIKeyRepo
+	users      UserRepo
+	sessions   SessionRepo
+	moderator  CommentModerator
+	renderer   ContentRenderer
+	listeners  []PostListener
+	policy     *Policy
 	logger     *slog.Logger
+
+	sessionTTL time.Duration
+	throttle   *loginThrottle
+
+	previewSecret []byte
+	previewTTL    time.Duration
+	// previewMu serializes preview nonce changes, so concurrently minted links can't revoke each other
+	previewMu sync.Mutex
+
+	// categoryMu serializes category tree changes and writes of categorized posts, so concurrent moves can't create
+	// a cycle and posts can't be written to a category while it's deleted
+	categoryMu sync.Mutex
+}
+
+// log returns the logger of the request handled in ctx, lines carry its ID
+func (app *Application) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, app.logger)
+}
+
+// Option configures optional Application dependencies
+type Option func(app *Application)
+
+// WithContentRenderer enables rendering of post content into HTML
+func WithContentRenderer(renderer ContentRenderer) Option {
+	return func(app *Application) {
+		app.renderer = renderer
+	}
+}
+
+// WithPostListener subscribes the listener to post changes
+func WithPostListener(listener PostListener) Option {
+	return func(app *Application) {
+		app.listeners = append(app.listeners, listener)
+	}
 }
 
 // Repo interface
 // Put interface in the place where we use it, to avoid unnecessary dependencies
 type Repo interface {
-	Create(post storage.Post) error
-	GetAll() ([]storage.Post, error)
-	GetByID(id int) (storage.Post, error)
-	Update(post storage.Post) error
-	Delete(id int) error
+	Create(ctx context.Context, post storage.Post) (storage.Post, error)
+	GetAll(ctx context.Context) ([]storage.Post, error)
+	GetByID(ctx context.Context, id int) (storage.Post, error)
+	GetBySlug(ctx context.Context, slug string) (storage.Post, error)
+	GetByTags(ctx context.Context, tags []string, matchAll bool) ([]storage.Post, error)
+	Update(ctx context.Context, post storage.Post) (storage.Post, error)
+	SetPreviewNonce(ctx context.Context, id int, nonce string) error
+	Delete(ctx context.Context, id int) error
 }
 
-func (app *Application) CreatePost(post models.Post) error {
-	dbPost := storage.Post{
-		ID:      post.ID,
-		Title:   post.Title,
-		Content: post.Content,
-		Author:  post.Author,
+// ContentRenderer converts post content into sanitized HTML.
+// Post ID and version allow implementations to cache results.
+type ContentRenderer interface {
+	Render(id, version int64, content string) (string, error)
+}
+
+// PostListener is notified synchronously after a post is stored or deleted,
+// so it must not block. Posts which are not published are reported as deleted, listeners only see public posts.
+type PostListener interface {
+	PostSaved(post models.Post)
+	PostDeleted(id int64)
+}
+
+// CreatePost stores the post and returns it with the generated fields.
+// The slug is generated from the title unless given, a suffix is appended when it's already taken.
+// Posts of other authors need ActionEditAnyPost besides ActionCreatePost. New posts are drafts unless another
+// status is given, creating scheduled or published posts needs ActionPublishPost.
+func (app *Application) CreatePost(ctx context.Context, post models.Post) (models.Post, error) {
+	app.log(ctx).Debug("Creating a new post")
+
+	if _, err := app.authorize(ctx, ActionCreatePost); err != nil {
+		return models.Post{}, err
+	}
+	if post.CategoryID != 0 {
+		app.categoryMu.Lock()
+		defer app.categoryMu.Unlock()
+	}
+	if err := app.checkCategory(post.CategoryID); err != nil {
+		return models.Post{}, err
+	}
+
+	dbPost := toStoragePost(post)
+	if err := app.resolveOwnedAuthor(ctx, &dbPost, ActionCreatePost); err != nil {
+		return models.Post{}, err
+	}
+	if err := app.applyStatus(ctx, &dbPost, nil, time.Now().UTC()); err != nil {
+		return models.Post{}, err
+	}
+	if dbPost.Slug == "" {
+		dbPost.Slug = slug.Make(post.Title)
 	}
 
-	app.logger.Debug("Creating a new post")
+	created, err := app.repository.Create(ctx, dbPost)
+	if err != nil {
+		return models.Post{}, err
+	}
 
-	return app.repository.Create(dbPost)
+	app.notifySaved(created)
+	return app.withReference(toModelPost(created))
 }
 
-func (app *Application) GetPosts() ([]models.Post, error) {
-	app.logger.Debug("Retrieving all posts")
+// GetPosts returns the posts the principal may see, published ones and those it may edit
+func (app *Application) GetPosts(ctx context.Context) ([]models.Post, error) {
+	app.log(ctx).Debug("Retrieving all posts")
 
-	dbPosts, err := app.repository.GetAll()
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return nil, err
+	}
+
+	dbPosts, err := app.repository.GetAll(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	var posts []models.Post
 	for _, dbPost := range dbPosts {
-		posts = append(posts, models.Post{
-			ID:      dbPost.ID,
-			Title:   dbPost.Title,
-			Content: dbPost.Content,
-			Author:  dbPost.Author,
-		})
+		if app.visible(ctx, dbPost) {
+			posts = append(posts, toModelPost(dbPost))
+		}
 	}
 
-	return posts, nil
+	return app.withReferences(posts)
 }
 
-func (app *Application) GetPostByID(id int) (models.Post, error) {
-	app.logger.Debug("Retrieving post by ID", slog.Int("id", id))
+// GetPostsByAuthor returns posts written by the author
+func (app *Application) GetPostsByAuthor(ctx context.Context, author string) ([]models.Post, error) {
+	app.log(ctx).Debug("Retrieving posts by author", "author", author)
 
-	dbPost, err := app.repository.GetByID(id)
-	if errors.Is(err, storage.ErrPostNotFound) {
-		return models.Post{}, ErrPostNotFound
+	posts, err := app.GetPosts(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return models.Post{
-		ID:      dbPost.ID,
-		Title:   dbPost.Title,
-		Content: dbPost.Content,
-		Author:  dbPost.Author,
-	}, nil
+	var result []models.Post
+	for _, post := range posts {
+		if post.Author == author {
+			result = append(result, post)
+		}
+	}
+
+	return result, nil
 }
 
-func (app *Application) UpdatePost(post models.Post) error {
+// GetPostsByTags returns posts having any of the tags, or all of them when matchAll is set.
+// Tags are normalized the same way as on save, so "Go" finds posts tagged "go".
+func (app *Application) GetPostsByTags(ctx context.Context, tags []string, matchAll bool) ([]models.Post, error) {
+	app.log(ctx).Debug("Retrieving posts by tags", "tags", tags, "match_all", matchAll)
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return nil, err
+	}
+
+	tags = normalizeTags(tags)
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	dbPosts, err := app.repository.GetByTags(ctx, tags, matchAll)
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []models.Post
+	for _, dbPost := range dbPosts {
+		if app.visible(ctx, dbPost) {
+			posts = append(posts, toModelPost(dbPost))
+		}
+	}
+
+	return app.withReferences(posts)
+}
+
+// GetTags returns tags in use with their post counts, most used first. Only posts the principal may see are counted,
+// so tags of unpublished posts stay hidden from others.
+func (app *Application) GetTags(ctx context.Context) ([]models.Tag, error) {
+	app.log(ctx).Debug("Retrieving tags")
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return nil, err
+	}
+
+	posts, err := app.repository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, post := range posts {
+		if !app.visible(ctx, post) {
+			continue
+		}
+		for _, tag := range post.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]models.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, models.Tag{Name: name, PostCount: int64(count)})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+func (app *Application) GetPostByID(ctx context.Context, id int) (models.Post, error) {
+	app.log(ctx).Debug("Retrieving post by ID", slog.Int("id", id))
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return models.Post{}, err
+	}
+
+	dbPost, err := app.getVisiblePost(ctx, id)
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	return app.withReference(toModelPost(dbPost))
+}
+
+// GetPostBySlug returns the post by its current or a former slug.
+// moved is true for a former slug, post.Slug holds the current one then.
+func (app *Application) GetPostBySlug(ctx context.Context, postSlug string) (post models.Post, moved bool, err error) {
+	app.log(ctx).Debug("Retrieving post by slug", "slug", postSlug)
+
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return models.Post{}, false, err
+	}
+
+	dbPost, err := app.repository.GetBySlug(ctx, postSlug)
+	if err != nil {
+		return models.Post{}, false, mapStorageError(err)
+	}
+	if !app.visible(ctx, dbPost) {
+		return models.Post{}, false, ErrPostNotFound
+	}
+
+	post, err = app.withReference(toModelPost(dbPost))
+	return post, dbPost.Slug != postSlug, err
+}
+
+// UpdatePost stores the new state of the post. A slug different from the stored one is taken as an explicit change
+// and must not be used by other posts, otherwise the slug is regenerated when the title changes.
+// Own posts need ActionEditOwnPost, posts of others and handing a post over to another author ActionEditAnyPost.
+// Status changes follow the workflow, an empty status keeps the stored one. A version other than 0 is the one
+// the post was read at, 0 stands for the stored one, ErrPostChanged is returned when it was changed since.
+func (app *Application) UpdatePost(ctx context.Context, post models.Post) error {
+	app.log(ctx).Debug("Updating post", "post_id", post.ID)
+
+	if err := app.authorizeEither(ctx, ActionEditOwnPost, ActionEditAnyPost); err != nil {
+		return err
+	}
+	stored, err := app.repository.GetByID(ctx, int(post.ID))
+	if err != nil {
+		return mapStorageError(err)
+	}
+	if err := app.authorizePost(ctx, stored, ActionEditOwnPost, ActionEditAnyPost); err != nil {
+		return err
+	}
+	if post.CategoryID != 0 {
+		app.categoryMu.Lock()
+		defer app.categoryMu.Unlock()
+	}
+	if err := app.checkCategory(post.CategoryID); err != nil {
+		return err
+	}
+
+	dbPost := toStoragePost(post)
+	// Without a version the write is still tied to the state read above, so status changes like publishing a
+	// scheduled post in between aren't overwritten
+	if dbPost.Version == 0 {
+		dbPost.Version = stored.Version
+	}
+	if err := app.resolveOwnedAuthor(ctx, &dbPost, ActionEditOwnPost); err != nil {
+		return err
+	}
+	if err := app.applyStatus(ctx, &dbPost, &stored, time.Now().UTC()); err != nil {
+		return err
+	}
+	switch {
+	case dbPost.Slug != "" && dbPost.Slug != stored.Slug:
+		owner, err := app.repository.GetBySlug(ctx, dbPost.Slug)
+		if err == nil && owner.ID != post.ID {
+			return ErrSlugTaken
+		}
+		if err != nil && !errors.Is(err, storage.ErrSlugNotFound) {
+			return err
+		}
+	case dbPost.Title != stored.Title:
+		dbPost.Slug = slug.Make(dbPost.Title)
+	default:
+		dbPost.Slug = stored.Slug
+	}
+
+	updated, err := app.repository.Update(ctx, dbPost)
+	if err != nil {
+		return mapStorageError(err)
+	}
+
+	app.notifySaved(updated)
+	return nil
+}
+
+// DeletePost removes the post with its comments. Own posts need ActionDeleteOwnPost, posts of others ActionDeleteAnyPost.
+func (app *Application) DeletePost(ctx context.Context, id int) error {
+	app.log(ctx).Debug("Deleting post", slog.Int("id", id))
+
+	if err := app.authorizeEither(ctx, ActionDeleteOwnPost, ActionDeleteAnyPost); err != nil {
+		return err
+	}
+	stored, err := app.repository.GetByID(ctx, id)
+	if err != nil {
+		return mapStorageError(err)
+	}
+	if err := app.authorizePost(ctx, stored, ActionDeleteOwnPost, ActionDeleteAnyPost); err != nil {
+		return err
+	}
+
+	if err := app.repository.Delete(ctx, id); err != nil {
+		return mapStorageError(err)
+	}
+
+	// Comments can't outlive their post
+	if app.comments != nil {
+		if err := app.comments.DeleteByPost(int64(id)); err != nil {
+			app.log(ctx).Error("Failed to delete comments of the post", "error", err, slog.Int("id", id))
+		}
+	}
+
+	for _, listener := range app.listeners {
+		listener.PostDeleted(int64(id))
+	}
+	return nil
+}
+
+func (app *Application) notifySaved(dbPost storage.Post) {
+	if len(app.listeners) == 0 {
+		return
+	}
+
+	post := toModelPost(dbPost)
+	for _, listener := range app.listeners {
+		if post.Status == models.PostStatusPublished {
+			listener.PostSaved(post)
+		} else {
+			listener.PostDeleted(post.ID)
+		}
+	}
+}
+
+// RenderContent fills ContentHTML of the post from its markdown content
+func (app *Application) RenderContent(ctx context.Context, post *models.Post) error {
+	if _, err := app.authorize(ctx, ActionReadPosts); err != nil {
+		return err
+	}
+	if app.renderer == nil {
+		return ErrRenderingDisabled
+	}
+
+	rendered, err := app.renderer.Render(post.ID, post.Version, post.Content)
+	if err != nil {
+		return errors.Wrap(err, "render post content")
+	}
+	post.ContentHTML = rendered
+
+	return nil
+}
+
+// mapStorageError translates storage errors into the service ones, so callers don't depend on the storage package
+func mapStorageError(err error) error {
+	if errors.Is(err, storage.ErrPostNotFound) || errors.Is(err, storage.ErrSlugNotFound) {
+		return ErrPostNotFound
+	}
+	if errors.Is(err, storage.ErrVersionConflict) {
+		return ErrPostChanged
+	}
+	if errors.Is(err, storage.ErrCategoryNotFound) {
+		return ErrCategoryNotFound
+	}
+	if errors.Is(err, storage.ErrCommentNotFound) {
+		return ErrCommentNotFound
+	}
+	if errors.Is(err, storage.ErrAuthorNotFound) {
+		return ErrAuthorNotFound
+	}
+	if errors.Is(err, storage.ErrAuthorNameTaken) {
+		return ErrAuthorNameTaken
+	}
+	if errors.Is(err, storage.ErrAPIKeyNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	if errors.Is(err, storage.ErrEmailTaken) {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+// withReferences fills the authors and category breadcrumbs the posts reference
+func (app *Application) withReferences(posts []models.Post) ([]models.Post, error) {
+	posts, err := app.withAuthors(posts)
+	if err != nil {
+		return nil, err
+	}
+	return app.withBreadcrumbs(posts)
+}
+
+// withReference is withReferences for a single post
+func (app *Application) withReference(post models.Post) (models.Post, error) {
+	posts, err := app.withReferences([]models.Post{post})
+	if err != nil {
+		return models.Post{}, err
+	}
+	return posts[0], nil
+}
+
+// toStoragePost converts the API model into the storage one. Read only fields are not copied, tags are normalized.
+func toStoragePost(post models.Post) storage.Post {
 	dbPost := storage.Post{
-		ID:      post.ID,
-		Title:   post.Title,
-		Content: post.Content,
-		Author:  post.Author,
+		ID:         post.ID,
+		Title:      post.Title,
+		Content:    post.Content,
+		Author:     post.Author,
+		AuthorID:   post.AuthorID,
+		Slug:       post.Slug,
+		Tags:       normalizeTags(post.Tags),
+		CategoryID: post.CategoryID,
+		Status:     post.Status,
+		Version:    post.Version,
+	}
+	if post.PublishAt != nil {
+		dbPost.PublishAt = time.Time(*post.PublishAt)
 	}
+	return dbPost
+}
 
-	app.logger.Debug("Updating post", "post_id", post.ID)
-	return app.repository.Update(dbPost)
+// normalizeTags turns tags into slugs, so "Web Development" and "web-development" are the same tag.
+// Tags left empty are dropped, duplicates are removed keeping the original order.
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = slug.Make(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
 }
 
-func (app *Application) DeletePost(id int) error {
-	app.logger.Debug("Deleting post", slog.Int("id", id))
-	return app.repository.Delete(id)
+func toModelPost(dbPost storage.Post) models.Post {
+	post := models.Post{
+		ID:         dbPost.ID,
+		Title:      dbPost.Title,
+		Content:    dbPost.Content,
+		Author:     dbPost.Author,
+		AuthorID:   dbPost.AuthorID,
+		Slug:       dbPost.Slug,
+		Tags:       dbPost.Tags,
+		CategoryID: dbPost.CategoryID,
+		Status:     postStatus(dbPost),
+		Version:    dbPost.Version,
+		CreatedAt:  strfmt.DateTime(dbPost.CreatedAt),
+		UpdatedAt:  strfmt.DateTime(dbPost.UpdatedAt),
+	}
+	if !dbPost.PublishAt.IsZero() {
+		publishAt := strfmt.DateTime(dbPost.PublishAt)
+		post.PublishAt = &publishAt
+	}
+	return post
 }
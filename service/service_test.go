@@ -1,13 +1,16 @@
 package service
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"rakia_blog_tt/auth"
 	"rakia_blog_tt/handler/models"
 	"rakia_blog_tt/storage"
 )
@@ -16,6 +19,11 @@ func loggerMock() *slog.Logger {
 	return slog.New(slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
 }
 
+// adminCtx authenticates calls as an admin, so tests not about access control may modify any post
+func adminCtx() context.Context {
+	return auth.NewContext(context.Background(), auth.Principal{Subject: "admin", Roles: []string{auth.RoleAdmin}})
+}
+
 func TestApplication_CreatePost(t *testing.T) {
 	mockRepo := new(MockRepo)
 	logger := loggerMock()
@@ -32,12 +40,34 @@ func TestApplication_CreatePost(t *testing.T) {
 		Title:   post.Title,
 		Content: post.Content,
 		Author:  post.Author,
+		Slug:    "title-1",
+		Status:  models.PostStatusDraft,
 	}
+	stored := dbPost
+	stored.ID = 1
+	stored.Version = 1
 
-	mockRepo.On("Create", dbPost).Return(nil)
+	mockRepo.On("Create", dbPost).Return(stored, nil)
 
-	err := app.CreatePost(post)
+	created, err := app.CreatePost(adminCtx(), post)
 	require.NoError(t, err)
+	assert.Equal(t, int64(1), created.ID)
+	assert.Equal(t, "title-1", created.Slug)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_CreatePost_ExplicitSlug(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+
+	post := models.Post{Title: "Title 1", Content: "Content 1", Author: "Author 1", Slug: "custom"}
+
+	mockRepo.On("Create", mock.MatchedBy(func(p storage.Post) bool { return p.Slug == "custom" })).
+		Return(storage.Post{ID: 1, Slug: "custom"}, nil)
+
+	created, err := app.CreatePost(adminCtx(), post)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", created.Slug)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -63,21 +93,24 @@ func TestApplication_GetPosts(t *testing.T) {
 
 	mockRepo.On("GetAll").Return(dbPosts, nil)
 
-	posts, err := app.GetPosts()
+	posts, err := app.GetPosts(context.Background())
 	require.NoError(t, err)
 
+	// Posts stored without a status predate the workflow and are published
 	expectedPosts := []models.Post{
 		{
 			ID:      1,
 			Title:   "Title 1",
 			Content: "Content 1",
 			Author:  "Author 1",
+			Status:  models.PostStatusPublished,
 		},
 		{
 			ID:      2,
 			Title:   "Title 2",
 			Content: "Content 2",
 			Author:  "Author 2",
+			Status:  models.PostStatusPublished,
 		},
 	}
 
@@ -99,7 +132,7 @@ func TestApplication_GetPostByID(t *testing.T) {
 
 	mockRepo.On("GetByID", 1).Return(dbPost, nil)
 
-	post, err := app.GetPostByID(1)
+	post, err := app.GetPostByID(context.Background(), 1)
 	require.NoError(t, err)
 
 	expectedPost := models.Post{
@@ -107,6 +140,7 @@ func TestApplication_GetPostByID(t *testing.T) {
 		Title:   "Title 1",
 		Content: "Content 1",
 		Author:  "Author 1",
+		Status:  models.PostStatusPublished,
 	}
 
 	assert.Equal(t, expectedPost, post)
@@ -130,23 +164,310 @@ func TestApplication_UpdatePost(t *testing.T) {
 		Title:   post.Title,
 		Content: post.Content,
 		Author:  post.Author,
+		Slug:    "updated-title",
+		Status:  models.PostStatusPublished,
 	}
 
-	mockRepo.On("Update", dbPost).Return(nil)
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1, Title: "Updated Title", Slug: "updated-title"}, nil)
+	mockRepo.On("Update", dbPost).Return(dbPost, nil)
 
-	err := app.UpdatePost(post)
+	err := app.UpdatePost(adminCtx(), post)
 	require.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestApplication_UpdatePost_Slug(t *testing.T) {
+	stored := storage.Post{ID: 1, Title: "Old Title", Content: "Content", Author: "Author", Slug: "old-title"}
+
+	tests := []struct {
+		name     string
+		post     models.Post
+		owner    *storage.Post // returned by GetBySlug, nil when the slug is free
+		wantSlug string
+		wantErr  error
+	}{
+		{
+			name:     "title change regenerates the slug",
+			post:     models.Post{ID: 1, Title: "New Title", Slug: "old-title"},
+			wantSlug: "new-title",
+		},
+		{
+			name:     "empty slug regenerates on title change",
+			post:     models.Post{ID: 1, Title: "New Title"},
+			wantSlug: "new-title",
+		},
+		{
+			name:     "same title keeps the slug",
+			post:     models.Post{ID: 1, Title: "Old Title"},
+			wantSlug: "old-title",
+		},
+		{
+			name:     "explicit slug wins over the title",
+			post:     models.Post{ID: 1, Title: "New Title", Slug: "custom"},
+			wantSlug: "custom",
+		},
+		{
+			name:     "explicit former slug of the same post",
+			post:     models.Post{ID: 1, Title: "Old Title", Slug: "older-title"},
+			owner:    &storage.Post{ID: 1, Slug: "old-title"},
+			wantSlug: "older-title",
+		},
+		{
+			name:    "explicit slug of another post",
+			post:    models.Post{ID: 1, Title: "Old Title", Slug: "taken"},
+			owner:   &storage.Post{ID: 2, Slug: "taken"},
+			wantErr: ErrSlugTaken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			app := New(mockRepo, loggerMock())
+
+			mockRepo.On("GetByID", 1).Return(stored, nil)
+			if tt.owner != nil {
+				mockRepo.On("GetBySlug", tt.post.Slug).Return(*tt.owner, nil)
+			} else {
+				mockRepo.On("GetBySlug", mock.Anything).Return(storage.Post{}, storage.ErrSlugNotFound).Maybe()
+			}
+			if tt.wantErr == nil {
+				mockRepo.On("Update", mock.MatchedBy(func(p storage.Post) bool { return p.Slug == tt.wantSlug })).
+					Return(storage.Post{ID: 1, Slug: tt.wantSlug}, nil)
+			}
+
+			err := app.UpdatePost(adminCtx(), tt.post)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+			} else {
+				require.NoError(t, err)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApplication_GetPostBySlug(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+
+	dbPost := storage.Post{ID: 1, Title: "New Title", Slug: "new-title"}
+	mockRepo.On("GetBySlug", "new-title").Return(dbPost, nil)
+	mockRepo.On("GetBySlug", "old-title").Return(dbPost, nil)
+	mockRepo.On("GetBySlug", "missing").Return(storage.Post{}, storage.ErrSlugNotFound)
+
+	post, moved, err := app.GetPostBySlug(context.Background(), "new-title")
+	require.NoError(t, err)
+	assert.False(t, moved)
+	assert.Equal(t, int64(1), post.ID)
+
+	post, moved, err = app.GetPostBySlug(context.Background(), "old-title")
+	require.NoError(t, err)
+	assert.True(t, moved)
+	assert.Equal(t, "new-title", post.Slug)
+
+	_, _, err = app.GetPostBySlug(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrPostNotFound)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestApplication_DeletePost(t *testing.T) {
 	mockRepo := new(MockRepo)
 	logger := loggerMock()
 	app := New(mockRepo, logger)
 
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1}, nil)
 	mockRepo.On("Delete", 1).Return(nil)
 
-	err := app.DeletePost(1)
+	err := app.DeletePost(adminCtx(), 1)
 	require.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestApplication_UpdatePost_NotFound(t *testing.T) {
+	mockRepo := new(MockRepo)
+	logger := loggerMock()
+	app := New(mockRepo, logger)
+
+	post := models.Post{
+		ID:      1,
+		Title:   "Updated Title",
+		Content: "Updated Content",
+		Author:  "Updated Author",
+	}
+
+	mockRepo.On("GetByID", 1).Return(storage.Post{}, storage.ErrPostNotFound)
+
+	err := app.UpdatePost(adminCtx(), post)
+	assert.ErrorIs(t, err, ErrPostNotFound)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_UpdatePost_Changed(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+
+	post := models.Post{ID: 1, Title: "Title", Content: "Content", Author: "Author", Version: 1}
+	dbPost := storage.Post{ID: 1, Title: "Title", Content: "Content", Author: "Author", Slug: "title",
+		Status: models.PostStatusPublished, Version: 1}
+
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1, Title: "Title", Slug: "title", Version: 2}, nil)
+	mockRepo.On("Update", dbPost).Return(storage.Post{}, storage.ErrVersionConflict)
+
+	err := app.UpdatePost(adminCtx(), post)
+	assert.ErrorIs(t, err, ErrPostChanged)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_UpdatePost_StoredVersion(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+
+	// Without a version the post is written at the version it was read at, a change in between is a conflict
+	post := models.Post{ID: 1, Title: "Title", Content: "Content", Author: "Author"}
+	dbPost := storage.Post{ID: 1, Title: "Title", Content: "Content", Author: "Author", Slug: "title",
+		Status: models.PostStatusPublished, Version: 2}
+
+	mockRepo.On("GetByID", 1).Return(storage.Post{ID: 1, Title: "Title", Slug: "title", Version: 2}, nil)
+	mockRepo.On("Update", dbPost).Return(storage.Post{}, storage.ErrVersionConflict)
+
+	err := app.UpdatePost(adminCtx(), post)
+	assert.ErrorIs(t, err, ErrPostChanged)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_RenderContent(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockRenderer := new(MockRenderer)
+	logger := loggerMock()
+	app := New(mockRepo, logger, WithContentRenderer(mockRenderer))
+
+	post := models.Post{
+		ID:      1,
+		Title:   "Title 1",
+		Content: "# Content 1",
+		Author:  "Author 1",
+		Version: 3,
+	}
+
+	mockRenderer.On("Render", int64(1), int64(3), "# Content 1").Return("<h1>Content 1</h1>", nil)
+
+	err := app.RenderContent(context.Background(), &post)
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>Content 1</h1>", post.ContentHTML)
+	mockRenderer.AssertExpectations(t)
+}
+
+func TestApplication_RenderContent_Disabled(t *testing.T) {
+	app := New(new(MockRepo), loggerMock())
+
+	err := app.RenderContent(context.Background(), &models.Post{Content: "text"})
+	assert.ErrorIs(t, err, ErrRenderingDisabled)
+}
+
+func TestApplication_GetPostsByAuthor(t *testing.T) {
+	mockRepo := new(MockRepo)
+	logger := loggerMock()
+	app := New(mockRepo, logger)
+
+	dbPosts := []storage.Post{
+		{ID: 1, Title: "Title 1", Content: "Content 1", Author: "Author 1"},
+		{ID: 2, Title: "Title 2", Content: "Content 2", Author: "Author 2"},
+		{ID: 3, Title: "Title 3", Content: "Content 3", Author: "Author 1"},
+	}
+
+	mockRepo.On("GetAll").Return(dbPosts, nil)
+
+	posts, err := app.GetPostsByAuthor(context.Background(), "Author 1")
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, int64(1), posts[0].ID)
+	assert.Equal(t, int64(3), posts[1].ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_NotifiesListeners(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockListener := new(MockListener)
+	logger := loggerMock()
+	app := New(mockRepo, logger, WithPostListener(mockListener))
+
+	post := models.Post{Title: "Title 1", Content: "Content 1", Author: "Author 1"}
+	stored := storage.Post{ID: 1, Title: "Title 1", Content: "Content 1", Author: "Author 1", Version: 1}
+
+	mockRepo.On("Create", mock.Anything).Return(stored, nil)
+	mockRepo.On("GetByID", 1).Return(stored, nil)
+	mockRepo.On("GetByID", 2).Return(storage.Post{}, storage.ErrPostNotFound)
+	mockRepo.On("Delete", 1).Return(nil)
+	mockListener.On("PostSaved", models.Post{ID: 1, Title: "Title 1", Content: "Content 1", Author: "Author 1", Status: models.PostStatusPublished, Version: 1}).Return()
+	mockListener.On("PostDeleted", int64(1)).Return()
+
+	_, err := app.CreatePost(adminCtx(), post)
+	require.NoError(t, err)
+	require.Error(t, app.UpdatePost(adminCtx(), models.Post{ID: 2}))
+	require.NoError(t, app.DeletePost(adminCtx(), 1))
+
+	mockListener.AssertExpectations(t)
+	mockListener.AssertNumberOfCalls(t, "PostSaved", 1)
+}
+
+func TestApplication_CreatePost_NormalizesTags(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+
+	post := models.Post{Title: "Title 1", Content: "Content 1", Author: "Author 1",
+		Tags: []string{"Go", "Web Development", "go", "web-development", "!!!"}}
+
+	mockRepo.On("Create", mock.MatchedBy(func(p storage.Post) bool {
+		return assert.ObjectsAreEqual([]string{"go", "web-development"}, p.Tags)
+	})).Return(storage.Post{ID: 1, Tags: []string{"go", "web-development"}}, nil)
+
+	created, err := app.CreatePost(adminCtx(), post)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "web-development"}, created.Tags)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_GetPostsByTags(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+
+	mockRepo.On("GetByTags", []string{"go", "web"}, true).Return([]storage.Post{{ID: 1, Tags: []string{"go", "web"}}}, nil)
+
+	posts, err := app.GetPostsByTags(context.Background(), []string{"Go", "WEB"}, true)
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	assert.Equal(t, int64(1), posts[0].ID)
+
+	// Nothing is left to look up after normalization
+	posts, err = app.GetPostsByTags(context.Background(), []string{"!!!"}, false)
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestApplication_GetTags(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+
+	mockRepo.On("GetAll").Return([]storage.Post{
+		{ID: 1, Tags: []string{"go", "web"}},
+		{ID: 2, Tags: []string{"go", "api"}, Status: models.PostStatusPublished},
+		{ID: 3, Tags: []string{"go"}, Status: models.PostStatusPublished},
+		{ID: 4, Tags: []string{"go", "secret-product-x"}, Status: models.PostStatusDraft, Author: "Jane Doe"},
+	}, nil)
+
+	tags, err := app.GetTags(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []models.Tag{
+		{Name: "go", PostCount: 3},
+		{Name: "api", PostCount: 1},
+		{Name: "web", PostCount: 1},
+	}, tags, "the draft isn't counted for anonymous clients")
+
+	tags, err = app.GetTags(auth.NewContext(context.Background(), jane))
+	require.NoError(t, err)
+	assert.Contains(t, tags, models.Tag{Name: "secret-product-x", PostCount: 1}, "authors see tags of their drafts")
+}
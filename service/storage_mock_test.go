@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/stretchr/testify/mock"
 	"rakia_blog_tt/storage"
 )
@@ -10,27 +12,42 @@ type MockRepo struct {
 	mock.Mock
 }
 
-func (m *MockRepo) Create(post storage.Post) error {
+func (m *MockRepo) Create(ctx context.Context, post storage.Post) (storage.Post, error) {
 	args := m.Called(post)
-	return args.Error(0)
+	return args.Get(0).(storage.Post), args.Error(1)
 }
 
-func (m *MockRepo) GetAll() ([]storage.Post, error) {
+func (m *MockRepo) GetAll(ctx context.Context) ([]storage.Post, error) {
 	args := m.Called()
 	return args.Get(0).([]storage.Post), args.Error(1)
 }
 
-func (m *MockRepo) GetByID(id int) (storage.Post, error) {
+func (m *MockRepo) GetByID(ctx context.Context, id int) (storage.Post, error) {
 	args := m.Called(id)
 	return args.Get(0).(storage.Post), args.Error(1)
 }
 
-func (m *MockRepo) Update(post storage.Post) error {
+func (m *MockRepo) GetBySlug(ctx context.Context, slug string) (storage.Post, error) {
+	args := m.Called(slug)
+	return args.Get(0).(storage.Post), args.Error(1)
+}
+
+func (m *MockRepo) GetByTags(ctx context.Context, tags []string, matchAll bool) ([]storage.Post, error) {
+	args := m.Called(tags, matchAll)
+	return args.Get(0).([]storage.Post), args.Error(1)
+}
+
+func (m *MockRepo) Update(ctx context.Context, post storage.Post) (storage.Post, error) {
 	args := m.Called(post)
+	return args.Get(0).(storage.Post), args.Error(1)
+}
+
+func (m *MockRepo) SetPreviewNonce(ctx context.Context, id int, nonce string) error {
+	args := m.Called(id, nonce)
 	return args.Error(0)
 }
 
-func (m *MockRepo) Delete(id int) error {
+func (m *MockRepo) Delete(ctx context.Context, id int) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxFailedLogins = 5
+	defaultLoginWindow     = 15 * time.Minute
+)
+
+// ThrottledError refuses a login to an account with too many recent failures, it matches ErrLoginThrottled
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return "too many failed logins, retry after " + e.RetryAfter.String()
+}
+
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrLoginThrottled
+}
+
+// loginThrottle counts failed logins per account. An account with maxFailures failures within the window
+// is locked until the oldest of them leaves the window, so passwords can't be guessed one after another.
+// Accounts are counted by email whether they exist or not, so the lock doesn't tell which emails are registered.
+type loginThrottle struct {
+	maxFailures int
+	window      time.Duration
+
+	mu       sync.Mutex
+	failures map[string][]time.Time // email key -> failed logins within the window, oldest first
+}
+
+func newLoginThrottle(maxFailures int, window time.Duration) *loginThrottle {
+	return &loginThrottle{
+		maxFailures: maxFailures,
+		window:      window,
+		failures:    make(map[string][]time.Time),
+	}
+}
+
+// WithLoginThrottle locks accounts after maxFailures failed logins within the window,
+// a non-positive maxFailures turns throttling off
+func WithLoginThrottle(maxFailures int, window time.Duration) Option {
+	return func(app *Application) {
+		app.throttle = newLoginThrottle(maxFailures, window)
+	}
+}
+
+// retryAfter returns how long the account stays locked, zero when it isn't
+func (t *loginThrottle) retryAfter(key string, now time.Time) time.Duration {
+	if t.maxFailures <= 0 {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures := t.recent(key, now)
+	if len(failures) < t.maxFailures {
+		return 0
+	}
+	return failures[len(failures)-t.maxFailures].Add(t.window).Sub(now)
+}
+
+// fail records a failed login of the account
+func (t *loginThrottle) fail(key string, now time.Time) {
+	if t.maxFailures <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Accounts nobody tries anymore are dropped, so guessing random emails doesn't grow the map forever
+	for other := range t.failures {
+		t.recent(other, now)
+	}
+	t.failures[key] = append(t.failures[key], now)
+}
+
+// reset forgets the failures of the account after a successful login
+func (t *loginThrottle) reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.failures, key)
+}
+
+// recent drops failures of the account which left the window and returns the others, t.mu must be held
+func (t *loginThrottle) recent(key string, now time.Time) []time.Time {
+	failures := t.failures[key]
+	since := now.Add(-t.window)
+	for len(failures) > 0 && !failures[0].After(since) {
+		failures = failures[1:]
+	}
+	if len(failures) == 0 {
+		delete(t.failures, key)
+		return nil
+	}
+	t.failures[key] = failures
+	return failures
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var (
+	ErrUsersDisabled      = errors.New("user accounts are not configured")
+	ErrEmailTaken         = errors.New("email is used by another account")
+	ErrInvalidPassword    = errors.New("password must be 10 to 128 characters long")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrLoginThrottled     = errors.New("too many failed logins")
+	ErrInvalidSession     = errors.New("invalid or expired session")
+)
+
+const (
+	minPasswordLength = 10
+	// maxPasswordLength keeps hashing cheap enough that long passwords can't be used to load the server
+	maxPasswordLength = 128
+	defaultSessionTTL = 7 * 24 * time.Hour
+)
+
+// userSubjectPrefix starts subjects of principals logged in with a session, the user ID follows it
+const userSubjectPrefix = "user:"
+
+// UserRepo stores user accounts
+type UserRepo interface {
+	Create(user storage.User) (storage.User, error)
+	GetByID(id int64) (storage.User, error)
+	GetByEmail(email string) (storage.User, error)
+}
+
+// SessionRepo stores login sessions by the hash of their token
+type SessionRepo interface {
+	Create(session storage.Session) (storage.Session, error)
+	GetByHash(hash string) (storage.Session, error)
+	Delete(hash string) error
+	DeleteByUser(userID int64) error
+}
+
+// WithUserRepo lets users sign up and log in, sessions are kept in the session repository
+func WithUserRepo(users UserRepo, sessions SessionRepo) Option {
+	return func(app *Application) {
+		app.users = users
+		app.sessions = sessions
+	}
+}
+
+// WithSessionTTL sets how long a login lasts
+func WithSessionTTL(ttl time.Duration) Option {
+	return func(app *Application) {
+		app.sessionTTL = ttl
+	}
+}
+
+// Session is a login of a user
+type Session struct {
+	// Token identifies the session in the session cookie, only its hash is stored
+	Token string
+	// CSRFToken is expected in forms posted within the session
+	CSRFToken string
+	ExpiresAt time.Time
+	User      models.User
+	// Principal is who requests of the session act as
+	Principal auth.Principal
+}
+
+// unknownUserHash is verified against for unknown emails, so the response time doesn't tell which emails are registered
+var unknownUserHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// SignUp creates an account, new users are readers. Anyone may sign up.
+func (app *Application) SignUp(ctx context.Context, user models.User, password string) (models.User, error) {
+	app.log(ctx).Debug("Signing up a new user")
+
+	if app.users == nil {
+		return models.User{}, ErrUsersDisabled
+	}
+	if length := utf8.RuneCountInString(password); length < minPasswordLength || length > maxPasswordLength {
+		return models.User{}, ErrInvalidPassword
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return models.User{}, err
+	}
+	created, err := app.users.Create(storage.User{
+		Email:        strings.TrimSpace(user.Email.String()),
+		Name:         strings.TrimSpace(user.Name),
+		PasswordHash: hash,
+		Roles:        []string{auth.RoleReader},
+	})
+	if err != nil {
+		return models.User{}, mapStorageError(err)
+	}
+
+	return toModelUser(created), nil
+}
+
+// Login starts a session of the user with the email and password. Accounts are locked for a while after
+// repeated failures, which is reported as a ThrottledError.
+func (app *Application) Login(ctx context.Context, email, password string) (Session, error) {
+	app.log(ctx).Debug("Logging in")
+
+	if app.users == nil {
+		return Session{}, ErrUsersDisabled
+	}
+
+	key := storage.EmailKey(email)
+	now := time.Now().UTC()
+	if wait := app.throttle.retryAfter(key, now); wait > 0 {
+		app.log(ctx).Info("Login throttled", slog.Duration("retry_after", wait))
+		return Session{}, &ThrottledError{RetryAfter: wait}
+	}
+
+	user, err := app.users.GetByEmail(email)
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+		return Session{}, err
+	}
+	hash := user.PasswordHash
+	if err != nil {
+		hash = unknownUserHash()
+	}
+	ok, verifyErr := auth.VerifyPassword(password, hash)
+	if verifyErr != nil {
+		return Session{}, verifyErr
+	}
+	if err != nil || !ok {
+		app.throttle.fail(key, now)
+		return Session{}, ErrInvalidCredentials
+	}
+	app.throttle.reset(key)
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return Session{}, err
+	}
+	csrfToken, err := auth.GenerateToken()
+	if err != nil {
+		return Session{}, err
+	}
+	created, err := app.sessions.Create(storage.Session{
+		Hash:      auth.HashToken(token),
+		UserID:    user.ID,
+		CSRFToken: csrfToken,
+		ExpiresAt: now.Add(app.sessionTTL),
+	})
+	if err != nil {
+		return Session{}, err
+	}
+
+	session := toSession(created, user)
+	session.Token = token
+	return session, nil
+}
+
+// AuthenticateSession returns the session of the token. Unknown and expired sessions are reported as ErrInvalidSession.
+func (app *Application) AuthenticateSession(ctx context.Context, token string) (Session, error) {
+	if app.users == nil {
+		return Session{}, ErrInvalidSession
+	}
+
+	hash := auth.HashToken(token)
+	stored, err := app.sessions.GetByHash(hash)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return Session{}, ErrInvalidSession
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	if !time.Now().Before(stored.ExpiresAt) {
+		if err := app.sessions.Delete(hash); err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+			app.log(ctx).Error("Failed to delete expired session", "error", err)
+		}
+		return Session{}, ErrInvalidSession
+	}
+
+	user, err := app.users.GetByID(stored.UserID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return Session{}, ErrInvalidSession
+	}
+	if err != nil {
+		return Session{}, err
+	}
+
+	session := toSession(stored, user)
+	session.Token = token
+	return session, nil
+}
+
+// Logout ends the session of the token, ending a session twice is not an error
+func (app *Application) Logout(ctx context.Context, token string) error {
+	app.log(ctx).Debug("Logging out")
+
+	if app.users == nil {
+		return ErrUsersDisabled
+	}
+
+	err := app.sessions.Delete(auth.HashToken(token))
+	if err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+		return err
+	}
+	return nil
+}
+
+// RevokeSessions ends every session of the user. Users may revoke their own sessions,
+// sessions of others need ActionManageUsers.
+func (app *Application) RevokeSessions(ctx context.Context, userID int64) error {
+	app.log(ctx).Debug("Revoking sessions", slog.Int64("user_id", userID))
+
+	if p, ok := auth.FromContext(ctx); !ok || p.Subject != userSubject(userID) {
+		if _, err := app.authorize(ctx, ActionManageUsers); err != nil {
+			return err
+		}
+	}
+	if app.users == nil {
+		return ErrUsersDisabled
+	}
+
+	return app.sessions.DeleteByUser(userID)
+}
+
+func userSubject(id int64) string {
+	return userSubjectPrefix + strconv.FormatInt(id, 10)
+}
+
+func toSession(stored storage.Session, user storage.User) Session {
+	return Session{
+		CSRFToken: stored.CSRFToken,
+		ExpiresAt: stored.ExpiresAt,
+		User:      toModelUser(user),
+		Principal: auth.Principal{
+			Subject:  userSubject(user.ID),
+			Name:     user.Name,
+			AuthorID: user.AuthorID,
+			Roles:    append([]string{}, user.Roles...),
+		},
+	}
+}
+
+func toModelUser(user storage.User) models.User {
+	return models.User{
+		ID:        user.ID,
+		Email:     strfmt.Email(user.Email),
+		Name:      user.Name,
+		Roles:     user.Roles,
+		AuthorID:  user.AuthorID,
+		CreatedAt: strfmt.DateTime(user.CreatedAt),
+	}
+}
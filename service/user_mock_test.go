@@ -0,0 +1,54 @@
+package service
+
+import (
+	"github.com/stretchr/testify/mock"
+	"rakia_blog_tt/storage"
+)
+
+// MockUserRepo is a mock implementation of the UserRepo interface
+type MockUserRepo struct {
+	mock.Mock
+}
+
+func (m *MockUserRepo) Create(user storage.User) (storage.User, error) {
+	args := m.Called(user)
+	return args.Get(0).(storage.User), args.Error(1)
+}
+
+func (m *MockUserRepo) GetByID(id int64) (storage.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(storage.User), args.Error(1)
+}
+
+func (m *MockUserRepo) GetByEmail(email string) (storage.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(storage.User), args.Error(1)
+}
+
+// MockSessionRepo is a mock implementation of the SessionRepo interface
+type MockSessionRepo struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepo) Create(session storage.Session) (storage.Session, error) {
+	args := m.Called(session)
+	if fn, ok := args.Get(0).(func(storage.Session) storage.Session); ok {
+		return fn(session), args.Error(1)
+	}
+	return args.Get(0).(storage.Session), args.Error(1)
+}
+
+func (m *MockSessionRepo) GetByHash(hash string) (storage.Session, error) {
+	args := m.Called(hash)
+	return args.Get(0).(storage.Session), args.Error(1)
+}
+
+func (m *MockSessionRepo) Delete(hash string) error {
+	args := m.Called(hash)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepo) DeleteByUser(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+const testPassword = "correct horse battery staple"
+
+func newUserTestApp(opts ...Option) (*Application, *MockUserRepo, *MockSessionRepo) {
+	mockUsers := new(MockUserRepo)
+	mockSessions := new(MockSessionRepo)
+	opts = append([]Option{WithUserRepo(mockUsers, mockSessions)}, opts...)
+	return New(new(MockRepo), loggerMock(), opts...), mockUsers, mockSessions
+}
+
+// storedUser returns jane with testPassword
+func storedUser(t *testing.T) storage.User {
+	t.Helper()
+	hash, err := auth.HashPassword(testPassword)
+	require.NoError(t, err)
+	return storage.User{ID: 1, Email: "jane@example.com", Name: "Jane Doe", PasswordHash: hash, Roles: []string{auth.RoleAuthor}, AuthorID: 3}
+}
+
+func TestApplication_SignUp(t *testing.T) {
+	app, mockUsers, _ := newUserTestApp()
+
+	var stored storage.User
+	mockUsers.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(storage.User)
+	}).Return(storage.User{ID: 1, Email: "jane@example.com", Name: "Jane Doe", Roles: []string{auth.RoleReader}}, nil).Once()
+
+	user, err := app.SignUp(context.Background(), models.User{Email: " jane@example.com ", Name: "Jane Doe"}, testPassword)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+	assert.Equal(t, "jane@example.com", stored.Email)
+	assert.Equal(t, []string{auth.RoleReader}, stored.Roles, "new users are readers")
+	assert.NotContains(t, stored.PasswordHash, testPassword)
+	ok, err := auth.VerifyPassword(testPassword, stored.PasswordHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	mockUsers.On("Create", mock.Anything).Return(storage.User{}, storage.ErrEmailTaken).Once()
+	_, err = app.SignUp(context.Background(), models.User{Email: "jane@example.com", Name: "Jane"}, testPassword)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+}
+
+func TestApplication_SignUp_InvalidPassword(t *testing.T) {
+	app, mockUsers, _ := newUserTestApp()
+
+	for _, password := range []string{"", "too short", string(make([]byte, maxPasswordLength+1))} {
+		_, err := app.SignUp(context.Background(), models.User{Email: "jane@example.com", Name: "Jane"}, password)
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+	}
+	mockUsers.AssertNotCalled(t, "Create", mock.Anything)
+
+	_, err := New(new(MockRepo), loggerMock()).SignUp(context.Background(), models.User{}, testPassword)
+	assert.ErrorIs(t, err, ErrUsersDisabled)
+}
+
+func TestApplication_Login(t *testing.T) {
+	app, mockUsers, mockSessions := newUserTestApp(WithSessionTTL(time.Hour))
+	jane := storedUser(t)
+	mockUsers.On("GetByEmail", "Jane@Example.com").Return(jane, nil)
+
+	var stored storage.Session
+	mockSessions.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(0).(storage.Session)
+	}).Return(func(s storage.Session) storage.Session { return s }, nil)
+
+	session, err := app.Login(context.Background(), "Jane@Example.com", testPassword)
+	require.NoError(t, err)
+	assert.NotEmpty(t, session.Token)
+	assert.NotEmpty(t, session.CSRFToken)
+	assert.NotEqual(t, session.Token, session.CSRFToken)
+	assert.Equal(t, auth.HashToken(session.Token), stored.Hash, "only the hash of the token is stored")
+	assert.Equal(t, int64(1), stored.UserID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+	assert.Equal(t, auth.Principal{Subject: "user:1", Name: "Jane Doe", AuthorID: 3, Roles: []string{auth.RoleAuthor}}, session.Principal)
+}
+
+func TestApplication_Login_InvalidCredentials(t *testing.T) {
+	app, mockUsers, mockSessions := newUserTestApp()
+	mockUsers.On("GetByEmail", "jane@example.com").Return(storedUser(t), nil)
+	mockUsers.On("GetByEmail", "john@example.com").Return(storage.User{}, storage.ErrUserNotFound)
+
+	_, err := app.Login(context.Background(), "jane@example.com", "wrong password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = app.Login(context.Background(), "john@example.com", testPassword)
+	assert.ErrorIs(t, err, ErrInvalidCredentials, "unknown emails look like wrong passwords")
+	mockSessions.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestApplication_Login_Throttle(t *testing.T) {
+	app, mockUsers, mockSessions := newUserTestApp(WithLoginThrottle(3, time.Hour))
+	mockUsers.On("GetByEmail", mock.Anything).Return(storedUser(t), nil)
+	mockSessions.On("Create", mock.Anything).Return(storage.Session{}, nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := app.Login(ctx, "jane@example.com", "wrong password")
+		require.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	_, err := app.Login(ctx, "JANE@example.com", testPassword)
+	var throttled *ThrottledError
+	require.ErrorAs(t, err, &throttled, "the right password doesn't help once locked")
+	assert.ErrorIs(t, err, ErrLoginThrottled)
+	assert.InDelta(t, time.Hour, throttled.RetryAfter, float64(time.Minute))
+	mockSessions.AssertNotCalled(t, "Create", mock.Anything)
+
+	_, err = app.Login(ctx, "john@example.com", testPassword)
+	assert.NoError(t, err, "other accounts aren't locked")
+}
+
+func TestLoginThrottle(t *testing.T) {
+	throttle := newLoginThrottle(2, time.Minute)
+	start := time.Now()
+
+	throttle.fail("jane", start)
+	assert.Zero(t, throttle.retryAfter("jane", start))
+	throttle.fail("jane", start.Add(10*time.Second))
+	assert.Equal(t, 40*time.Second, throttle.retryAfter("jane", start.Add(20*time.Second)))
+	assert.Zero(t, throttle.retryAfter("jane", start.Add(time.Minute)), "the oldest failure left the window")
+
+	throttle.fail("jane", start.Add(time.Minute))
+	throttle.reset("jane")
+	assert.Zero(t, throttle.retryAfter("jane", start.Add(time.Minute)))
+
+	throttle.fail("john", start)
+	throttle.fail("jane", start.Add(2*time.Minute))
+	assert.NotContains(t, throttle.failures, "john", "stale accounts are dropped")
+
+	off := newLoginThrottle(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		off.fail("jane", start)
+	}
+	assert.Zero(t, off.retryAfter("jane", start))
+}
+
+func TestApplication_AuthenticateSession(t *testing.T) {
+	app, mockUsers, mockSessions := newUserTestApp()
+	jane := storedUser(t)
+	mockUsers.On("GetByID", int64(1)).Return(jane, nil)
+	mockUsers.On("GetByID", int64(2)).Return(storage.User{}, storage.ErrUserNotFound)
+	mockSessions.On("GetByHash", auth.HashToken("valid")).
+		Return(storage.Session{Hash: auth.HashToken("valid"), UserID: 1, CSRFToken: "csrf", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mockSessions.On("GetByHash", auth.HashToken("expired")).
+		Return(storage.Session{Hash: auth.HashToken("expired"), UserID: 1, ExpiresAt: time.Now().Add(-time.Second)}, nil)
+	mockSessions.On("GetByHash", auth.HashToken("orphaned")).
+		Return(storage.Session{UserID: 2, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mockSessions.On("GetByHash", auth.HashToken("unknown")).Return(storage.Session{}, storage.ErrSessionNotFound)
+	mockSessions.On("Delete", auth.HashToken("expired")).Return(nil)
+
+	session, err := app.AuthenticateSession(context.Background(), "valid")
+	require.NoError(t, err)
+	assert.Equal(t, "csrf", session.CSRFToken)
+	assert.Equal(t, "user:1", session.Principal.Subject)
+	assert.Equal(t, "jane@example.com", session.User.Email.String())
+
+	for _, token := range []string{"expired", "orphaned", "unknown"} {
+		_, err := app.AuthenticateSession(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidSession, token)
+	}
+	mockSessions.AssertCalled(t, "Delete", auth.HashToken("expired"))
+}
+
+func TestApplication_Logout(t *testing.T) {
+	app, _, mockSessions := newUserTestApp()
+	mockSessions.On("Delete", auth.HashToken("token")).Return(nil).Once()
+	mockSessions.On("Delete", auth.HashToken("token")).Return(storage.ErrSessionNotFound).Once()
+
+	require.NoError(t, app.Logout(context.Background(), "token"))
+	assert.NoError(t, app.Logout(context.Background(), "token"), "ending a session twice is fine")
+}
+
+func TestApplication_RevokeSessions(t *testing.T) {
+	app, _, mockSessions := newUserTestApp()
+	mockSessions.On("DeleteByUser", mock.Anything).Return(nil)
+	jane := auth.NewContext(context.Background(), auth.Principal{Subject: "user:1", Roles: []string{auth.RoleReader}})
+
+	require.NoError(t, app.RevokeSessions(jane, 1), "users revoke their own sessions")
+	assert.ErrorIs(t, app.RevokeSessions(jane, 2), ErrForbidden)
+	assert.ErrorIs(t, app.RevokeSessions(context.Background(), 2), ErrUnauthenticated)
+	require.NoError(t, app.RevokeSessions(adminCtx(), 2))
+
+	mockSessions.AssertCalled(t, "DeleteByUser", int64(1))
+	mockSessions.AssertCalled(t, "DeleteByUser", int64(2))
+	mockSessions.AssertNumberOfCalls(t, "DeleteByUser", 2)
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid post status change")
+	ErrInvalidPublishAt  = errors.New("scheduled posts need publish_at in the future")
+)
+
+// DefaultSchedulerInterval is how often scheduled posts are checked unless configured otherwise
+const DefaultSchedulerInterval = time.Minute
+
+// TransitionError tells which status change the workflow refused, it matches ErrInvalidTransition
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return "post status can't change from " + e.From + " to " + e.To
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// postTransitions lists the statuses each status may change to. Changes into or out of scheduled and published
+// need ActionPublishPost, the others are part of writing a post.
+var postTransitions = map[string][]string{
+	models.PostStatusDraft:     {models.PostStatusInReview, models.PostStatusScheduled, models.PostStatusPublished, models.PostStatusArchived},
+	models.PostStatusInReview:  {models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished, models.PostStatusArchived},
+	models.PostStatusScheduled: {models.PostStatusDraft, models.PostStatusPublished, models.PostStatusArchived},
+	models.PostStatusPublished: {models.PostStatusDraft, models.PostStatusArchived},
+	models.PostStatusArchived:  {models.PostStatusDraft, models.PostStatusPublished},
+}
+
+// postStatus returns the workflow status of the post, posts stored before statuses existed are published
+func postStatus(post storage.Post) string {
+	if post.Status == "" {
+		return models.PostStatusPublished
+	}
+	return post.Status
+}
+
+// publishing reports whether the status is controlled by ActionPublishPost
+func publishing(status string) bool {
+	return status == models.PostStatusScheduled || status == models.PostStatusPublished
+}
+
+// applyStatus checks the status change of the post against the workflow and fills its status and publish_at.
+// stored is the current state of the post, nil for new posts which start as drafts. An empty status keeps the
+// current one. publish_at is taken from the post for scheduled posts only, publishing records the time it happens.
+func (app *Application) applyStatus(ctx context.Context, post *storage.Post, stored *storage.Post, now time.Time) error {
+	from := models.PostStatusDraft
+	var publishAt time.Time
+	if stored != nil {
+		from = postStatus(*stored)
+		publishAt = stored.PublishAt
+	}
+	to := post.Status
+	if to == "" {
+		to = from
+	}
+
+	if to != from && !slices.Contains(postTransitions[from], to) {
+		return &TransitionError{From: from, To: to}
+	}
+	rescheduled := to == models.PostStatusScheduled && !post.PublishAt.Equal(publishAt)
+	if (to != from && (publishing(from) || publishing(to))) || rescheduled {
+		if _, err := app.authorize(ctx, ActionPublishPost); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case to == models.PostStatusScheduled:
+		if (from != to || rescheduled) && !post.PublishAt.After(now) {
+			return ErrInvalidPublishAt
+		}
+		publishAt = post.PublishAt.UTC()
+	case to == models.PostStatusPublished && from != to:
+		publishAt = now
+	case from == models.PostStatusScheduled:
+		publishAt = time.Time{}
+	}
+
+	post.Status = to
+	post.PublishAt = publishAt
+	return nil
+}
+
+// visible reports whether the post may be shown to the principal of the request.
+// Published posts are public, the others are seen only by those who may edit them.
+func (app *Application) visible(ctx context.Context, post storage.Post) bool {
+	return postStatus(post) == models.PostStatusPublished ||
+		app.authorizePost(ctx, post, ActionEditOwnPost, ActionEditAnyPost) == nil
+}
+
+// getVisiblePost returns the post by ID, posts the principal may not see are reported as ErrPostNotFound
+func (app *Application) getVisiblePost(ctx context.Context, id int) (storage.Post, error) {
+	post, err := app.repository.GetByID(ctx, id)
+	if err != nil {
+		return storage.Post{}, mapStorageError(err)
+	}
+	if !app.visible(ctx, post) {
+		return storage.Post{}, ErrPostNotFound
+	}
+	return post, nil
+}
+
+// RunScheduler publishes scheduled posts once their publish_at passes. It checks every interval and returns when
+// ctx is done, so it is meant to run in its own goroutine for the lifetime of the server.
+func (app *Application) RunScheduler(ctx context.Context, interval time.Duration) {
+	app.log(ctx).Info("Starting the post scheduler", slog.Duration("interval", interval))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		app.publishDue(ctx, time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			app.log(ctx).Info("Post scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishDue publishes scheduled posts with publish_at not after now and returns how many were published
+func (app *Application) publishDue(ctx context.Context, now time.Time) int {
+	posts, err := app.repository.GetAll(ctx)
+	if err != nil {
+		app.log(ctx).Error("Failed to load scheduled posts", "error", err)
+		return 0
+	}
+
+	published := 0
+	for _, post := range posts {
+		if ctx.Err() != nil {
+			break
+		}
+		if post.Status != models.PostStatusScheduled || post.PublishAt.After(now) {
+			continue
+		}
+
+		// Read the post again right before publishing, so a change made meanwhile isn't overwritten
+		current, err := app.repository.GetByID(ctx, int(post.ID))
+		if err != nil {
+			if !errors.Is(err, storage.ErrPostNotFound) {
+				app.log(ctx).Error("Failed to load scheduled post", "error", err, slog.Int64("post_id", post.ID))
+			}
+			continue
+		}
+		if current.Status != models.PostStatusScheduled || current.PublishAt.After(now) {
+			continue
+		}
+
+		current.Status = models.PostStatusPublished
+		updated, err := app.repository.Update(ctx, current)
+		if err != nil {
+			app.log(ctx).Error("Failed to publish scheduled post", "error", err, slog.Int64("post_id", post.ID))
+			continue
+		}
+
+		app.log(ctx).Info("Published scheduled post", slog.Int64("post_id", post.ID))
+		app.notifySaved(updated)
+		published++
+	}
+
+	return published
+}
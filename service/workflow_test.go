@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/auth"
+	"rakia_blog_tt/handler/models"
+	"rakia_blog_tt/storage"
+)
+
+func TestApplication_ApplyStatus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tomorrow := now.Add(24 * time.Hour)
+	yesterday := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name          string
+		principal     auth.Principal
+		stored        *storage.Post
+		post          storage.Post
+		wantStatus    string
+		wantPublishAt time.Time
+		wantErr       error
+	}{
+		{
+			name:       "new posts are drafts",
+			principal:  jane,
+			wantStatus: models.PostStatusDraft,
+		},
+		{
+			name:       "author submits for review",
+			principal:  jane,
+			stored:     &storage.Post{Status: models.PostStatusDraft},
+			post:       storage.Post{Status: models.PostStatusInReview},
+			wantStatus: models.PostStatusInReview,
+		},
+		{
+			name:       "empty status keeps the stored one",
+			principal:  jane,
+			stored:     &storage.Post{Status: models.PostStatusInReview},
+			wantStatus: models.PostStatusInReview,
+		},
+		{
+			name:          "author edits a published post",
+			principal:     jane,
+			stored:        &storage.Post{Status: models.PostStatusPublished, PublishAt: yesterday},
+			post:          storage.Post{Status: models.PostStatusPublished, PublishAt: tomorrow},
+			wantStatus:    models.PostStatusPublished,
+			wantPublishAt: yesterday,
+		},
+		{
+			name:      "author can't publish",
+			principal: jane,
+			stored:    &storage.Post{Status: models.PostStatusInReview},
+			post:      storage.Post{Status: models.PostStatusPublished},
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "author can't unpublish",
+			principal: jane,
+			stored:    &storage.Post{Status: models.PostStatusPublished},
+			post:      storage.Post{Status: models.PostStatusDraft},
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "author can't create published posts",
+			principal: jane,
+			post:      storage.Post{Status: models.PostStatusPublished},
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:          "editor publishes",
+			principal:     editor,
+			stored:        &storage.Post{Status: models.PostStatusInReview},
+			post:          storage.Post{Status: models.PostStatusPublished},
+			wantStatus:    models.PostStatusPublished,
+			wantPublishAt: now,
+		},
+		{
+			name:          "editor schedules",
+			principal:     editor,
+			stored:        &storage.Post{Status: models.PostStatusInReview},
+			post:          storage.Post{Status: models.PostStatusScheduled, PublishAt: tomorrow},
+			wantStatus:    models.PostStatusScheduled,
+			wantPublishAt: tomorrow,
+		},
+		{
+			name:      "scheduling needs a future time",
+			principal: editor,
+			stored:    &storage.Post{Status: models.PostStatusDraft},
+			post:      storage.Post{Status: models.PostStatusScheduled, PublishAt: yesterday},
+			wantErr:   ErrInvalidPublishAt,
+		},
+		{
+			name:      "scheduling needs a time",
+			principal: editor,
+			post:      storage.Post{Status: models.PostStatusScheduled},
+			wantErr:   ErrInvalidPublishAt,
+		},
+		{
+			name:      "author can't reschedule",
+			principal: jane,
+			stored:    &storage.Post{Status: models.PostStatusScheduled, PublishAt: tomorrow},
+			post:      storage.Post{Status: models.PostStatusScheduled, PublishAt: tomorrow.Add(time.Hour)},
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:          "author edits a scheduled post",
+			principal:     jane,
+			stored:        &storage.Post{Status: models.PostStatusScheduled, PublishAt: tomorrow},
+			post:          storage.Post{PublishAt: tomorrow},
+			wantStatus:    models.PostStatusScheduled,
+			wantPublishAt: tomorrow,
+		},
+		{
+			name:       "unscheduling clears publish_at",
+			principal:  editor,
+			stored:     &storage.Post{Status: models.PostStatusScheduled, PublishAt: tomorrow},
+			post:       storage.Post{Status: models.PostStatusDraft, PublishAt: tomorrow},
+			wantStatus: models.PostStatusDraft,
+		},
+		{
+			name:          "archiving keeps the publication time",
+			principal:     editor,
+			stored:        &storage.Post{Status: models.PostStatusPublished, PublishAt: yesterday},
+			post:          storage.Post{Status: models.PostStatusArchived},
+			wantStatus:    models.PostStatusArchived,
+			wantPublishAt: yesterday,
+		},
+		{
+			name:          "posts without status are published",
+			principal:     editor,
+			stored:        &storage.Post{},
+			post:          storage.Post{Status: models.PostStatusArchived},
+			wantStatus:    models.PostStatusArchived,
+			wantPublishAt: time.Time{},
+		},
+		{
+			name:      "published posts can't go back to review",
+			principal: admin,
+			stored:    &storage.Post{Status: models.PostStatusPublished},
+			post:      storage.Post{Status: models.PostStatusInReview},
+			wantErr:   ErrInvalidTransition,
+		},
+		{
+			name:      "archived posts can't be scheduled",
+			principal: admin,
+			stored:    &storage.Post{Status: models.PostStatusArchived},
+			post:      storage.Post{Status: models.PostStatusScheduled, PublishAt: tomorrow},
+			wantErr:   ErrInvalidTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := New(new(MockRepo), loggerMock())
+			ctx := auth.NewContext(context.Background(), tt.principal)
+
+			post := tt.post
+			err := app.applyStatus(ctx, &post, tt.stored, now)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, post.Status)
+			assert.Equal(t, tt.wantPublishAt, post.PublishAt)
+		})
+	}
+}
+
+func TestApplication_ApplyStatus_TransitionError(t *testing.T) {
+	app := New(new(MockRepo), loggerMock())
+	post := storage.Post{Status: models.PostStatusInReview}
+
+	err := app.applyStatus(adminCtx(), &post, &storage.Post{Status: models.PostStatusArchived}, time.Now())
+	var transition *TransitionError
+	require.ErrorAs(t, err, &transition)
+	assert.Equal(t, TransitionError{From: models.PostStatusArchived, To: models.PostStatusInReview}, *transition)
+}
+
+func TestApplication_PostVisibility(t *testing.T) {
+	posts := []storage.Post{
+		{ID: 1, Title: "Published", Author: "John Roe", AuthorID: 2, Status: models.PostStatusPublished},
+		{ID: 2, Title: "Jane's draft", Author: "Jane Doe", AuthorID: 1, Status: models.PostStatusDraft},
+		{ID: 3, Title: "John's review", Author: "John Roe", AuthorID: 2, Status: models.PostStatusInReview},
+		{ID: 4, Title: "Legacy", Author: "John Roe"},
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantIDs []int64
+	}{
+		{name: "anonymous", ctx: context.Background(), wantIDs: []int64{1, 4}},
+		{name: "author sees own drafts", ctx: auth.NewContext(context.Background(), jane), wantIDs: []int64{1, 2, 4}},
+		{name: "editor sees every post", ctx: auth.NewContext(context.Background(), editor), wantIDs: []int64{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepo)
+			app := New(mockRepo, loggerMock())
+			mockRepo.On("GetAll").Return(posts, nil)
+			for _, post := range posts {
+				mockRepo.On("GetByID", int(post.ID)).Return(post, nil)
+			}
+
+			listed, err := app.GetPosts(tt.ctx)
+			require.NoError(t, err)
+			var ids []int64
+			for _, post := range listed {
+				ids = append(ids, post.ID)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+
+			for _, post := range posts {
+				_, err := app.GetPostByID(tt.ctx, int(post.ID))
+				if slices.Contains(tt.wantIDs, post.ID) {
+					assert.NoError(t, err, post.Title)
+				} else {
+					assert.ErrorIs(t, err, ErrPostNotFound, "hidden posts look like missing ones")
+				}
+			}
+		})
+	}
+}
+
+func TestApplication_FindPosts_Status(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+	mockRepo.On("GetAll").Return([]storage.Post{
+		{ID: 1, Status: models.PostStatusPublished},
+		{ID: 2, Status: models.PostStatusInReview},
+		{ID: 3, Status: models.PostStatusInReview},
+	}, nil)
+
+	posts, err := app.FindPosts(adminCtx(), PostFilter{Status: models.PostStatusInReview})
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	assert.Equal(t, int64(2), posts[0].ID)
+	assert.Equal(t, int64(3), posts[1].ID)
+}
+
+func TestApplication_NotifiesListeners_OnlyPublished(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mockListener := new(MockListener)
+	app := New(mockRepo, loggerMock(), WithPostListener(mockListener))
+
+	mockRepo.On("Create", mock.Anything).Return(storage.Post{ID: 1, Status: models.PostStatusDraft}, nil)
+	mockListener.On("PostDeleted", int64(1)).Return()
+
+	_, err := app.CreatePost(adminCtx(), models.Post{Title: "Title", Author: "Jane Doe"})
+	require.NoError(t, err)
+	mockListener.AssertNotCalled(t, "PostSaved", mock.Anything)
+	mockListener.AssertCalled(t, "PostDeleted", int64(1))
+}
+
+func TestApplication_PublishDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	due := storage.Post{ID: 1, Status: models.PostStatusScheduled, PublishAt: now.Add(-time.Minute)}
+	later := storage.Post{ID: 2, Status: models.PostStatusScheduled, PublishAt: now.Add(time.Minute)}
+	unscheduled := storage.Post{ID: 3, Status: models.PostStatusScheduled, PublishAt: now.Add(-time.Minute)}
+
+	mockRepo := new(MockRepo)
+	mockListener := new(MockListener)
+	app := New(mockRepo, loggerMock(), WithPostListener(mockListener))
+	mockRepo.On("GetAll").Return([]storage.Post{due, later, unscheduled, {ID: 4, Status: models.PostStatusDraft}}, nil)
+	mockRepo.On("GetByID", 1).Return(due, nil)
+	// Unscheduled by an editor after the list was read
+	mockRepo.On("GetByID", 3).Return(storage.Post{ID: 3, Status: models.PostStatusDraft}, nil)
+
+	published := due
+	published.Status = models.PostStatusPublished
+	mockRepo.On("Update", published).Return(published, nil).Once()
+	mockListener.On("PostSaved", mock.MatchedBy(func(p models.Post) bool { return p.ID == 1 })).Return()
+
+	assert.Equal(t, 1, app.publishDue(context.Background(), now))
+	mockRepo.AssertExpectations(t)
+	mockListener.AssertExpectations(t)
+}
+
+func TestApplication_RunScheduler_StopsWithContext(t *testing.T) {
+	mockRepo := new(MockRepo)
+	app := New(mockRepo, loggerMock())
+	mockRepo.On("GetAll").Return([]storage.Post{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.RunScheduler(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler didn't stop")
+	}
+	mockRepo.AssertCalled(t, "GetAll")
+}
@@ -0,0 +1,328 @@
+// Package sitemap keeps the XML sitemap of the blog up to date as posts change.
+// Files are rebuilt lazily and only for the parts affected by changes since the previous build.
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"rakia_blog_tt/handler/models"
+)
+
+const (
+	// MaxURLs is the limit of URLs in a single sitemap file defined by the sitemaps protocol
+	MaxURLs = 50000
+
+	// IndexFile is the entry point for crawlers. It's a plain sitemap while all URLs fit into a single file
+	// and a sitemap index referencing gzip-compressed child sitemaps otherwise.
+	IndexFile = "sitemap.xml"
+
+	// ChildPath is the path child sitemaps are served under
+	ChildPath = "/sitemaps/"
+
+	namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// URLBuilder returns the absolute URL of the public post page
+type URLBuilder func(post models.Post) string
+
+type Options struct {
+	// BaseURL is the absolute URL of the service, child sitemaps are referenced relative to it
+	BaseURL string
+	PostURL URLBuilder
+	// ChunkSize is the maximum number of URLs per file, MaxURLs when not set
+	ChunkSize int
+}
+
+type entry struct {
+	id      int64
+	loc     string
+	lastMod time.Time
+}
+
+// chunk holds posts with IDs in [n*size+1, (n+1)*size]. Assigning posts by ID keeps chunks stable,
+// so a change touches exactly one child sitemap.
+type chunk struct {
+	entries map[int64]entry
+	dirty   bool
+	file    []byte
+	// changed is the time of the last change of the chunk content, it's the modification time of the file
+	changed time.Time
+}
+
+type file struct {
+	content  []byte
+	modified time.Time
+}
+
+// Generator implements service.PostListener
+type Generator struct {
+	mu        sync.Mutex
+	baseURL   string
+	postURL   URLBuilder
+	chunkSize int
+	chunks    map[int64]*chunk
+	total     int
+	changed   time.Time
+	// index is the cached IndexFile, nil when it has to be rebuilt
+	index *file
+}
+
+func New(opts Options) *Generator {
+	if opts.ChunkSize <= 0 || opts.ChunkSize > MaxURLs {
+		opts.ChunkSize = MaxURLs
+	}
+
+	return &Generator{
+		baseURL:   strings.TrimSuffix(opts.BaseURL, "/"),
+		postURL:   opts.PostURL,
+		chunkSize: opts.ChunkSize,
+		chunks:    make(map[int64]*chunk),
+	}
+}
+
+// Reset replaces the content of the sitemap, used to fill it on startup
+func (g *Generator) Reset(posts []models.Post) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.chunks = make(map[int64]*chunk)
+	g.total = 0
+	for _, post := range posts {
+		g.save(post)
+	}
+	g.touch(nil)
+	for _, c := range g.chunks {
+		c.changed = g.changed
+	}
+}
+
+func (g *Generator) PostSaved(post models.Post) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.touch(g.save(post))
+}
+
+func (g *Generator) PostDeleted(id int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c, ok := g.chunks[g.chunkOf(id)]
+	if !ok {
+		return
+	}
+	if _, ok := c.entries[id]; !ok {
+		return
+	}
+
+	delete(c.entries, id)
+	g.total--
+	if len(c.entries) == 0 {
+		delete(g.chunks, g.chunkOf(id))
+	}
+	g.touch(c)
+}
+
+// touch marks the chunk and the index as changed
+func (g *Generator) touch(c *chunk) {
+	g.changed = time.Now().UTC()
+	g.index = nil
+	if c != nil {
+		c.dirty = true
+		c.changed = g.changed
+	}
+}
+
+func (g *Generator) save(post models.Post) *chunk {
+	n := g.chunkOf(post.ID)
+	c, ok := g.chunks[n]
+	if !ok {
+		c = &chunk{entries: make(map[int64]entry)}
+		g.chunks[n] = c
+	}
+
+	if _, exists := c.entries[post.ID]; !exists {
+		g.total++
+	}
+
+	lastMod := time.Time(post.UpdatedAt)
+	if lastMod.IsZero() {
+		lastMod = time.Time(post.CreatedAt)
+	}
+	c.entries[post.ID] = entry{id: post.ID, loc: g.postURL(post), lastMod: lastMod.UTC()}
+	c.dirty = true
+	return c
+}
+
+func (g *Generator) chunkOf(id int64) int64 {
+	return (id - 1) / int64(g.chunkSize)
+}
+
+// File returns a sitemap file by its name: IndexFile or a child sitemap referenced from the index
+func (g *Generator) File(name string) ([]byte, time.Time, bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if name == IndexFile {
+		if err := g.buildIndex(); err != nil {
+			return nil, time.Time{}, false, err
+		}
+		return g.index.content, g.index.modified, true, nil
+	}
+
+	if !g.split() {
+		return nil, time.Time{}, false, nil
+	}
+
+	var number int64
+	if _, err := fmt.Sscanf(name, "sitemap-%d.xml.gz", &number); err != nil || childName(number-1) != name {
+		return nil, time.Time{}, false, nil
+	}
+	c, ok := g.chunks[number-1]
+	if !ok {
+		return nil, time.Time{}, false, nil
+	}
+	if err := g.buildChunk(c); err != nil {
+		return nil, time.Time{}, false, err
+	}
+
+	return c.file, c.changed, true, nil
+}
+
+// split reports whether URLs no longer fit into a single file
+func (g *Generator) split() bool {
+	return g.total > g.chunkSize
+}
+
+func (g *Generator) buildIndex() error {
+	if g.index != nil {
+		return nil
+	}
+
+	if !g.split() {
+		var entries []entry
+		for _, c := range g.chunks {
+			for _, e := range c.entries {
+				entries = append(entries, e)
+			}
+		}
+		content, err := encodeURLSet(entries)
+		if err != nil {
+			return err
+		}
+		g.index = &file{content: content, modified: g.changed}
+		return nil
+	}
+
+	numbers := make([]int64, 0, len(g.chunks))
+	for n := range g.chunks {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	index := sitemapIndex{XMLNS: namespace}
+	for _, n := range numbers {
+		index.Sitemaps = append(index.Sitemaps, sitemapRef{
+			Loc:     g.baseURL + ChildPath + childName(n),
+			LastMod: formatTime(g.chunks[n].changed),
+		})
+	}
+
+	content, err := encode(index)
+	if err != nil {
+		return err
+	}
+	g.index = &file{content: content, modified: g.changed}
+	return nil
+}
+
+// buildChunk compresses the child sitemap again only if its posts changed since the last build
+func (g *Generator) buildChunk(c *chunk) error {
+	if !c.dirty && c.file != nil {
+		return nil
+	}
+
+	entries := make([]entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	content, err := encodeURLSet(entries)
+	if err != nil {
+		return err
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(content); err != nil {
+		return errors.Wrap(err, "compress sitemap")
+	}
+	if err := zw.Close(); err != nil {
+		return errors.Wrap(err, "compress sitemap")
+	}
+
+	c.file = compressed.Bytes()
+	c.dirty = false
+	return nil
+}
+
+// childName is the file name of the n-th chunk, numbered from 1 for humans
+func childName(n int64) string {
+	return fmt.Sprintf("sitemap-%d.xml.gz", n+1)
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	URLs    []url    `xml:"url"`
+}
+
+type url struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapRef `xml:"sitemap"`
+}
+
+type sitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+func encodeURLSet(entries []entry) ([]byte, error) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+
+	set := urlSet{XMLNS: namespace, URLs: make([]url, 0, len(entries))}
+	for _, e := range entries {
+		set.URLs = append(set.URLs, url{Loc: e.loc, LastMod: formatTime(e.lastMod)})
+	}
+	return encode(set)
+}
+
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, errors.Wrap(err, "encode sitemap")
+	}
+	return buf.Bytes(), nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
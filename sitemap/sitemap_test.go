@@ -0,0 +1,147 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"rakia_blog_tt/handler/models"
+)
+
+type testURLSet struct {
+	XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+}
+
+type testIndex struct {
+	XMLName  xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"sitemap"`
+}
+
+var updatedAt = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func newGenerator(chunkSize int) *Generator {
+	return New(Options{
+		BaseURL:   "http://blog.test/",
+		PostURL:   func(post models.Post) string { return fmt.Sprintf("http://blog.test/blog/posts/%d", post.ID) },
+		ChunkSize: chunkSize,
+	})
+}
+
+func post(id int64) models.Post {
+	return models.Post{ID: id, UpdatedAt: strfmt.DateTime(updatedAt)}
+}
+
+func gunzip(t *testing.T, content []byte) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return data
+}
+
+func TestGenerator_SingleFile(t *testing.T) {
+	g := newGenerator(3)
+	g.Reset([]models.Post{post(2), post(1)})
+	g.PostSaved(post(3))
+
+	content, modified, ok, err := g.File(IndexFile)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.False(t, modified.IsZero())
+
+	var set testURLSet
+	require.NoError(t, xml.Unmarshal(content, &set))
+	require.Len(t, set.URLs, 3)
+	assert.Equal(t, "http://blog.test/blog/posts/1", set.URLs[0].Loc)
+	assert.Equal(t, "2024-05-01T10:00:00Z", set.URLs[0].LastMod)
+
+	_, _, ok, err = g.File("sitemap-1.xml.gz")
+	require.NoError(t, err)
+	assert.False(t, ok, "child sitemaps exist only after splitting")
+}
+
+func TestGenerator_SplitIntoIndex(t *testing.T) {
+	g := newGenerator(2)
+	g.Reset([]models.Post{post(1), post(2), post(3), post(4), post(5)})
+
+	content, _, ok, err := g.File(IndexFile)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var index testIndex
+	require.NoError(t, xml.Unmarshal(content, &index))
+	require.Len(t, index.Sitemaps, 3)
+	assert.Equal(t, "http://blog.test/sitemaps/sitemap-1.xml.gz", index.Sitemaps[0].Loc)
+	assert.Equal(t, "http://blog.test/sitemaps/sitemap-3.xml.gz", index.Sitemaps[2].Loc)
+	assert.NotEmpty(t, index.Sitemaps[0].LastMod)
+
+	child, _, ok, err := g.File("sitemap-3.xml.gz")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var set testURLSet
+	require.NoError(t, xml.Unmarshal(gunzip(t, child), &set))
+	require.Len(t, set.URLs, 1)
+	assert.Equal(t, "http://blog.test/blog/posts/5", set.URLs[0].Loc)
+
+	_, _, ok, err = g.File("sitemap-4.xml.gz")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestGenerator_IncrementalRebuild(t *testing.T) {
+	g := newGenerator(2)
+	g.Reset([]models.Post{post(1), post(2), post(3), post(4)})
+	g.PostSaved(post(5))
+
+	first, _, _, err := g.File("sitemap-1.xml.gz")
+	require.NoError(t, err)
+	third, _, _, err := g.File("sitemap-3.xml.gz")
+	require.NoError(t, err)
+
+	g.PostSaved(models.Post{ID: 6, UpdatedAt: strfmt.DateTime(updatedAt.Add(time.Hour))})
+
+	unchanged, _, _, err := g.File("sitemap-1.xml.gz")
+	require.NoError(t, err)
+	assert.Same(t, &first[0], &unchanged[0], "unchanged child sitemap must not be rebuilt")
+
+	rebuilt, _, _, err := g.File("sitemap-3.xml.gz")
+	require.NoError(t, err)
+	assert.NotSame(t, &third[0], &rebuilt[0])
+
+	var set testURLSet
+	require.NoError(t, xml.Unmarshal(gunzip(t, rebuilt), &set))
+	require.Len(t, set.URLs, 2)
+	assert.Equal(t, "2024-05-01T11:00:00Z", set.URLs[1].LastMod)
+}
+
+func TestGenerator_PostDeleted(t *testing.T) {
+	g := newGenerator(2)
+	g.Reset([]models.Post{post(1), post(2), post(3)})
+
+	g.PostDeleted(3)
+	g.PostDeleted(42)
+
+	content, _, _, err := g.File(IndexFile)
+	require.NoError(t, err)
+
+	var set testURLSet
+	require.NoError(t, xml.Unmarshal(content, &set), "back to a single file after falling under the limit")
+	assert.Len(t, set.URLs, 2)
+}
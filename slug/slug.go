@@ -0,0 +1,96 @@
+// Package slug turns post titles into URL path segments.
+package slug
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxLength limits generated slugs, longer titles are cut on a word boundary
+	MaxLength = 80
+
+	// Fallback is used when nothing of the title survives transliteration, e.g. for emoji only titles
+	Fallback = "post"
+)
+
+// Pattern is the format of a valid slug: lowercase ASCII words separated by single hyphens
+var Pattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// transliterations covers letters that have no ASCII base after Unicode decomposition
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i", 'ħ': "h",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya", 'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k",
+	'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t",
+	'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Make builds a slug from the text. Letters with diacritics are reduced to their base letter,
+// Cyrillic and Greek are transliterated, everything else that is not a letter or digit separates words.
+func Make(text string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range norm.NFKD.String(strings.ToLower(text)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue // combining marks left by the decomposition
+		}
+
+		var word string
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word = string(r)
+		case transliterations[r] != "":
+			word = transliterations[r]
+		default:
+			hyphen = b.Len() > 0
+			continue
+		}
+
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteString(word)
+	}
+
+	return truncate(b.String())
+}
+
+// truncate cuts the slug to MaxLength, dropping the last partial word
+func truncate(s string) string {
+	if len(s) <= MaxLength {
+		return s
+	}
+	s = s[:MaxLength+1]
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		return s[:i]
+	}
+	return s[:MaxLength]
+}
+
+// WithSuffix returns the n-th candidate for a slug that is already taken: "title", "title-2", "title-3"...
+func WithSuffix(base string, n int) string {
+	if n <= 1 {
+		return base
+	}
+	suffix := "-" + strconv.Itoa(n)
+	if len(base)+len(suffix) > MaxLength {
+		base = strings.TrimRight(base[:MaxLength-len(suffix)], "-")
+	}
+	return base + suffix
+}
+
+// Valid reports whether s can be used as a slug as is
+func Valid(s string) bool {
+	return len(s) <= MaxLength && Pattern.MatchString(s)
+}
@@ -0,0 +1,58 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"ascii", "Hello World", "hello-world"},
+		{"punctuation", "  Go 1.22: what's new?! ", "go-1-22-what-s-new"},
+		{"diacritics", "Crème brûlée à la française", "creme-brulee-a-la-francaise"},
+		{"special latin", "Straße Łódź Ærø", "strasse-lodz-aero"},
+		{"cyrillic", "Привет, мир", "privet-mir"},
+		{"greek", "Καλημέρα κόσμε", "kalimera-kosme"},
+		{"ligatures", "ﬁle ½", "file-1-2"},
+		{"unsupported script only", "日本語", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Make(tt.title))
+		})
+	}
+}
+
+func TestMakeTruncates(t *testing.T) {
+	s := Make(strings.Repeat("word ", 30))
+
+	assert.LessOrEqual(t, len(s), MaxLength)
+	assert.True(t, Valid(s))
+	assert.True(t, strings.HasSuffix(s, "-word"))
+}
+
+func TestWithSuffix(t *testing.T) {
+	assert.Equal(t, "title", WithSuffix("title", 1))
+	assert.Equal(t, "title-3", WithSuffix("title", 3))
+
+	long := Make(strings.Repeat("a", MaxLength))
+	suffixed := WithSuffix(long, 12)
+	assert.Len(t, suffixed, MaxLength)
+	assert.True(t, strings.HasSuffix(suffixed, "-12"))
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("hello-world-2"))
+	assert.False(t, Valid("Hello"))
+	assert.False(t, Valid("hello--world"))
+	assert.False(t, Valid("-hello"))
+	assert.False(t, Valid(""))
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKey is a long lived credential of a machine client. Only the hash of the key is kept.
+type APIKey struct {
+	ID   int64
+	Name string
+	// Prefix is the start of the key, so admins can tell keys apart without the key itself
+	Prefix   string
+	Hash     string
+	Scopes   []string
+	AuthorID int64
+	// ExpiresAt is zero for keys that don't expire
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
+
+// InMemoryAPIKeyRepository implements the APIKeyRepo interface
+type InMemoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	data   map[int64]APIKey
+	hashes map[string]int64 // key hash -> key ID
+	nextID int64
+	logger *slog.Logger
+}
+
+// NewInMemoryAPIKeyRepository creates a new in-memory API key repository
+func NewInMemoryAPIKeyRepository(logger *slog.Logger) *InMemoryAPIKeyRepository {
+	return &InMemoryAPIKeyRepository{
+		data:   make(map[int64]APIKey),
+		hashes: make(map[string]int64),
+		nextID: 1,
+		logger: logger,
+	}
+}
+
+// Create stores a new key and returns it with the generated fields filled
+func (repo *InMemoryAPIKeyRepository) Create(key APIKey) (APIKey, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key.ID = repo.nextID
+	key.Scopes = append([]string(nil), key.Scopes...)
+	key.CreatedAt = time.Now().UTC()
+	repo.nextID++
+	repo.data[key.ID] = key
+	repo.hashes[key.Hash] = key.ID
+	return key, nil
+}
+
+// GetAll returns every key ordered by ID
+func (repo *InMemoryAPIKeyRepository) GetAll() ([]APIKey, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(repo.data))
+	for _, key := range repo.data {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// GetByHash finds the key by the hash of its secret
+func (repo *InMemoryAPIKeyRepository) GetByHash(hash string) (APIKey, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	id, ok := repo.hashes[hash]
+	if !ok {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return repo.data[id], nil
+}
+
+// Touch records that the key was used at the given time
+func (repo *InMemoryAPIKeyRepository) Touch(id int64, at time.Time) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key, ok := repo.data[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	key.LastUsedAt = at
+	repo.data[id] = key
+	return nil
+}
+
+func (repo *InMemoryAPIKeyRepository) Delete(id int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key, ok := repo.data[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	delete(repo.data, id)
+	delete(repo.hashes, key.Hash)
+	return nil
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryAPIKeyRepository(t *testing.T) {
+	repo := NewInMemoryAPIKeyRepository(loggerMock())
+
+	scopes := []string{"posts:read"}
+	first, err := repo.Create(APIKey{Name: "CI", Prefix: "bk_abc", Hash: "hash-1", Scopes: scopes})
+	require.NoError(t, err)
+	second, err := repo.Create(APIKey{Name: "Importer", Hash: "hash-2"})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.False(t, first.CreatedAt.IsZero())
+
+	scopes[0] = "admin"
+	found, err := repo.GetByHash("hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, found.ID)
+	assert.Equal(t, []string{"posts:read"}, found.Scopes, "scopes are copied on create")
+
+	_, err = repo.GetByHash("hash-3")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	usedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Touch(first.ID, usedAt))
+	found, err = repo.GetByHash("hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, usedAt, found.LastUsedAt)
+	assert.ErrorIs(t, repo.Touch(42, usedAt), ErrAPIKeyNotFound)
+
+	keys, err := repo.GetAll()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, first.ID, keys[0].ID)
+
+	require.NoError(t, repo.Delete(first.ID))
+	_, err = repo.GetByHash("hash-1")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound, "revoked keys stop working")
+	assert.ErrorIs(t, repo.Delete(first.ID), ErrAPIKeyNotFound)
+}
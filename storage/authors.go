@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrAuthorNotFound  = errors.New("author not found")
+	ErrAuthorNameTaken = errors.New("author name is taken")
+)
+
+type Author struct {
+	ID        int64
+	Name      string // Unique ignoring case and repeated spaces
+	Bio       string
+	AvatarURL string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// InMemoryAuthorRepository implements the AuthorRepo interface
+type InMemoryAuthorRepository struct {
+	mu     sync.RWMutex
+	data   map[int64]Author
+	names  map[string]int64 // name key -> author ID
+	nextID int64
+	logger *slog.Logger
+}
+
+// NewInMemoryAuthorRepository creates a new in-memory author repository
+func NewInMemoryAuthorRepository(logger *slog.Logger) *InMemoryAuthorRepository {
+	return &InMemoryAuthorRepository{
+		data:   make(map[int64]Author),
+		names:  make(map[string]int64),
+		nextID: 1,
+		logger: logger,
+	}
+}
+
+// AuthorKey identifies an author name, so "Author 1" and "author  1" are the same person
+func AuthorKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Create stores a new author and returns it with the generated fields filled
+func (repo *InMemoryAuthorRepository) Create(author Author) (Author, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := AuthorKey(author.Name)
+	if _, ok := repo.names[key]; ok {
+		return Author{}, ErrAuthorNameTaken
+	}
+
+	author.ID = repo.nextID
+	author.CreatedAt = time.Now().UTC()
+	author.UpdatedAt = author.CreatedAt
+	repo.nextID++
+	repo.data[author.ID] = author
+	repo.names[key] = author.ID
+	return author, nil
+}
+
+// GetAll returns every author ordered by ID
+func (repo *InMemoryAuthorRepository) GetAll() ([]Author, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	authors := make([]Author, 0, len(repo.data))
+	for _, author := range repo.data {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool { return authors[i].ID < authors[j].ID })
+	return authors, nil
+}
+
+func (repo *InMemoryAuthorRepository) GetByID(id int64) (Author, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	author, ok := repo.data[id]
+	if !ok {
+		return Author{}, ErrAuthorNotFound
+	}
+	return author, nil
+}
+
+// GetByName finds the author ignoring case and repeated spaces
+func (repo *InMemoryAuthorRepository) GetByName(name string) (Author, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	id, ok := repo.names[AuthorKey(name)]
+	if !ok {
+		return Author{}, ErrAuthorNotFound
+	}
+	return repo.data[id], nil
+}
+
+// Update replaces a stored author and returns the new state
+func (repo *InMemoryAuthorRepository) Update(author Author) (Author, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.data[author.ID]
+	if !ok {
+		return Author{}, ErrAuthorNotFound
+	}
+	key := AuthorKey(author.Name)
+	if id, ok := repo.names[key]; ok && id != author.ID {
+		return Author{}, ErrAuthorNameTaken
+	}
+
+	delete(repo.names, AuthorKey(stored.Name))
+	repo.names[key] = author.ID
+	author.CreatedAt = stored.CreatedAt
+	author.UpdatedAt = time.Now().UTC()
+	repo.data[author.ID] = author
+	return author, nil
+}
+
+func (repo *InMemoryAuthorRepository) Delete(id int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	author, ok := repo.data[id]
+	if !ok {
+		return ErrAuthorNotFound
+	}
+	delete(repo.data, id)
+	delete(repo.names, AuthorKey(author.Name))
+	return nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryAuthorRepository(t *testing.T) {
+	repo := NewInMemoryAuthorRepository(loggerMock())
+
+	first, err := repo.Create(Author{Name: "Author 1", Bio: "Writes about Go"})
+	require.NoError(t, err)
+	second, err := repo.Create(Author{Name: "Author 2"})
+	require.NoError(t, err)
+
+	t.Run("Names are unique ignoring case and spaces", func(t *testing.T) {
+		_, err := repo.Create(Author{Name: " author   1 "})
+		assert.ErrorIs(t, err, ErrAuthorNameTaken)
+
+		found, err := repo.GetByName("AUTHOR 1")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, found.ID)
+
+		_, err = repo.GetByName("Author 3")
+		assert.ErrorIs(t, err, ErrAuthorNotFound)
+	})
+
+	t.Run("Get All", func(t *testing.T) {
+		authors, err := repo.GetAll()
+		require.NoError(t, err)
+		require.Len(t, authors, 2)
+		assert.Equal(t, first.ID, authors[0].ID)
+		assert.Equal(t, second.ID, authors[1].ID)
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		second.Name = "Author 1"
+		_, err := repo.Update(second)
+		assert.ErrorIs(t, err, ErrAuthorNameTaken)
+
+		first.Name = "Renamed"
+		updated, err := repo.Update(first)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", updated.Name)
+		assert.Equal(t, first.CreatedAt, updated.CreatedAt)
+
+		_, err = repo.GetByName("Author 1")
+		assert.ErrorIs(t, err, ErrAuthorNotFound, "former name is released")
+		found, err := repo.GetByName("renamed")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, found.ID)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, repo.Delete(first.ID))
+		assert.ErrorIs(t, repo.Delete(first.ID), ErrAuthorNotFound)
+
+		_, err := repo.GetByName("Renamed")
+		assert.ErrorIs(t, err, ErrAuthorNotFound)
+	})
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+type Category struct {
+	ID        int64
+	Name      string
+	ParentID  int64 // 0 for root categories
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// InMemoryCategoryRepository implements the CategoryRepo interface.
+// Tree consistency (existing parents, no cycles) is up to the caller.
+type InMemoryCategoryRepository struct {
+	mu     sync.RWMutex
+	data   map[int64]Category
+	nextID int64
+	logger *slog.Logger
+}
+
+// NewInMemoryCategoryRepository creates a new in-memory category repository
+func NewInMemoryCategoryRepository(logger *slog.Logger) *InMemoryCategoryRepository {
+	return &InMemoryCategoryRepository{data: make(map[int64]Category), nextID: 1, logger: logger}
+}
+
+// Create stores a new category and returns it with the generated fields filled
+func (repo *InMemoryCategoryRepository) Create(category Category) (Category, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	category.ID = repo.nextID
+	category.CreatedAt = time.Now().UTC()
+	category.UpdatedAt = category.CreatedAt
+	repo.nextID++
+	repo.data[category.ID] = category
+	return category, nil
+}
+
+// GetAll returns every category ordered by ID
+func (repo *InMemoryCategoryRepository) GetAll() ([]Category, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	categories := make([]Category, 0, len(repo.data))
+	for _, category := range repo.data {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories, nil
+}
+
+func (repo *InMemoryCategoryRepository) GetByID(id int64) (Category, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	category, ok := repo.data[id]
+	if !ok {
+		return Category{}, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// Update replaces a stored category and returns the new state
+func (repo *InMemoryCategoryRepository) Update(category Category) (Category, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.data[category.ID]
+	if !ok {
+		return Category{}, ErrCategoryNotFound
+	}
+
+	category.CreatedAt = stored.CreatedAt
+	category.UpdatedAt = time.Now().UTC()
+	repo.data[category.ID] = category
+	return category, nil
+}
+
+func (repo *InMemoryCategoryRepository) Delete(id int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.data[id]; !ok {
+		return ErrCategoryNotFound
+	}
+	delete(repo.data, id)
+	return nil
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCategoryRepository(t *testing.T) {
+	repo := NewInMemoryCategoryRepository(loggerMock())
+
+	engineering, err := repo.Create(Category{Name: "Engineering"})
+	require.NoError(t, err)
+	backend, err := repo.Create(Category{Name: "Backend", ParentID: engineering.ID})
+	require.NoError(t, err)
+
+	t.Run("Create Category", func(t *testing.T) {
+		assert.Equal(t, int64(1), engineering.ID)
+		assert.Equal(t, int64(2), backend.ID)
+		assert.False(t, backend.CreatedAt.IsZero())
+	})
+
+	t.Run("Get All Categories", func(t *testing.T) {
+		categories, err := repo.GetAll()
+		require.NoError(t, err)
+		require.Len(t, categories, 2)
+		assert.Equal(t, "Engineering", categories[0].Name)
+		assert.Equal(t, "Backend", categories[1].Name)
+	})
+
+	t.Run("Update Category", func(t *testing.T) {
+		backend.Name = "Back end"
+		updated, err := repo.Update(backend)
+		require.NoError(t, err)
+		assert.Equal(t, backend.CreatedAt, updated.CreatedAt)
+
+		stored, err := repo.GetByID(backend.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Back end", stored.Name)
+
+		_, err = repo.Update(Category{ID: 42})
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+	})
+
+	t.Run("Delete Category", func(t *testing.T) {
+		require.NoError(t, repo.Delete(backend.ID))
+
+		_, err := repo.GetByID(backend.ID)
+		assert.ErrorIs(t, err, ErrCategoryNotFound)
+		assert.ErrorIs(t, repo.Delete(backend.ID), ErrCategoryNotFound)
+	})
+}
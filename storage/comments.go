@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrCommentNotFound = errors.New("comment not found")
+
+type Comment struct {
+	ID        int64
+	PostID    int64
+	ParentID  int64 // 0 for top level comments
+	Author    string
+	Content   string
+	Owner     string   // Subject of the principal who wrote the comment, empty for anonymous comments
+	Deleted   bool     // Deleted comments with replies are kept without content, so the thread stays intact
+	Status    string   // Moderation status, see the moderation package
+	Flags     []string // Reasons spam checks flagged the comment for
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// InMemoryCommentRepository implements the CommentRepo interface
+type InMemoryCommentRepository struct {
+	mu     sync.RWMutex
+	data   map[int64]Comment
+	byPost map[int64][]int64 // post ID -> IDs of its comments in creation order
+	nextID int64
+	logger *slog.Logger
+}
+
+// NewInMemoryCommentRepository creates a new in-memory comment repository
+func NewInMemoryCommentRepository(logger *slog.Logger) *InMemoryCommentRepository {
+	return &InMemoryCommentRepository{
+		data:   make(map[int64]Comment),
+		byPost: make(map[int64][]int64),
+		nextID: 1,
+		logger: logger,
+	}
+}
+
+// Create stores a new comment and returns it with the generated fields filled
+func (repo *InMemoryCommentRepository) Create(comment Comment) (Comment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	comment.ID = repo.nextID
+	comment.Flags = append([]string(nil), comment.Flags...)
+	comment.CreatedAt = time.Now().UTC()
+	comment.UpdatedAt = comment.CreatedAt
+	repo.nextID++
+	repo.data[comment.ID] = comment
+	repo.byPost[comment.PostID] = append(repo.byPost[comment.PostID], comment.ID)
+	return comment, nil
+}
+
+func (repo *InMemoryCommentRepository) GetByID(id int64) (Comment, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	comment, ok := repo.data[id]
+	if !ok {
+		return Comment{}, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// GetByPost returns every comment of the post in creation order
+func (repo *InMemoryCommentRepository) GetByPost(postID int64) ([]Comment, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	comments := make([]Comment, 0, len(repo.byPost[postID]))
+	for _, id := range repo.byPost[postID] {
+		comments = append(comments, repo.data[id])
+	}
+	return comments, nil
+}
+
+// GetByStatus returns comments of every post with the moderation status, oldest first
+func (repo *InMemoryCommentRepository) GetByStatus(status string) ([]Comment, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var comments []Comment
+	for _, comment := range repo.data {
+		if comment.Status == status {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	return comments, nil
+}
+
+// Update replaces a stored comment and returns the new state. Post and parent can't be changed.
+func (repo *InMemoryCommentRepository) Update(comment Comment) (Comment, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.data[comment.ID]
+	if !ok {
+		return Comment{}, ErrCommentNotFound
+	}
+
+	comment.PostID = stored.PostID
+	comment.Flags = append([]string(nil), comment.Flags...)
+	comment.ParentID = stored.ParentID
+	comment.CreatedAt = stored.CreatedAt
+	comment.UpdatedAt = time.Now().UTC()
+	repo.data[comment.ID] = comment
+	return comment, nil
+}
+
+func (repo *InMemoryCommentRepository) Delete(id int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	comment, ok := repo.data[id]
+	if !ok {
+		return ErrCommentNotFound
+	}
+
+	delete(repo.data, id)
+	ids := repo.byPost[comment.PostID]
+	for i, commentID := range ids {
+		if commentID == id {
+			repo.byPost[comment.PostID] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// DeleteByPost removes all comments of the post
+func (repo *InMemoryCommentRepository) DeleteByPost(postID int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, id := range repo.byPost[postID] {
+		delete(repo.data, id)
+	}
+	delete(repo.byPost, postID)
+	return nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryCommentRepository(t *testing.T) {
+	repo := NewInMemoryCommentRepository(loggerMock())
+
+	first, err := repo.Create(Comment{PostID: 1, Author: "Reader 1", Content: "First"})
+	require.NoError(t, err)
+	reply, err := repo.Create(Comment{PostID: 1, ParentID: first.ID, Author: "Reader 2", Content: "Reply"})
+	require.NoError(t, err)
+	other, err := repo.Create(Comment{PostID: 2, Author: "Reader 1", Content: "Other post"})
+	require.NoError(t, err)
+
+	t.Run("Get By Post", func(t *testing.T) {
+		comments, err := repo.GetByPost(1)
+		require.NoError(t, err)
+		require.Len(t, comments, 2)
+		assert.Equal(t, first.ID, comments[0].ID)
+		assert.Equal(t, reply.ID, comments[1].ID)
+		assert.Equal(t, first.ID, comments[1].ParentID)
+
+		comments, err = repo.GetByPost(42)
+		require.NoError(t, err)
+		assert.Empty(t, comments)
+	})
+
+	t.Run("Get By Status", func(t *testing.T) {
+		pending, err := repo.Create(Comment{PostID: 2, Author: "Reader 3", Content: "Pending", Status: "pending"})
+		require.NoError(t, err)
+
+		comments, err := repo.GetByStatus("pending")
+		require.NoError(t, err)
+		require.Len(t, comments, 1)
+		assert.Equal(t, pending.ID, comments[0].ID)
+
+		require.NoError(t, repo.Delete(pending.ID))
+	})
+
+	t.Run("Update keeps post and parent", func(t *testing.T) {
+		reply.Content = "Edited"
+		reply.PostID = 2
+		reply.ParentID = 0
+		updated, err := repo.Update(reply)
+		require.NoError(t, err)
+		assert.Equal(t, "Edited", updated.Content)
+		assert.Equal(t, int64(1), updated.PostID)
+		assert.Equal(t, first.ID, updated.ParentID)
+		assert.False(t, updated.UpdatedAt.Before(updated.CreatedAt))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, repo.Delete(reply.ID))
+		assert.ErrorIs(t, repo.Delete(reply.ID), ErrCommentNotFound)
+
+		comments, err := repo.GetByPost(1)
+		require.NoError(t, err)
+		require.Len(t, comments, 1)
+	})
+
+	t.Run("Delete By Post", func(t *testing.T) {
+		require.NoError(t, repo.DeleteByPost(1))
+
+		comments, err := repo.GetByPost(1)
+		require.NoError(t, err)
+		assert.Empty(t, comments)
+		_, err = repo.GetByID(first.ID)
+		assert.ErrorIs(t, err, ErrCommentNotFound)
+
+		_, err = repo.GetByID(other.ID)
+		assert.NoError(t, err)
+	})
+}
@@ -1,22 +1,45 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"os"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+
+	"rakia_blog_tt/logging"
+	"rakia_blog_tt/slug"
 )
 
-var ErrPostNotFound = errors.New("post not found")
+var (
+	ErrPostNotFound = errors.New("post not found")
+	ErrSlugNotFound = errors.New("slug not found")
+	// ErrVersionConflict is returned by Update when the post was changed since the version it was read at
+	ErrVersionConflict = errors.New("post version conflict")
+)
 
 type Post struct {
-	ID      int64
-	Title   string
-	Content string
-	Author  string
+	ID         int64
+	Title      string
+	Content    string
+	Author     string    // Display name, kept in sync with the author record when AuthorID is set
+	AuthorID   int64     // 0 when the post is not linked to an author record
+	Slug       string    // Unique across current and former slugs of all posts
+	Tags       []string  // Normalized by the caller, indexed for lookups by tag
+	CategoryID int64     // 0 when the post is not categorized
+	Status     string    // Workflow status, empty for posts stored before statuses existed, which are published
+	PublishAt  time.Time // When a scheduled post gets published, or when a published post was published
+	Version    int64     // Incremented on every update, starts from 1. Update checks it unless it's 0.
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	// PreviewNonce is signed into preview links of the post, changed with SetPreviewNonce only
+	PreviewNonce string
 }
 
 // InMemoryPostRepository implements the Repo interface
@@ -24,22 +47,53 @@ type InMemoryPostRepository struct {
 	data   *sync.Map
 	nextID int64 // Primary Key, Autoincrement :)
 	logger *slog.Logger
+
+	// mu serializes writes, so ID and slug assignment stay consistent with the slug index
+	mu      sync.RWMutex
+	slugs   map[string]int64              // current slug -> post ID
+	history map[string]int64              // former slug -> post ID, kept for redirects
+	tags    map[string]map[int64]struct{} // tag -> IDs of posts with the tag
 }
 
 // NewInMemoryPostRepository creates a new in-memory post repository
 func NewInMemoryPostRepository(logger *slog.Logger) *InMemoryPostRepository {
 	db := new(sync.Map)
-	return &InMemoryPostRepository{data: db, nextID: 1, logger: logger}
+	return &InMemoryPostRepository{
+		data:    db,
+		nextID:  1,
+		logger:  logger,
+		slugs:   make(map[string]int64),
+		history: make(map[string]int64),
+		tags:    make(map[string]map[int64]struct{}),
+	}
 }
 
-func (repo *InMemoryPostRepository) Create(post Post) error {
+// Create stores a new post and returns it with the generated fields filled.
+// Post.Slug is taken as a base, a numeric suffix is appended if it's already in use.
+func (repo *InMemoryPostRepository) Create(ctx context.Context, post Post) (Post, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
 	post.ID = repo.nextID
+	post.Version = 1
+	post.CreatedAt = time.Now().UTC()
+	post.UpdatedAt = post.CreatedAt
+	post.Slug = repo.uniqueSlug(post.Slug, post.ID)
+	post.Tags = append([]string(nil), post.Tags...)
 	repo.nextID++
 	repo.data.Store(strconv.FormatInt(post.ID, 10), post)
-	return nil
+	repo.slugs[post.Slug] = post.ID
+	repo.indexTags(post)
+	repo.log(ctx).Debug("Post stored", "post_id", post.ID, "slug", post.Slug)
+	return post, nil
+}
+
+// log returns the logger of the request the repository is used for
+func (repo *InMemoryPostRepository) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, repo.logger)
 }
 
-func (repo *InMemoryPostRepository) GetAll() ([]Post, error) {
+func (repo *InMemoryPostRepository) GetAll(ctx context.Context) ([]Post, error) {
 	posts := []Post{}
 	repo.data.Range(func(key, value interface{}) bool {
 		posts = append(posts, value.(Post))
@@ -48,7 +102,7 @@ func (repo *InMemoryPostRepository) GetAll() ([]Post, error) {
 	return posts, nil
 }
 
-func (repo *InMemoryPostRepository) GetByID(id int) (Post, error) {
+func (repo *InMemoryPostRepository) GetByID(ctx context.Context, id int) (Post, error) {
 	post, ok := repo.data.Load(strconv.Itoa(id))
 	if !ok {
 		return Post{}, ErrPostNotFound
@@ -56,32 +110,179 @@ func (repo *InMemoryPostRepository) GetByID(id int) (Post, error) {
 	return post.(Post), nil
 }
 
-func (repo *InMemoryPostRepository) Update(post Post) error {
-	_, ok := repo.data.Load(strconv.FormatInt(post.ID, 10))
+// GetBySlug returns the post owning the slug, either as its current or a former one.
+// Callers compare the slug with Post.Slug to tell these cases apart.
+func (repo *InMemoryPostRepository) GetBySlug(ctx context.Context, slug string) (Post, error) {
+	repo.mu.RLock()
+	id, ok := repo.slugs[slug]
 	if !ok {
-		return ErrPostNotFound
+		id, ok = repo.history[slug]
+	}
+	repo.mu.RUnlock()
+	if !ok {
+		return Post{}, ErrSlugNotFound
+	}
+
+	return repo.GetByID(ctx, int(id))
+}
+
+// GetByTags returns posts having any of the tags, or all of them when matchAll is set, ordered by ID
+func (repo *InMemoryPostRepository) GetByTags(ctx context.Context, tags []string, matchAll bool) ([]Post, error) {
+	repo.mu.RLock()
+	matches := make(map[int64]int)
+	for _, tag := range tags {
+		for id := range repo.tags[tag] {
+			matches[id]++
+		}
+	}
+	repo.mu.RUnlock()
+
+	posts := []Post{}
+	for id, count := range matches {
+		if matchAll && count < len(tags) {
+			continue
+		}
+		post, err := repo.GetByID(ctx, int(id))
+		if errors.Is(err, ErrPostNotFound) {
+			continue // deleted after the index was read
+		}
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+	return posts, nil
+}
+
+// Update replaces a stored post and returns the new state.
+// An empty slug keeps the stored one, a changed slug is de-duplicated like on Create
+// and the previous one is kept in the history. A version other than 0 must be the stored one,
+// ErrVersionConflict is returned otherwise.
+func (repo *InMemoryPostRepository) Update(ctx context.Context, post Post) (Post, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	value, ok := repo.data.Load(strconv.FormatInt(post.ID, 10))
+	if !ok {
+		return Post{}, ErrPostNotFound
+	}
+	stored := value.(Post)
+	if post.Version != 0 && post.Version != stored.Version {
+		return Post{}, ErrVersionConflict
 	}
 
+	post.Version = stored.Version + 1
+	post.CreatedAt = stored.CreatedAt
+	post.PreviewNonce = stored.PreviewNonce
+	post.UpdatedAt = time.Now().UTC()
+
+	if post.Slug == "" || post.Slug == stored.Slug {
+		post.Slug = stored.Slug
+	} else {
+		post.Slug = repo.uniqueSlug(post.Slug, post.ID)
+		delete(repo.slugs, stored.Slug)
+		delete(repo.history, post.Slug) // the post may take back one of its former slugs
+		repo.history[stored.Slug] = post.ID
+		repo.slugs[post.Slug] = post.ID
+	}
+
+	post.Tags = append([]string(nil), post.Tags...)
+	repo.unindexTags(stored)
+	repo.indexTags(post)
+
 	repo.data.Store(strconv.FormatInt(post.ID, 10), post)
+	repo.log(ctx).Debug("Post replaced", "post_id", post.ID, "version", post.Version, "slug", post.Slug)
+
+	return post, nil
+}
+
+// SetPreviewNonce replaces the preview nonce of the post. It isn't an edit of the post, so the version stays.
+func (repo *InMemoryPostRepository) SetPreviewNonce(ctx context.Context, id int, nonce string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	value, ok := repo.data.Load(strconv.Itoa(id))
+	if !ok {
+		return ErrPostNotFound
+	}
+	post := value.(Post)
+	post.PreviewNonce = nonce
+	repo.data.Store(strconv.Itoa(id), post)
+	repo.log(ctx).Debug("Preview nonce replaced", "post_id", id)
 
 	return nil
 }
 
-func (repo *InMemoryPostRepository) Delete(id int) error {
-	_, ok := repo.data.Load(strconv.Itoa(id))
+func (repo *InMemoryPostRepository) Delete(ctx context.Context, id int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	value, ok := repo.data.Load(strconv.Itoa(id))
 	if !ok {
 		return ErrPostNotFound
 	}
 
 	repo.data.Delete(strconv.Itoa(id))
 
+	repo.unindexTags(value.(Post))
+
+	// Slugs of a deleted post are released
+	delete(repo.slugs, value.(Post).Slug)
+	for slug, postID := range repo.history {
+		if postID == int64(id) {
+			delete(repo.history, slug)
+		}
+	}
+	repo.log(ctx).Debug("Post deleted", "post_id", id)
+
 	return nil
 }
 
+// indexTags adds the post to the tag index. Must be called with mu held.
+func (repo *InMemoryPostRepository) indexTags(post Post) {
+	for _, tag := range post.Tags {
+		if repo.tags[tag] == nil {
+			repo.tags[tag] = make(map[int64]struct{})
+		}
+		repo.tags[tag][post.ID] = struct{}{}
+	}
+}
+
+// unindexTags removes the post from the tag index, dropping tags left without posts. Must be called with mu held.
+func (repo *InMemoryPostRepository) unindexTags(post Post) {
+	for _, tag := range post.Tags {
+		delete(repo.tags[tag], post.ID)
+		if len(repo.tags[tag]) == 0 {
+			delete(repo.tags, tag)
+		}
+	}
+}
+
+// uniqueSlug returns base or its first suffixed variant not used by other posts.
+// Former slugs count as used, otherwise redirects of the old URLs would break.
+// Must be called with mu held.
+func (repo *InMemoryPostRepository) uniqueSlug(base string, id int64) string {
+	if base == "" {
+		base = slug.Fallback
+	}
+	for n := 1; ; n++ {
+		candidate := slug.WithSuffix(base, n)
+		owner, ok := repo.slugs[candidate]
+		if !ok {
+			owner, ok = repo.history[candidate]
+		}
+		if !ok || owner == id {
+			return candidate
+		}
+	}
+}
+
 // saveToFile saves the current state of the repository to a file
 // No usage now. Added just in case. Easy to implement and control via config if we need to persist in-mem DB content
 func (repo *InMemoryPostRepository) saveToFile(filename string) error {
-	posts, err := repo.GetAll()
+	posts, err := repo.GetAll(context.Background())
 	if err != nil {
 		return err
 	}
@@ -107,9 +308,17 @@ func (repo *InMemoryPostRepository) loadFromFile(filename string) error {
 		return err
 	}
 
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
 	repo.data = &sync.Map{}
+	repo.slugs = make(map[string]int64)
+	repo.history = make(map[string]int64)
+	repo.tags = make(map[string]map[int64]struct{})
 	for _, post := range posts {
 		repo.data.Store(strconv.FormatInt(post.ID, 10), post)
+		repo.slugs[post.Slug] = post.ID
+		repo.indexTags(post)
 		if post.ID >= repo.nextID {
 			repo.nextID = post.ID + 1
 		}
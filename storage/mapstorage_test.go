@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"io"
 	"log/slog"
 	"os"
@@ -17,14 +18,16 @@ func loggerMock() *slog.Logger {
 func TestInMemoryPostRepository(t *testing.T) {
 	logger := loggerMock()
 	repo := NewInMemoryPostRepository(logger)
+	ctx := context.Background()
 
 	t.Run("Create Post", func(t *testing.T) {
 		post := Post{Title: "Title 1", Content: "Content 1", Author: "Author 1"}
-		err := repo.Create(post)
+		created, err := repo.Create(ctx, post)
 		require.NoError(t, err)
+		assert.Equal(t, int64(1), created.ID)
 
 		// Since we don't have the post ID directly after creation, we'll retrieve all posts
-		posts, err := repo.GetAll()
+		posts, err := repo.GetAll(ctx)
 		require.NoError(t, err)
 		require.Len(t, posts, 1)
 
@@ -33,69 +36,102 @@ func TestInMemoryPostRepository(t *testing.T) {
 		assert.Equal(t, post.Content, retrievedPost.Content)
 		assert.Equal(t, post.Author, retrievedPost.Author)
 		assert.NotZero(t, int(retrievedPost.ID))
+		assert.Equal(t, int64(1), retrievedPost.Version)
+		assert.False(t, retrievedPost.CreatedAt.IsZero())
+		assert.Equal(t, retrievedPost.CreatedAt, retrievedPost.UpdatedAt)
 	})
 
 	t.Run("Get All Posts", func(t *testing.T) {
-		posts, err := repo.GetAll()
+		posts, err := repo.GetAll(ctx)
 		require.NoError(t, err)
 		assert.NotEmpty(t, posts)
 	})
 
 	t.Run("Get Post By ID", func(t *testing.T) {
 		post := Post{Title: "Title 2", Content: "Content 2", Author: "Author 2"}
-		err := repo.Create(post)
+		_, err := repo.Create(ctx, post)
 		require.NoError(t, err)
 
 		// Since we don't have the post ID directly after creation, we'll retrieve all posts
-		posts, err := repo.GetAll()
+		posts, err := repo.GetAll(ctx)
 		require.NoError(t, err)
 		require.Len(t, posts, 2) // Should be 2 posts now
 
 		retrievedPost := posts[1] // The second post
-		retrievedPostByID, err := repo.GetByID(int(retrievedPost.ID))
+		retrievedPostByID, err := repo.GetByID(ctx, int(retrievedPost.ID))
 		require.NoError(t, err)
 		assert.Equal(t, retrievedPost, retrievedPostByID)
 	})
 
 	t.Run("Update Post", func(t *testing.T) {
 		post := Post{Title: "Title 3", Content: "Content 3", Author: "Author 3"}
-		err := repo.Create(post)
+		_, err := repo.Create(ctx, post)
 		require.NoError(t, err)
 
 		// Retrieve all posts to get the ID of the last inserted post
-		posts, err := repo.GetAll()
+		posts, err := repo.GetAll(ctx)
 		require.NoError(t, err)
 		require.Len(t, posts, 3)
 
 		// Update the last post
 		retrievedPost := posts[2]
 		retrievedPost.Title = "Updated Title 3"
-		err = repo.Update(retrievedPost)
+		updated, err := repo.Update(ctx, retrievedPost)
 		require.NoError(t, err)
+		assert.Equal(t, "Updated Title 3", updated.Title)
 
 		// Verify update
-		updatedPost, err := repo.GetByID(int(retrievedPost.ID))
+		updatedPost, err := repo.GetByID(ctx, int(retrievedPost.ID))
 		require.NoError(t, err)
 		assert.Equal(t, "Updated Title 3", updatedPost.Title)
+		assert.Equal(t, retrievedPost.Version+1, updatedPost.Version)
+		assert.Equal(t, retrievedPost.CreatedAt, updatedPost.CreatedAt)
+		assert.False(t, updatedPost.UpdatedAt.Before(retrievedPost.UpdatedAt))
+
+		_, err = repo.Update(ctx, retrievedPost)
+		assert.ErrorIs(t, err, ErrVersionConflict, "the post was read before the update")
+		retrievedPost.Version = 0
+		_, err = repo.Update(ctx, retrievedPost)
+		assert.NoError(t, err, "version 0 replaces any version")
+	})
+
+	t.Run("Set Preview Nonce", func(t *testing.T) {
+		stored, err := repo.GetByID(ctx, 1)
+		require.NoError(t, err)
+
+		require.NoError(t, repo.SetPreviewNonce(ctx, 1, "nonce"))
+		post, err := repo.GetByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "nonce", post.PreviewNonce)
+		assert.Equal(t, stored.Version, post.Version, "the nonce is not an edit")
+
+		post.PreviewNonce = ""
+		_, err = repo.Update(ctx, post)
+		require.NoError(t, err)
+		post, err = repo.GetByID(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "nonce", post.PreviewNonce, "updates keep the nonce")
+
+		assert.ErrorIs(t, repo.SetPreviewNonce(ctx, 42, "nonce"), ErrPostNotFound)
 	})
 
 	t.Run("Delete Post", func(t *testing.T) {
 		post := Post{Title: "Title 4", Content: "Content 4", Author: "Author 4"}
-		err := repo.Create(post)
+		_, err := repo.Create(ctx, post)
 		require.NoError(t, err)
 
 		// Retrieve all posts to get the ID of the last inserted post
-		posts, err := repo.GetAll()
+		posts, err := repo.GetAll(ctx)
 		require.NoError(t, err)
 		require.Len(t, posts, 4)
 
 		// Delete the last post
 		retrievedPost := posts[3]
-		err = repo.Delete(int(retrievedPost.ID))
+		err = repo.Delete(ctx, int(retrievedPost.ID))
 		require.NoError(t, err)
 
 		// Verify deletion
-		_, err = repo.GetByID(int(retrievedPost.ID))
+		_, err = repo.GetByID(ctx, int(retrievedPost.ID))
 		assert.Error(t, err)
 	})
 
@@ -110,8 +146,143 @@ func TestInMemoryPostRepository(t *testing.T) {
 		err = newRepo.loadFromFile(filename)
 		require.NoError(t, err)
 
-		posts, err := newRepo.GetAll()
+		posts, err := newRepo.GetAll(ctx)
 		require.NoError(t, err)
 		assert.NotEmpty(t, posts)
 	})
 }
+
+func TestInMemoryPostRepositorySlugs(t *testing.T) {
+	repo := NewInMemoryPostRepository(loggerMock())
+	ctx := context.Background()
+
+	first, err := repo.Create(ctx, Post{Title: "Hello", Slug: "hello"})
+	require.NoError(t, err)
+	second, err := repo.Create(ctx, Post{Title: "Hello", Slug: "hello"})
+	require.NoError(t, err)
+	untitled, err := repo.Create(ctx, Post{Title: "!!!"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello", first.Slug)
+	assert.Equal(t, "hello-2", second.Slug)
+	assert.Equal(t, "post", untitled.Slug)
+
+	t.Run("Get By Slug", func(t *testing.T) {
+		post, err := repo.GetBySlug(ctx, "hello-2")
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, post.ID)
+
+		_, err = repo.GetBySlug(ctx, "missing")
+		assert.ErrorIs(t, err, ErrSlugNotFound)
+	})
+
+	t.Run("Empty slug on update keeps the stored one", func(t *testing.T) {
+		first.Slug = ""
+		updated, err := repo.Update(ctx, first)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", updated.Slug)
+		first.Version = updated.Version
+	})
+
+	t.Run("Changed slug keeps the former one resolvable", func(t *testing.T) {
+		first.Slug = "goodbye"
+		updated, err := repo.Update(ctx, first)
+		require.NoError(t, err)
+		assert.Equal(t, "goodbye", updated.Slug)
+		first.Version = updated.Version
+
+		post, err := repo.GetBySlug(ctx, "hello")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, post.ID)
+		assert.Equal(t, "goodbye", post.Slug)
+
+		// The former slug is still reserved for redirects
+		third, err := repo.Create(ctx, Post{Title: "Hello", Slug: "hello"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello-3", third.Slug)
+	})
+
+	t.Run("Post takes back its former slug", func(t *testing.T) {
+		first.Slug = "hello"
+		updated, err := repo.Update(ctx, first)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", updated.Slug)
+
+		post, err := repo.GetBySlug(ctx, "goodbye")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", post.Slug)
+	})
+
+	t.Run("Delete releases slugs", func(t *testing.T) {
+		require.NoError(t, repo.Delete(ctx, int(first.ID)))
+
+		_, err := repo.GetBySlug(ctx, "hello")
+		assert.ErrorIs(t, err, ErrSlugNotFound)
+		_, err = repo.GetBySlug(ctx, "goodbye")
+		assert.ErrorIs(t, err, ErrSlugNotFound)
+
+		created, err := repo.Create(ctx, Post{Title: "Hello", Slug: "hello"})
+		require.NoError(t, err)
+		assert.Equal(t, "hello", created.Slug)
+	})
+}
+
+func TestInMemoryPostRepositoryTags(t *testing.T) {
+	repo := NewInMemoryPostRepository(loggerMock())
+	ctx := context.Background()
+
+	first, err := repo.Create(ctx, Post{Title: "First", Tags: []string{"go", "web"}})
+	require.NoError(t, err)
+	second, err := repo.Create(ctx, Post{Title: "Second", Tags: []string{"go"}})
+	require.NoError(t, err)
+	_, err = repo.Create(ctx, Post{Title: "Untagged"})
+	require.NoError(t, err)
+
+	ids := func(posts []Post) []int64 {
+		result := []int64{}
+		for _, post := range posts {
+			result = append(result, post.ID)
+		}
+		return result
+	}
+
+	t.Run("Get By Tags", func(t *testing.T) {
+		posts, err := repo.GetByTags(ctx, []string{"go"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{first.ID, second.ID}, ids(posts))
+
+		posts, err = repo.GetByTags(ctx, []string{"go", "web"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{first.ID, second.ID}, ids(posts))
+
+		posts, err = repo.GetByTags(ctx, []string{"go", "web"}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{first.ID}, ids(posts))
+
+		posts, err = repo.GetByTags(ctx, []string{"missing"}, false)
+		require.NoError(t, err)
+		assert.Empty(t, posts)
+	})
+
+	t.Run("Update reindexes tags", func(t *testing.T) {
+		first.Tags = []string{"rust"}
+		_, err := repo.Update(ctx, first)
+		require.NoError(t, err)
+
+		posts, err := repo.GetByTags(ctx, []string{"go", "web"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{second.ID}, ids(posts))
+
+		posts, err = repo.GetByTags(ctx, []string{"rust"}, false)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{first.ID}, ids(posts))
+	})
+
+	t.Run("Delete removes the post from the index", func(t *testing.T) {
+		require.NoError(t, repo.Delete(ctx, int(second.ID)))
+
+		posts, err := repo.GetByTags(ctx, []string{"go"}, false)
+		require.NoError(t, err)
+		assert.Empty(t, posts)
+	})
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is a login of a user. It is found by the hash of the token in the session cookie, the token itself isn't kept.
+type Session struct {
+	Hash   string
+	UserID int64
+	// CSRFToken is expected in forms posted within the session
+	CSRFToken string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// InMemorySessionRepository implements the SessionRepo interface
+type InMemorySessionRepository struct {
+	mu     sync.RWMutex
+	data   map[string]Session // hash -> session
+	logger *slog.Logger
+}
+
+// NewInMemorySessionRepository creates a new in-memory session repository
+func NewInMemorySessionRepository(logger *slog.Logger) *InMemorySessionRepository {
+	return &InMemorySessionRepository{
+		data:   make(map[string]Session),
+		logger: logger,
+	}
+}
+
+// Create stores a new session, expired sessions are dropped along the way so they don't pile up
+func (repo *InMemorySessionRepository) Create(session Session) (Session, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now().UTC()
+	for hash, stored := range repo.data {
+		if !now.Before(stored.ExpiresAt) {
+			delete(repo.data, hash)
+		}
+	}
+
+	session.CreatedAt = now
+	repo.data[session.Hash] = session
+	return session, nil
+}
+
+// GetByHash finds the session by the hash of its token, expired sessions are returned too
+func (repo *InMemorySessionRepository) GetByHash(hash string) (Session, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	session, ok := repo.data[hash]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (repo *InMemorySessionRepository) Delete(hash string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.data[hash]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(repo.data, hash)
+	return nil
+}
+
+// DeleteByUser revokes every session of the user
+func (repo *InMemorySessionRepository) DeleteByUser(userID int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for hash, session := range repo.data {
+		if session.UserID == userID {
+			delete(repo.data, hash)
+		}
+	}
+	return nil
+}
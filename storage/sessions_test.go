@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemorySessionRepository(t *testing.T) {
+	repo := NewInMemorySessionRepository(loggerMock())
+	later := time.Now().Add(time.Hour)
+
+	first, err := repo.Create(Session{Hash: "hash-1", UserID: 1, CSRFToken: "csrf", ExpiresAt: later})
+	require.NoError(t, err)
+	assert.False(t, first.CreatedAt.IsZero())
+	_, err = repo.Create(Session{Hash: "hash-2", UserID: 1, ExpiresAt: later})
+	require.NoError(t, err)
+	_, err = repo.Create(Session{Hash: "hash-3", UserID: 2, ExpiresAt: later})
+	require.NoError(t, err)
+
+	found, err := repo.GetByHash("hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "csrf", found.CSRFToken)
+
+	require.NoError(t, repo.Delete("hash-1"))
+	_, err = repo.GetByHash("hash-1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.ErrorIs(t, repo.Delete("hash-1"), ErrSessionNotFound)
+
+	require.NoError(t, repo.DeleteByUser(1))
+	_, err = repo.GetByHash("hash-2")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = repo.GetByHash("hash-3")
+	assert.NoError(t, err, "sessions of other users stay")
+}
+
+func TestInMemorySessionRepository_DropsExpired(t *testing.T) {
+	repo := NewInMemorySessionRepository(loggerMock())
+
+	_, err := repo.Create(Session{Hash: "expired", UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = repo.GetByHash("expired")
+	assert.NoError(t, err, "expiry is up to the caller")
+
+	_, err = repo.Create(Session{Hash: "fresh", UserID: 2, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = repo.GetByHash("expired")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"time"
 )
 
@@ -22,47 +23,422 @@ func NewStorageMetricDecorator(db *InMemoryPostRepository, metrics MetricsInterf
 	}
 }
 
-func (d *MetricDecorator) Create(post Post) error {
+func (d *MetricDecorator) Create(ctx context.Context, post Post) (Post, error) {
 	startTime := time.Now()
-	err := d.db.Create(post)
+	created, err := d.db.Create(ctx, post)
 
 	d.metrics.ObserveQueryDuration(startTime, "Create")
 
-	return err
+	return created, err
 }
 
-func (d *MetricDecorator) GetAll() ([]Post, error) {
+func (d *MetricDecorator) GetAll(ctx context.Context) ([]Post, error) {
 	startTime := time.Now()
-	posts, err := d.db.GetAll()
+	posts, err := d.db.GetAll(ctx)
 
 	d.metrics.ObserveQueryDuration(startTime, "GetAll")
 
 	return posts, err
 }
 
-func (d *MetricDecorator) GetByID(id int) (Post, error) {
+func (d *MetricDecorator) GetByID(ctx context.Context, id int) (Post, error) {
 	startTime := time.Now()
-	post, err := d.db.GetByID(id)
+	post, err := d.db.GetByID(ctx, id)
 
 	d.metrics.ObserveQueryDuration(startTime, "GetByID")
 
 	return post, err
 }
 
-func (d *MetricDecorator) Update(post Post) error {
+func (d *MetricDecorator) GetBySlug(ctx context.Context, slug string) (Post, error) {
+	startTime := time.Now()
+	post, err := d.db.GetBySlug(ctx, slug)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetBySlug")
+
+	return post, err
+}
+
+func (d *MetricDecorator) GetByTags(ctx context.Context, tags []string, matchAll bool) ([]Post, error) {
 	startTime := time.Now()
-	err := d.db.Update(post)
+	posts, err := d.db.GetByTags(ctx, tags, matchAll)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetByTags")
+
+	return posts, err
+}
+
+func (d *MetricDecorator) Update(ctx context.Context, post Post) (Post, error) {
+	startTime := time.Now()
+	updated, err := d.db.Update(ctx, post)
 
 	d.metrics.ObserveQueryDuration(startTime, "Update")
 
+	return updated, err
+}
+
+func (d *MetricDecorator) SetPreviewNonce(ctx context.Context, id int, nonce string) error {
+	startTime := time.Now()
+	err := d.db.SetPreviewNonce(ctx, id, nonce)
+
+	d.metrics.ObserveQueryDuration(startTime, "SetPreviewNonce")
+
 	return err
 }
 
-func (d *MetricDecorator) Delete(id int) error {
+func (d *MetricDecorator) Delete(ctx context.Context, id int) error {
 	startTime := time.Now()
-	err := d.db.Delete(id)
+	err := d.db.Delete(ctx, id)
 
 	d.metrics.ObserveQueryDuration(startTime, "Delete")
 
 	return err
 }
+
+// CategoryMetricDecorator observes query durations of the category repository
+type CategoryMetricDecorator struct {
+	db      *InMemoryCategoryRepository
+	metrics MetricsInterface
+}
+
+func NewCategoryMetricDecorator(db *InMemoryCategoryRepository, metrics MetricsInterface) *CategoryMetricDecorator {
+	return &CategoryMetricDecorator{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+func (d *CategoryMetricDecorator) Create(category Category) (Category, error) {
+	startTime := time.Now()
+	created, err := d.db.Create(category)
+
+	d.metrics.ObserveQueryDuration(startTime, "CreateCategory")
+
+	return created, err
+}
+
+func (d *CategoryMetricDecorator) GetAll() ([]Category, error) {
+	startTime := time.Now()
+	categories, err := d.db.GetAll()
+
+	d.metrics.ObserveQueryDuration(startTime, "GetAllCategories")
+
+	return categories, err
+}
+
+func (d *CategoryMetricDecorator) GetByID(id int64) (Category, error) {
+	startTime := time.Now()
+	category, err := d.db.GetByID(id)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetCategoryByID")
+
+	return category, err
+}
+
+func (d *CategoryMetricDecorator) Update(category Category) (Category, error) {
+	startTime := time.Now()
+	updated, err := d.db.Update(category)
+
+	d.metrics.ObserveQueryDuration(startTime, "UpdateCategory")
+
+	return updated, err
+}
+
+func (d *CategoryMetricDecorator) Delete(id int64) error {
+	startTime := time.Now()
+	err := d.db.Delete(id)
+
+	d.metrics.ObserveQueryDuration(startTime, "DeleteCategory")
+
+	return err
+}
+
+// CommentMetricDecorator observes query durations of the comment repository
+type CommentMetricDecorator struct {
+	db      *InMemoryCommentRepository
+	metrics MetricsInterface
+}
+
+func NewCommentMetricDecorator(db *InMemoryCommentRepository, metrics MetricsInterface) *CommentMetricDecorator {
+	return &CommentMetricDecorator{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+func (d *CommentMetricDecorator) Create(comment Comment) (Comment, error) {
+	startTime := time.Now()
+	created, err := d.db.Create(comment)
+
+	d.metrics.ObserveQueryDuration(startTime, "CreateComment")
+
+	return created, err
+}
+
+func (d *CommentMetricDecorator) GetByID(id int64) (Comment, error) {
+	startTime := time.Now()
+	comment, err := d.db.GetByID(id)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetCommentByID")
+
+	return comment, err
+}
+
+func (d *CommentMetricDecorator) GetByPost(postID int64) ([]Comment, error) {
+	startTime := time.Now()
+	comments, err := d.db.GetByPost(postID)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetCommentsByPost")
+
+	return comments, err
+}
+
+func (d *CommentMetricDecorator) GetByStatus(status string) ([]Comment, error) {
+	startTime := time.Now()
+	comments, err := d.db.GetByStatus(status)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetCommentsByStatus")
+
+	return comments, err
+}
+
+func (d *CommentMetricDecorator) Update(comment Comment) (Comment, error) {
+	startTime := time.Now()
+	updated, err := d.db.Update(comment)
+
+	d.metrics.ObserveQueryDuration(startTime, "UpdateComment")
+
+	return updated, err
+}
+
+func (d *CommentMetricDecorator) Delete(id int64) error {
+	startTime := time.Now()
+	err := d.db.Delete(id)
+
+	d.metrics.ObserveQueryDuration(startTime, "DeleteComment")
+
+	return err
+}
+
+func (d *CommentMetricDecorator) DeleteByPost(postID int64) error {
+	startTime := time.Now()
+	err := d.db.DeleteByPost(postID)
+
+	d.metrics.ObserveQueryDuration(startTime, "DeleteCommentsByPost")
+
+	return err
+}
+
+// AuthorMetricDecorator observes query durations of the author repository
+type AuthorMetricDecorator struct {
+	db      *InMemoryAuthorRepository
+	metrics MetricsInterface
+}
+
+func NewAuthorMetricDecorator(db *InMemoryAuthorRepository, metrics MetricsInterface) *AuthorMetricDecorator {
+	return &AuthorMetricDecorator{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+func (d *AuthorMetricDecorator) Create(author Author) (Author, error) {
+	startTime := time.Now()
+	created, err := d.db.Create(author)
+
+	d.metrics.ObserveQueryDuration(startTime, "CreateAuthor")
+
+	return created, err
+}
+
+func (d *AuthorMetricDecorator) GetAll() ([]Author, error) {
+	startTime := time.Now()
+	authors, err := d.db.GetAll()
+
+	d.metrics.ObserveQueryDuration(startTime, "GetAllAuthors")
+
+	return authors, err
+}
+
+func (d *AuthorMetricDecorator) GetByID(id int64) (Author, error) {
+	startTime := time.Now()
+	author, err := d.db.GetByID(id)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetAuthorByID")
+
+	return author, err
+}
+
+func (d *AuthorMetricDecorator) GetByName(name string) (Author, error) {
+	startTime := time.Now()
+	author, err := d.db.GetByName(name)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetAuthorByName")
+
+	return author, err
+}
+
+func (d *AuthorMetricDecorator) Update(author Author) (Author, error) {
+	startTime := time.Now()
+	updated, err := d.db.Update(author)
+
+	d.metrics.ObserveQueryDuration(startTime, "UpdateAuthor")
+
+	return updated, err
+}
+
+func (d *AuthorMetricDecorator) Delete(id int64) error {
+	startTime := time.Now()
+	err := d.db.Delete(id)
+
+	d.metrics.ObserveQueryDuration(startTime, "DeleteAuthor")
+
+	return err
+}
+
+// APIKeyMetricDecorator observes query durations of the API key repository
+type APIKeyMetricDecorator struct {
+	db      *InMemoryAPIKeyRepository
+	metrics MetricsInterface
+}
+
+func NewAPIKeyMetricDecorator(db *InMemoryAPIKeyRepository, metrics MetricsInterface) *APIKeyMetricDecorator {
+	return &APIKeyMetricDecorator{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+func (d *APIKeyMetricDecorator) Create(key APIKey) (APIKey, error) {
+	startTime := time.Now()
+	created, err := d.db.Create(key)
+
+	d.metrics.ObserveQueryDuration(startTime, "CreateAPIKey")
+
+	return created, err
+}
+
+func (d *APIKeyMetricDecorator) GetAll() ([]APIKey, error) {
+	startTime := time.Now()
+	keys, err := d.db.GetAll()
+
+	d.metrics.ObserveQueryDuration(startTime, "GetAllAPIKeys")
+
+	return keys, err
+}
+
+func (d *APIKeyMetricDecorator) GetByHash(hash string) (APIKey, error) {
+	startTime := time.Now()
+	key, err := d.db.GetByHash(hash)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetAPIKeyByHash")
+
+	return key, err
+}
+
+func (d *APIKeyMetricDecorator) Touch(id int64, at time.Time) error {
+	startTime := time.Now()
+	err := d.db.Touch(id, at)
+
+	d.metrics.ObserveQueryDuration(startTime, "TouchAPIKey")
+
+	return err
+}
+
+func (d *APIKeyMetricDecorator) Delete(id int64) error {
+	startTime := time.Now()
+	err := d.db.Delete(id)
+
+	d.metrics.ObserveQueryDuration(startTime, "DeleteAPIKey")
+
+	return err
+}
+
+// UserMetricDecorator observes query durations of the user repository
+type UserMetricDecorator struct {
+	db      *InMemoryUserRepository
+	metrics MetricsInterface
+}
+
+func NewUserMetricDecorator(db *InMemoryUserRepository, metrics MetricsInterface) *UserMetricDecorator {
+	return &UserMetricDecorator{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+func (d *UserMetricDecorator) Create(user User) (User, error) {
+	startTime := time.Now()
+	created, err := d.db.Create(user)
+
+	d.metrics.ObserveQueryDuration(startTime, "CreateUser")
+
+	return created, err
+}
+
+func (d *UserMetricDecorator) GetByID(id int64) (User, error) {
+	startTime := time.Now()
+	user, err := d.db.GetByID(id)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetUserByID")
+
+	return user, err
+}
+
+func (d *UserMetricDecorator) GetByEmail(email string) (User, error) {
+	startTime := time.Now()
+	user, err := d.db.GetByEmail(email)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetUserByEmail")
+
+	return user, err
+}
+
+// SessionMetricDecorator observes query durations of the session repository
+type SessionMetricDecorator struct {
+	db      *InMemorySessionRepository
+	metrics MetricsInterface
+}
+
+func NewSessionMetricDecorator(db *InMemorySessionRepository, metrics MetricsInterface) *SessionMetricDecorator {
+	return &SessionMetricDecorator{
+		db:      db,
+		metrics: metrics,
+	}
+}
+
+func (d *SessionMetricDecorator) Create(session Session) (Session, error) {
+	startTime := time.Now()
+	created, err := d.db.Create(session)
+
+	d.metrics.ObserveQueryDuration(startTime, "CreateSession")
+
+	return created, err
+}
+
+func (d *SessionMetricDecorator) GetByHash(hash string) (Session, error) {
+	startTime := time.Now()
+	session, err := d.db.GetByHash(hash)
+
+	d.metrics.ObserveQueryDuration(startTime, "GetSessionByHash")
+
+	return session, err
+}
+
+func (d *SessionMetricDecorator) Delete(hash string) error {
+	startTime := time.Now()
+	err := d.db.Delete(hash)
+
+	d.metrics.ObserveQueryDuration(startTime, "DeleteSession")
+
+	return err
+}
+
+func (d *SessionMetricDecorator) DeleteByUser(userID int64) error {
+	startTime := time.Now()
+	err := d.db.DeleteByUser(userID)
+
+	d.metrics.ObserveQueryDuration(startTime, "DeleteUserSessions")
+
+	return err
+}
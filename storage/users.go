@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrEmailTaken   = errors.New("email is taken")
+)
+
+// User is an account of the HTML side of the blog
+type User struct {
+	ID    int64
+	Email string // Unique ignoring case
+	Name  string
+	// PasswordHash is an argon2id hash in the PHC string format
+	PasswordHash string
+	Roles        []string
+	// AuthorID links the user to an author, zero for readers
+	AuthorID  int64
+	CreatedAt time.Time
+}
+
+// InMemoryUserRepository implements the UserRepo interface
+type InMemoryUserRepository struct {
+	mu     sync.RWMutex
+	data   map[int64]User
+	emails map[string]int64 // email key -> user ID
+	nextID int64
+	logger *slog.Logger
+}
+
+// NewInMemoryUserRepository creates a new in-memory user repository
+func NewInMemoryUserRepository(logger *slog.Logger) *InMemoryUserRepository {
+	return &InMemoryUserRepository{
+		data:   make(map[int64]User),
+		emails: make(map[string]int64),
+		nextID: 1,
+		logger: logger,
+	}
+}
+
+// EmailKey identifies an email address, so "Jane@Example.com" and "jane@example.com" are one account
+func EmailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Create stores a new user and returns it with the generated fields filled
+func (repo *InMemoryUserRepository) Create(user User) (User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	key := EmailKey(user.Email)
+	if _, ok := repo.emails[key]; ok {
+		return User{}, ErrEmailTaken
+	}
+
+	user.ID = repo.nextID
+	user.Roles = append([]string(nil), user.Roles...)
+	user.CreatedAt = time.Now().UTC()
+	repo.nextID++
+	repo.data[user.ID] = user
+	repo.emails[key] = user.ID
+	return user, nil
+}
+
+func (repo *InMemoryUserRepository) GetByID(id int64) (User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	user, ok := repo.data[id]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// GetByEmail finds the user by email ignoring case
+func (repo *InMemoryUserRepository) GetByEmail(email string) (User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	id, ok := repo.emails[EmailKey(email)]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return repo.data[id], nil
+}
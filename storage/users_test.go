@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryUserRepository(t *testing.T) {
+	repo := NewInMemoryUserRepository(loggerMock())
+
+	roles := []string{"reader"}
+	jane, err := repo.Create(User{Email: "Jane@Example.com", Name: "Jane", PasswordHash: "hash", Roles: roles})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), jane.ID)
+	assert.False(t, jane.CreatedAt.IsZero())
+
+	_, err = repo.Create(User{Email: " jane@example.COM ", Name: "Other Jane"})
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	roles[0] = "admin"
+	found, err := repo.GetByEmail("jane@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, jane.ID, found.ID)
+	assert.Equal(t, []string{"reader"}, found.Roles, "roles are copied on create")
+
+	found, err = repo.GetByID(jane.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane@Example.com", found.Email, "email is kept as given")
+
+	_, err = repo.GetByID(42)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	_, err = repo.GetByEmail("john@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}